@@ -0,0 +1,99 @@
+// Package exporter embeds tf_exporter in other Go services: Its Exporter collects the Terraform metrics as
+// configured by the flags of the exporter, to be registered with the registry of the service and served
+// along with its own metrics.
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/collector"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Flags are the flags of the exporter, e.g. Organizations for --organizations.
+type Flags = setup.CLI
+
+// Options configure the Exporter: The flags of the exporter, and how it's embedded in the service.
+type Options struct {
+	Flags
+	// Registerer registers the metrics of the requests to the API (client_api_*), e.g. the registry of the service
+	// (Omit to not expose them).
+	Registerer prometheus.Registerer
+	// Timeout bounds every collection, like the scrape timeout sent by Prometheus to the exporter (Omit for none).
+	Timeout time.Duration
+}
+
+// DefaultOptions returns the Options with the default values of the flags, to be changed before calling New.
+func DefaultOptions() (Options, error) {
+	flags, err := setup.DefaultCLI()
+	return Options{Flags: flags}, err
+}
+
+// Exporter collects the Terraform metrics from the API on every collection, unless their results are cached
+// with CacheTTL. Only the instance of APIAddress is scraped, and the options changing how the metrics are
+// served (e.g. MetricNamespace or Labels) are left to the service, like the tracer provider.
+// It implements the prometheus.Collector interface.
+type Exporter struct {
+	config   setup.Config
+	scrapers []collector.Scraper
+	metrics  collector.Metrics
+	cache    *collector.Cache
+	timeout  time.Duration
+}
+
+// New returns a new Exporter configured by the options, running the scrapers of Collect (or the default ones).
+// The logger is optional, the messages are logged as configured by the options without it.
+func New(options Options, logger log.Logger) (*Exporter, error) {
+	config, err := setup.New(options.Flags, logger, options.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	scrapers := collector.DefaultScrapers()
+	if len(options.Collect) > 0 {
+		if scrapers, err = collector.FilterScrapers(options.Collect); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Exporter{
+		config:   config,
+		scrapers: scrapers,
+		metrics:  collector.NewMetrics(),
+		cache:    collector.NewCache(config.CacheTTL),
+		timeout:  options.Timeout,
+	}, nil
+}
+
+// Describe implements the prometheus.Collector interface.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	collector.New(context.Background(), e.config, e.scrapers, e.metrics, e.cache).Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface, bounded by the Timeout of the options.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	e.CollectContext(ctx, ch)
+}
+
+// CollectContext collects the metrics like Collect, until ctx is done, e.g. to bound it by the request
+// of the service being served.
+func (e *Exporter) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	collector.New(ctx, e.config, e.scrapers, e.metrics, e.cache).Collect(ch)
+}
+
+// Close stops the work the Exporter left running in the background, e.g. pinging an API unreachable when it was created.
+func (e *Exporter) Close() {
+	e.config.Close()
+}
@@ -0,0 +1,143 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestExporter(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{"data":[{"id":"ws-1","type":"workspaces","attributes":{"name":"dev"},"relationships":{"organization":{"data":{"id":"test-org","type":"organizations"}}}}],"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}}}`))
+		case "/api/v2/organizations/slow-org/workspaces":
+			time.Sleep(time.Second)
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	convey.Convey("Collects the metrics of the scrapers, registered with another registry", t, func() {
+		options, err := DefaultOptions()
+		convey.So(err, convey.ShouldBeNil)
+		options.APIAddress = mockAPI.URL
+		options.APIToken = "test"
+		options.Organizations = []string{"test-org"}
+		options.Collect = []string{"workspaces"}
+
+		e, err := New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+		registry := prometheus.NewRegistry()
+		convey.So(registry.Register(e), convey.ShouldBeNil)
+
+		families, err := registry.Gather()
+		convey.So(err, convey.ShouldBeNil)
+		series := map[string]int{}
+		for _, mf := range families {
+			series[mf.GetName()] = len(mf.Metric)
+		}
+		convey.So(series["tf_workspaces_info"], convey.ShouldEqual, 1)
+		convey.So(series["tf_exporter_scrape_success"], convey.ShouldEqual, 1)
+		convey.So(series["client_api_requests_total"], convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("Registers the metrics of the API client with the Registerer of the options", t, func() {
+		options, err := DefaultOptions()
+		convey.So(err, convey.ShouldBeNil)
+		options.APIAddress = mockAPI.URL
+		options.APIToken = "test"
+		options.Organizations = []string{"test-org"}
+		options.Collect = []string{"workspaces"}
+		registry := prometheus.NewRegistry()
+		options.Registerer = registry
+
+		e, err := New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(registry.Register(e), convey.ShouldBeNil)
+
+		families, err := registry.Gather()
+		convey.So(err, convey.ShouldBeNil)
+		names := map[string]bool{}
+		for _, mf := range families {
+			names[mf.GetName()] = true
+		}
+		convey.So(names["client_api_requests_total"], convey.ShouldBeTrue)
+
+		// Metrics conflicting with the ones of the service are an error.
+		conflicting := prometheus.NewRegistry()
+		conflicting.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "client_api_requests_total", Help: "Another metric."}))
+		options.Registerer = conflicting
+		_, err = New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+
+	convey.Convey("Collections are bounded by the Timeout of the options", t, func() {
+		options, err := DefaultOptions()
+		convey.So(err, convey.ShouldBeNil)
+		options.APIAddress = mockAPI.URL
+		options.APIToken = "test"
+		options.Organizations = []string{"slow-org"}
+		options.Collect = []string{"workspaces"}
+		options.Timeout = 50 * time.Millisecond
+
+		e, err := New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+		registry := prometheus.NewRegistry()
+		convey.So(registry.Register(e), convey.ShouldBeNil)
+
+		start := time.Now()
+		families, err := registry.Gather()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(time.Since(start), convey.ShouldBeLessThan, time.Second)
+		success := map[string]float64{}
+		for _, mf := range families {
+			for _, m := range mf.Metric {
+				success[mf.GetName()] += m.GetGauge().GetValue()
+			}
+		}
+		convey.So(success, convey.ShouldContainKey, "tf_exporter_scrape_success")
+		convey.So(success["tf_exporter_scrape_success"], convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("Returns the errors of the options, and can be created again", t, func() {
+		options, err := DefaultOptions()
+		convey.So(err, convey.ShouldBeNil)
+		options.APIAddress = mockAPI.URL
+
+		_, err = New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+
+		options.APIToken = "test"
+		options.Collect = []string{"unknown"}
+		_, err = New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+
+		options.RunsLookback = 0
+		_, err = New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+
+		// The metric namespace and labels are validated like the flags of the exporter.
+		options, err = DefaultOptions()
+		convey.So(err, convey.ShouldBeNil)
+		options.APIAddress, options.APIToken = mockAPI.URL, "test"
+		options.MetricNamespace = "tfc-prod"
+		_, err = New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+
+		options.MetricNamespace = "tfc_prod"
+		options.Labels = map[string]string{"environment-name": "prod"}
+		_, err = New(options, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"net/http"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/app"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/collector"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+)
+
+// Scraper is the interface of the scrapers, implemented by the plugins to scrape the API like the builtin ones.
+// It's stable, new capabilities are optional interfaces instead of new methods.
+type Scraper = collector.Scraper
+
+// Config is the configuration the scrapers are run with: The Flags, the Client of the API (a go-tfe client),
+// the API to request the endpoints the go-tfe client doesn't support, and the Pool every request goes through.
+type Config = setup.Config
+
+// JSONAPI requests the endpoints of the API the go-tfe client doesn't support, e.g. to set the API of a Config in tests.
+type JSONAPI = setup.JSONAPI
+
+// BuildInfo describes the build of the exporter, shown by its landing page.
+type BuildInfo = app.BuildInfo
+
+// Register adds the scraper to the ones selectable with --collect. It's meant to be called from the init function
+// of the package of the scraper, and panics if its name is already used.
+func Register(scraper Scraper) {
+	collector.Register(scraper)
+}
+
+// NewJSONAPI returns a new JSONAPI client for the API at the given address.
+func NewJSONAPI(client *http.Client, address, token string) (*JSONAPI, error) {
+	return setup.NewJSONAPI(client, address, token)
+}
+
+// Main runs the exporter as configured by the command line, like the tf_exporter command. A main package importing
+// the packages of third-party plugins, which register their scrapers, and calling Main builds the exporter with them.
+func Main(info BuildInfo) {
+	app.Main(info)
+}
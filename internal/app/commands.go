@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/collector"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log/level"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// checkTimeout limits how long the check command waits for the API.
+const checkTimeout = 30 * time.Second
+
+// listScrapers prints the name, API version and help of the scrapers.
+func listScrapers(w io.Writer, scrapers []collector.Scraper) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tHELP")
+	for _, scraper := range scrapers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", scraper.Name(), scraper.Version(), scraper.Help())
+	}
+	tw.Flush()
+}
+
+// check validates the configuration, that the API can be reached with the token,
+// and that the token can access the configured organizations.
+func check(w io.Writer, config setup.Config) error {
+	if _, err := selectScrapers(config.Collect, collector.Scrapers); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	user, err := config.Client.Users.ReadCurrent(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read the user of the API token: %w", err)
+	}
+
+	address := config.APIAddress
+	if address == "" {
+		address = tfe.DefaultAddress
+	}
+	fmt.Fprintf(w, "Authenticated to %s as %s\n", address, user.Username)
+
+	accessible, err := listOrganizations(ctx, config)
+	if err != nil {
+		return fmt.Errorf("unable to list the organizations: %w", err)
+	}
+	fmt.Fprintf(w, "Accessible organizations: %s\n", strings.Join(accessible, ", "))
+
+	var missing []string
+	for _, organization := range config.Organizations {
+		if !contains(accessible, organization) {
+			missing = append(missing, organization)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the API token can't access the organizations: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// runBackfill writes the history to --output, or to the standard output.
+func runBackfill(config setup.Config) error {
+	if config.Backfill.Output == "" {
+		return backfill(os.Stdout, config)
+	}
+
+	f, err := os.Create(config.Backfill.Output)
+	if err != nil {
+		return err
+	}
+	if err := backfill(f, config); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// backfill writes the history of the runs and applies of the organizations as OpenMetrics with timestamps,
+// in the configured namespace and with the configured labels like the exposed metrics.
+func backfill(w io.Writer, config setup.Config) error {
+	from, to := config.Backfill.From, config.Backfill.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("the history must start before it ends: from %s to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	if config.Backfill.Step <= 0 {
+		return fmt.Errorf("the step must be positive, got %s", config.Backfill.Step)
+	}
+
+	ctx := context.Background()
+	if len(config.Organizations) == 0 {
+		organizations, err := listOrganizations(ctx, config)
+		if err != nil {
+			return fmt.Errorf("unable to list the organizations: %w", err)
+		}
+		config.Organizations = organizations
+	}
+
+	level.Info(config.Logger).Log("msg", "Backfilling the history of the runs", "from", from, "to", to, "organizations", strings.Join(config.Organizations, ","))
+	families, err := collector.Backfill(ctx, &config, from, to, config.Backfill.Step)
+	if err != nil {
+		return err
+	}
+	families, err = exposed(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil }), config).Gather()
+	if err != nil {
+		return err
+	}
+
+	enc := expfmt.NewEncoder(w, expfmt.FmtOpenMetrics)
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return err
+		}
+	}
+	_, err = expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+// listOrganizations returns the names of all the organizations the API token can access.
+func listOrganizations(ctx context.Context, config setup.Config) ([]string, error) {
+	var names []string
+	options := &tfe.OrganizationListOptions{ListOptions: tfe.ListOptions{PageNumber: 1}}
+	for options.PageNumber != 0 {
+		list, err := config.Client.Organizations.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+		options.PageNumber = list.Pagination.NextPage
+	}
+
+	return names, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheck(t *testing.T) {
+	failOrganizations := false
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/account/details":
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data":{"id":"user-1","type":"users","attributes":{"username":"test-user"}}}`))
+		case "/api/v2/organizations":
+			if failOrganizations {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			// The organizations are listed across two pages.
+			if r.URL.Query().Get("page[number]") == "2" {
+				w.Write([]byte(`{
+					"meta":{"pagination":{"current-page":2,"prev-page":1,"total-pages":2,"total-count":2}},
+					"data":[{"id":"org-2","type":"organizations","attributes":{"name":"org-2"}}]
+				}`))
+				return
+			}
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"next-page":2,"total-pages":2,"total-count":2}},
+				"data":[{"id":"org-1","type":"organizations","attributes":{"name":"org-1"}}]
+			}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer mockAPI.Close()
+
+	newConfig := func(token string, organizations ...string) setup.Config {
+		client, err := tfe.NewClient(&tfe.Config{
+			Address: mockAPI.URL,
+			Token:   token,
+		})
+		if err != nil {
+			t.Fatalf("error creating a stub api client: %s", err)
+		}
+
+		return setup.Config{
+			Client: *client,
+			CLI:    setup.CLI{APIAddress: mockAPI.URL, Organizations: organizations},
+			Logger: log.NewNopLogger(),
+		}
+	}
+
+	convey.Convey("Check", t, func() {
+		failOrganizations = false
+		var out bytes.Buffer
+
+		convey.Convey("lists the organizations of every page", func() {
+			convey.So(check(&out, newConfig("valid", "org-1", "org-2")), convey.ShouldBeNil)
+			convey.So(out.String(), convey.ShouldEqual, "Authenticated to "+mockAPI.URL+" as test-user\nAccessible organizations: org-1, org-2\n")
+		})
+
+		convey.Convey("reports the configured organizations the token can't access", func() {
+			err := check(&out, newConfig("valid", "org-2", "org-3"))
+			convey.So(err, convey.ShouldNotBeNil)
+			convey.So(err.Error(), convey.ShouldEqual, "the API token can't access the organizations: org-3")
+		})
+
+		convey.Convey("wraps the errors of the API", func() {
+			err := check(&out, newConfig("invalid"))
+			convey.So(errors.Is(err, tfe.ErrUnauthorized), convey.ShouldBeTrue)
+			convey.So(err.Error(), convey.ShouldStartWith, "unable to read the user of the API token: ")
+
+			failOrganizations = true
+			err = check(&out, newConfig("valid"))
+			convey.So(err, convey.ShouldNotBeNil)
+			convey.So(err.Error(), convey.ShouldStartWith, "unable to list the organizations: ")
+		})
+	})
+}
+
+func TestListOrganizations(t *testing.T) {
+	pages := []string{}
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Path != "/api/v2/organizations" {
+			return
+		}
+
+		page := r.URL.Query().Get("page[number]")
+		pages = append(pages, page)
+		switch page {
+		case "1":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"next-page":2,"total-pages":3,"total-count":3}},
+				"data":[{"id":"org-1","type":"organizations","attributes":{"name":"org-1"}}]
+			}`))
+		case "2":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":2,"next-page":3,"total-pages":3,"total-count":3}},
+				"data":[{"id":"org-2","type":"organizations","attributes":{"name":"org-2"}}]
+			}`))
+		default:
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":3,"total-pages":3,"total-count":3}},
+				"data":[{"id":"org-3","type":"organizations","attributes":{"name":"org-3"}}]
+			}`))
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	convey.Convey("Lists the organizations page by page until the last one", t, func() {
+		organizations, err := listOrganizations(context.Background(), setup.Config{Client: *client})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(organizations, convey.ShouldResemble, []string{"org-1", "org-2", "org-3"})
+		convey.So(pages, convey.ShouldResemble, []string{"1", "2", "3"})
+	})
+}
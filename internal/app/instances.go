@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/collector"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// instance is a Terraform instance to scrape, with the state carried between its scrapes.
+type instance struct {
+	config  setup.Config
+	metrics collector.Metrics
+	cache   *collector.Cache
+	// flight is per instance, as the collections are coalesced by scrapers and organizations only.
+	flight     *collector.Flight
+	background *collector.Background
+}
+
+// newInstances returns the instances to scrape: The one of --api-address, along with the ones of --instance.
+func newInstances(config setup.Config) []*instance {
+	configs := config.Instances
+	if len(configs) == 0 {
+		configs = []setup.Config{config}
+	}
+
+	instances := make([]*instance, 0, len(configs))
+	for _, c := range configs {
+		i := &instance{
+			config:  c,
+			metrics: collector.NewMetrics(),
+			cache:   collector.NewCache(c.CacheTTL),
+			flight:  collector.NewFlight(),
+		}
+		i.registerer(prometheus.DefaultRegisterer).MustRegister(i.cache)
+		instances = append(instances, i)
+	}
+
+	return instances
+}
+
+// findInstance returns the instance with the given name, or the one of --api-address if no name is given.
+func findInstance(instances []*instance, name string) (*instance, bool) {
+	if name == "" {
+		return instances[0], true
+	}
+
+	for _, i := range instances {
+		if i.config.Instance == name {
+			return i, true
+		}
+	}
+
+	return nil, false
+}
+
+// registerer returns a Registerer adding the instance label to the metrics of the instance, when several are scraped.
+func (i *instance) registerer(reg prometheus.Registerer) prometheus.Registerer {
+	if i.config.Instance == "" {
+		return reg
+	}
+
+	return prometheus.WrapRegistererWith(prometheus.Labels{"instance": i.config.Instance}, reg)
+}
+
+// collector returns the collector of the metrics of the instance: The latest background collection if any,
+// or a new collection bound to ctx, shared with the concurrent ones.
+func (i *instance) collector(ctx context.Context, config setup.Config, scrapers []collector.Scraper) prometheus.Collector {
+	if i.background != nil {
+		return i.background
+	}
+
+	return i.flight.Collector(collector.New(ctx, config, scrapers, i.metrics, i.cache))
+}
+
+// runBackground collects the metrics of the instance every interval until ctx is done.
+func (i *instance) runBackground(ctx context.Context, scrapers []collector.Scraper, interval time.Duration) {
+	i.background = collector.NewBackground(i.config, scrapers, i.metrics, i.cache)
+	go i.background.Run(ctx, interval)
+}
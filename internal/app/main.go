@@ -0,0 +1,686 @@
+// Package app is the tf_exporter command: It serves the metrics, or runs the other commands, as configured by
+// the command line.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/audit"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/collector"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/health"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/push"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/webhook"
+
+	"github.com/coreos/go-systemd/v22/activation"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
+)
+
+// BuildInfo describes the build of the exporter, populated at build-time via ldflags.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+}
+
+// Build information, set by Main.
+var (
+	Version   string
+	Commit    string
+	GoVersion = runtime.Version()
+	BuildDate string
+)
+
+// scrapeContext returns the request context (cancelled when the connection gets closed),
+// limited by the scrape timeout Prometheus sends in its headers, minus the configured offset.
+func scrapeContext(r *http.Request, config setup.Config) (context.Context, context.CancelFunc) {
+	ctx := r.Context()
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		timeoutSeconds, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			level.Error(config.Logger).Log("msg", "Failed to parse timeout from Prometheus header", "err", err)
+		} else {
+			timeout := time.Duration(timeoutSeconds * float64(time.Second))
+			if config.TimeoutOffset >= timeout {
+				level.Error(config.Logger).Log("msg", "Timeout offset should be lower than prometheus scrape timeout", "offset", config.TimeoutOffset, "timeout", timeout)
+			} else {
+				// Leaves time to send the metrics before Prometheus gives up on the scrape.
+				timeout -= config.TimeoutOffset
+			}
+			return context.WithTimeout(ctx, timeout)
+		}
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// newPushGatherer returns the metrics to push, collected on every push unless they are collected in the background.
+func newPushGatherer(scrapers []collector.Scraper, instances []*instance, config setup.Config) func(context.Context) prometheus.Gatherer {
+	return func(ctx context.Context) prometheus.Gatherer {
+		registry := prometheus.NewRegistry()
+		for _, i := range instances {
+			i.registerer(registry).MustRegister(i.collector(ctx, i.config, scrapers))
+		}
+
+		return exposed(withTelemetry(registry, config), config)
+	}
+}
+
+// events holds the metrics of the events received from or tailed off Terraform, exposed along with the scraped ones.
+var events = prometheus.NewRegistry()
+
+// withTelemetry returns the Terraform metrics, scraped and received,
+// along with the exporter's own metrics unless they're served on the telemetry address.
+func withTelemetry(registry *prometheus.Registry, config setup.Config) prometheus.Gatherer {
+	if config.TelemetryAddress != "" {
+		return prometheus.Gatherers{
+			events,
+			registry,
+		}
+	}
+
+	return prometheus.Gatherers{
+		prometheus.DefaultGatherer,
+		events,
+		registry,
+	}
+}
+
+// exposed returns the metrics as they're exposed: With their labels normalized or hashed, in the configured namespace
+// and with the configured labels.
+func exposed(g prometheus.Gatherer, config setup.Config) prometheus.Gatherer {
+	g = collector.WithLabelNormalization(g, labelNormalization(config))
+	return collector.WithConstLabels(collector.WithNamespace(g, config.MetricNamespace), config.Labels)
+}
+
+// labelNormalization returns the normalization and hashing of the label values configured by the --labels.* flags.
+func labelNormalization(config setup.Config) collector.LabelNormalization {
+	n := collector.LabelNormalization{
+		Labels:                 config.LabelsNormalize,
+		LowercaseOrganizations: config.LabelsLowercaseOrganizations,
+		Replacement:            config.LabelsReplacement,
+		MaxLength:              config.LabelsMaxLength,
+		Hashed:                 config.LabelsHash,
+		HashKey:                config.LabelsHashKey,
+	}
+	if config.LabelsReplaceCharacters != "" {
+		// The expression was validated when parsing the flags.
+		n.Disallowed = regexp.MustCompile(config.LabelsReplaceCharacters)
+	}
+
+	return n
+}
+
+// selectScrapers returns the registered scrapers matching the given names, or the enabled ones if none is given.
+func selectScrapers(names []string, enabled []collector.Scraper) ([]collector.Scraper, error) {
+	if len(names) == 0 {
+		return enabled, nil
+	}
+
+	return collector.FilterScrapers(names)
+}
+
+func newHandler(enabled []collector.Scraper, instances []*instance, config setup.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Scrapes can be scoped with: /metrics?collect[]=<scraper>&org[]=<organization>
+		scrapers, err := selectScrapers(r.URL.Query()["collect[]"], enabled)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		organizations := r.URL.Query()["org[]"]
+
+		ctx, cancel := scrapeContext(r, config)
+		defer cancel()
+
+		// Concurrent scrapes of the same scrapers and organizations share a single collection.
+		registry := prometheus.NewRegistry()
+		for _, i := range instances {
+			config := i.config
+			if len(organizations) > 0 {
+				config.Organizations = organizations
+			}
+			i.registerer(registry).MustRegister(i.collector(ctx, config, scrapers))
+		}
+
+		gatherers := exposed(withTelemetry(registry, config), config)
+		// Delegate http serving to Prometheus client library, which will call collector.Collect.
+		h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{EnableOpenMetrics: config.OpenMetricsEnabled()})
+		h.ServeHTTP(w, r)
+	}
+}
+
+// newProbeHandler implements the multi-target exporter pattern, scraping a single organization per request:
+// /probe?organization=<name>&collect=<scraper1>,<scraper2>&instance=<name>
+func newProbeHandler(enabled []collector.Scraper, instances []*instance) http.HandlerFunc {
+	flights := make(map[*instance]*collector.Flight, len(instances))
+	for _, i := range instances {
+		flights[i] = collector.NewFlight()
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		organization := r.URL.Query().Get("organization")
+		if organization == "" {
+			http.Error(w, "organization parameter is missing", http.StatusBadRequest)
+			return
+		}
+		instance, ok := findInstance(instances, r.URL.Query().Get("instance"))
+		if !ok {
+			http.Error(w, "unknown instance: "+r.URL.Query().Get("instance"), http.StatusBadRequest)
+			return
+		}
+		config := instance.config
+
+		var names []string
+		if collect := r.URL.Query().Get("collect"); collect != "" {
+			names = strings.Split(collect, ",")
+		}
+		scrapers, err := selectScrapers(names, enabled)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := scrapeContext(r, config)
+		defer cancel()
+
+		// The target organization is scraped even if it belongs to another shard.
+		config.Organizations = []string{organization}
+		config.Shard = setup.Shard{}
+		registry := prometheus.NewRegistry()
+		instance.registerer(registry).MustRegister(flights[instance].Collector(collector.New(ctx, config, scrapers, collector.NewMetrics(), instance.cache)))
+
+		// Probes only expose the metrics of the requested target.
+		h := promhttp.HandlerFor(exposed(registry, config), promhttp.HandlerOpts{EnableOpenMetrics: config.OpenMetricsEnabled()})
+		h.ServeHTTP(w, r)
+	}
+}
+
+// newBackgroundHandler serves the metrics of the latest background collection of every instance.
+func newBackgroundHandler(instances []*instance, config setup.Config) http.HandlerFunc {
+	registry := prometheus.NewRegistry()
+	for _, i := range instances {
+		i.registerer(registry).MustRegister(i.background)
+	}
+
+	gatherers := exposed(withTelemetry(registry, config), config)
+	// Metrics are served from the latest background collection, so no request context is needed.
+	h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{EnableOpenMetrics: config.OpenMetricsEnabled()})
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The background collection can't be scoped, so scoped scrapes fail instead of getting every metric.
+		if q := r.URL.Query(); len(q["collect[]"]) > 0 || len(q["org[]"]) > 0 {
+			http.Error(w, "collect[] and org[] aren't supported with --collect-interval, as metrics are collected in the background", http.StatusBadRequest)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+}
+
+// metricDocs documents the metrics of the scrapers, with the names in the configured namespace.
+func metricDocs(scrapers []collector.Scraper, config setup.Config) ([]collector.MetricDoc, error) {
+	docs, err := collector.Docs(scrapers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		docs[i].Name = collector.RenameNamespace(docs[i].Name, config.MetricNamespace)
+	}
+
+	return docs, nil
+}
+
+// newDocsHandler lists every metric the registered scrapers can emit, with their help and labels, as JSON.
+func newDocsHandler(scrapers []collector.Scraper, config setup.Config) (http.HandlerFunc, error) {
+	docs, err := metricDocs(scrapers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(docs)
+	}, nil
+}
+
+// newConfigHandler dumps the effective configuration, from the flags and the environment, as JSON with its secrets redacted.
+func newConfigHandler(config setup.Config) http.HandlerFunc {
+	cli := config.CLI.Redacted()
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(cli)
+	}
+}
+
+// healthStatus is the health of the exporter, served as JSON.
+type healthStatus struct {
+	Healthy bool `json:"healthy"`
+	// APIError is why the API can't be reached with the token, if it can't.
+	APIError  string           `json:"api_error,omitempty"`
+	Instances []instanceStatus `json:"instances"`
+}
+
+// instanceStatus is the outcome of the last run of the scrapers of an instance.
+type instanceStatus struct {
+	Instance string                    `json:"instance,omitempty"`
+	Scrapers []collector.ScraperStatus `json:"scrapers"`
+}
+
+// newHealthHandler reports whether the exporter is healthy, i.e. the API can be reached and the last run of every scraper
+// succeeded, along with when each scraper last ran, for how long, what it fetched and its last error, as JSON.
+func newHealthHandler(checker *health.Checker, instances []*instance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := healthStatus{Healthy: true}
+		if err := checker.Check(r.Context()); err != nil {
+			status.Healthy = false
+			status.APIError = err.Error()
+		}
+		for _, i := range instances {
+			status.Healthy = status.Healthy && i.metrics.Status.Healthy()
+			status.Instances = append(status.Instances, instanceStatus{
+				Instance: i.config.Instance,
+				Scrapers: i.metrics.Status.Scrapers(),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(status)
+	}
+}
+
+// newLogLevelHandler returns the current log level, or changes it at runtime on PUT/POST requests: level=<level>
+func newLogLevelHandler(config setup.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut || r.Method == http.MethodPost {
+			lvl := r.FormValue("level")
+			if err := config.SetLogLevel(lvl); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			level.Info(config.Logger).Log("msg", "Changed log level", "level", lvl)
+		}
+
+		_, _ = w.Write([]byte(config.CurrentLogLevel() + "\n"))
+	}
+}
+
+// newRefreshOrganizationsHandler forgets the organizations listed by every instance on POST requests,
+// so the next scrape lists them again instead of waiting for --organizations-discovery-ttl.
+func newRefreshOrganizationsHandler(instances []*instance, config setup.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Only POST requests refresh the organizations", http.StatusMethodNotAllowed)
+			return
+		}
+
+		for _, i := range instances {
+			i.metrics.Discovery.Refresh()
+		}
+		level.Info(config.Logger).Log("msg", "Refreshing the organizations on the next scrape")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newLandingPage describes the exporter build, its configured organizations and the state of every registered scraper,
+// or the scrapers of the background collection with --collect-interval.
+func newLandingPage(enabled []collector.Scraper, config setup.Config) (*web.LandingPageHandler, error) {
+	organizations := "all"
+	if len(config.Organizations) > 0 {
+		organizations = template.HTMLEscapeString(strings.Join(config.Organizations, ", "))
+	}
+
+	metrics := "Organizations: " + organizations
+	if config.CollectInterval > 0 {
+		names := make([]string, 0, len(enabled))
+		for _, scraper := range enabled {
+			names = append(names, scraper.Name())
+		}
+		metrics += fmt.Sprintf(", collected every %s by the scrapers: %s", config.CollectInterval, strings.Join(names, ", "))
+	}
+
+	links := []web.LandingLinks{
+		{Address: "/metrics", Text: "Metrics", Description: metrics},
+		{Address: "/healthz", Text: "Liveness"},
+		{Address: "/readyz", Text: "Readiness", Description: "Checks the connectivity and token against the Terraform API"},
+		{Address: "/health", Text: "Health", Description: "Readiness along with the outcome of the last run of every scraper, as JSON"},
+		{Address: "/metrics-docs", Text: "Metrics docs", Description: "Metrics every scraper can emit, with their help and labels"},
+		{Address: "/debug/config", Text: "Config", Description: "Effective configuration, with its secrets redacted"},
+	}
+	// Scrapes of the background collection can't be scoped to a scraper, so there's no link to each one.
+	scrapers := collector.Scrapers
+	if config.CollectInterval > 0 {
+		scrapers = nil
+	}
+	for _, scraper := range scrapers {
+		state := "disabled"
+		for _, e := range enabled {
+			if e.Name() == scraper.Name() {
+				state = "enabled"
+			}
+		}
+		links = append(links, web.LandingLinks{
+			Address:     "/metrics?collect[]=" + url.QueryEscape(scraper.Name()),
+			Text:        "Scraper " + scraper.Name(),
+			Description: fmt.Sprintf("%s (API %s, %s)", scraper.Help(), scraper.Version(), state),
+		})
+	}
+
+	return web.NewLandingPage(web.LandingConfig{
+		Name:        "Terraform Cloud/Enterprise Exporter",
+		Description: "Prometheus exporter for Terraform Cloud/Enterprise metrics",
+		Version:     fmt.Sprintf("%s (revision: %s, go: %s, build date: %s)", Version, Commit, GoVersion, BuildDate),
+		Links:       links,
+	})
+}
+
+// Main runs the exporter as configured by the command line, with the scrapers registered by then, e.g. by the
+// plugins imported by the main package. It exits with status 1 on errors.
+func Main(info BuildInfo) {
+	Version, Commit, BuildDate = info.Version, info.Commit, info.BuildDate
+
+	config, err := setup.NewConfig()
+	if err != nil {
+		err = fmt.Errorf("error setting up the exporter: %w", err)
+	} else {
+		err = run(config)
+	}
+	if err != nil {
+		level.Error(config.Logger).Log("msg", "Exiting on error", "err", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command of the config.
+func run(config setup.Config) error {
+	if config.GenericScrapersFile != "" {
+		if err := collector.RegisterGenericScrapers(config.GenericScrapersFile); err != nil {
+			return fmt.Errorf("error reading the generic scrapers: %w", err)
+		}
+	}
+
+	switch config.Command {
+	case "list-scrapers":
+		listScrapers(os.Stdout, collector.Scrapers)
+	case "docs":
+		docs, err := metricDocs(collector.Scrapers, config)
+		if err != nil {
+			return fmt.Errorf("error documenting the metrics: %w", err)
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(docs); err != nil {
+			return fmt.Errorf("error writing the metrics docs: %w", err)
+		}
+	case "backfill":
+		if err := runBackfill(config); err != nil {
+			return fmt.Errorf("backfill failed: %w", err)
+		}
+	case "check":
+		if err := check(os.Stdout, config); err != nil {
+			return fmt.Errorf("check failed: %w", err)
+		}
+	default:
+		// Cancelled on SIGTERM/SIGINT (or when the Windows service is stopped),
+		// which stops the background collection and any in-flight scrape.
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		return runService(ctx, config, serve)
+	}
+
+	return nil
+}
+
+// listen listens on the listen address, which can also be a unix socket: unix:///path/to/socket,
+// or returns the systemd activated listeners with --systemd-socket.
+func listen(config setup.Config) ([]net.Listener, error) {
+	if config.SystemdSocket {
+		level.Info(config.Logger).Log("msg", "Listening on systemd activated listeners instead of port listeners.")
+		listeners, err := activation.Listeners()
+		if err != nil {
+			return nil, err
+		}
+		if len(listeners) < 1 {
+			return nil, errors.New("no socket activation file descriptors found")
+		}
+		return listeners, nil
+	}
+
+	path := strings.TrimPrefix(config.ListenAddress, "unix://")
+	if path == config.ListenAddress {
+		listener, err := net.Listen("tcp", config.ListenAddress)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{listener}, nil
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []net.Listener{listener}, nil
+}
+
+// removeStaleSocket removes the socket left behind by an exporter that didn't shut down cleanly.
+// Anything else found at the path is left alone, failing instead.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s already exists and isn't a socket", path)
+	}
+
+	return os.Remove(path)
+}
+
+// serve exposes the metrics over http until ctx is done, or until it fails.
+func serve(ctx context.Context, config setup.Config) error {
+	level.Info(config.Logger).Log("msg", "Starting tf_exporter", "version", Version, "revision", Commit)
+	level.Debug(config.Logger).Log("msg", "Build Context", "go", GoVersion, "date", BuildDate)
+
+	scrapers, err := selectScrapers(config.Collect, collector.DefaultScrapers())
+	if err != nil {
+		return fmt.Errorf("invalid list of scrapers: %w", err)
+	}
+
+	if config.DisableRuntimeMetrics {
+		prometheus.Unregister(collectors.NewGoCollector())
+		prometheus.Unregister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	if config.Shard.Enabled() {
+		level.Info(config.Logger).Log("msg", "Scraping a shard of the organizations", "shard", config.Shard)
+	}
+
+	instances := newInstances(config)
+	handlerFunc := newHandler(scrapers, instances, config)
+	if config.CollectInterval > 0 {
+		level.Info(config.Logger).Log("msg", "Collecting metrics in the background", "interval", config.CollectInterval)
+		for _, i := range instances {
+			i.runBackground(ctx, scrapers, config.CollectInterval)
+		}
+		handlerFunc = newBackgroundHandler(instances, config)
+	}
+
+	pushers := []push.Pusher{}
+	if config.OTLPMetricsEndpoint != "" {
+		pushers = append(pushers, push.NewOTLP(config.OTLPMetricsEndpoint, config.OTLPMetricsInsecure))
+	}
+	if config.PushGatewayURL != "" {
+		pushers = append(pushers, push.NewPushgateway(config.PushGatewayURL, config.PushGatewayGrouping))
+	}
+	if config.RemoteWriteURL != "" {
+		remoteWrite, err := push.NewRemoteWrite(push.RemoteWriteConfig{
+			URL:                config.RemoteWriteURL,
+			BearerToken:        config.RemoteWriteBearerToken,
+			BearerTokenFile:    config.RemoteWriteBearerTokenFile,
+			CAFile:             config.RemoteWriteCAFile,
+			CertFile:           config.RemoteWriteCertFile,
+			KeyFile:            config.RemoteWriteKeyFile,
+			InsecureSkipVerify: config.RemoteWriteInsecureSkipVerify,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating remote write client: %w", err)
+		}
+		pushers = append(pushers, remoteWrite)
+	}
+	if len(pushers) > 0 {
+		level.Info(config.Logger).Log("msg", "Pushing metrics", "interval", config.PushInterval, "pushers", len(pushers))
+		go push.Run(ctx, config.PushInterval, newPushGatherer(scrapers, instances, config), pushers, config.Logger)
+	}
+	if config.WebhookEnabled {
+		receiver, err := webhook.NewReceiver(webhook.Config{
+			Token:          config.WebhookToken,
+			TokenFile:      config.WebhookTokenFile,
+			TokenFiles:     config.WebhookTokenFiles,
+			AllowUnsigned:  config.WebhookAllowUnsigned,
+			WorkspaceLabel: config.WebhookWorkspaceLabel,
+
+			NativeHistogramBucketFactor: config.NativeHistogramBucketFactor,
+		}, config.Logger)
+		if err != nil {
+			return fmt.Errorf("error creating webhook receiver: %w", err)
+		}
+		if config.WebhookAllowUnsigned && config.WebhookToken == "" && config.WebhookTokenFile == "" {
+			level.Warn(config.Logger).Log("msg", "Accepting unsigned run notifications on /webhook, set --webhook.token to validate them")
+		}
+		events.MustRegister(receiver)
+		http.Handle("/webhook", receiver)
+		http.Handle("/webhook/", receiver)
+	}
+
+	if config.AuditTrailEnabled {
+		tailer, err := audit.NewTailer(config.AuditTrails, config.AuditTrailBookmarkFile, config.Logger)
+		if err != nil {
+			return fmt.Errorf("error creating audit trail tailer: %w", err)
+		}
+		level.Info(config.Logger).Log("msg", "Tailing the audit trail", "interval", config.AuditTrailInterval)
+		events.MustRegister(tailer)
+		go tailer.Run(ctx, config.AuditTrailInterval)
+	}
+
+	http.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handlerFunc))
+	http.Handle("/probe", newProbeHandler(scrapers, instances))
+	http.HandleFunc("/healthz", health.LivenessHandler)
+	checker := health.NewChecker(config)
+	http.HandleFunc("/readyz", checker.ReadinessHandler)
+	http.HandleFunc("/health", newHealthHandler(checker, instances))
+	http.Handle("/-/loglevel", newLogLevelHandler(config))
+	http.HandleFunc("/-/refresh-organizations", newRefreshOrganizationsHandler(instances, config))
+	docsHandler, err := newDocsHandler(collector.Scrapers, config)
+	if err != nil {
+		return fmt.Errorf("error documenting the metrics: %w", err)
+	}
+	http.HandleFunc("/metrics-docs", docsHandler)
+	http.HandleFunc("/debug/config", newConfigHandler(config))
+
+	landingPage, err := newLandingPage(scrapers, config)
+	if err != nil {
+		return fmt.Errorf("error creating landing page: %w", err)
+	}
+	http.Handle("/", landingPage)
+
+	level.Info(config.Logger).Log("msg", "Listening on address", "address", config.ListenAddress)
+	srv := &http.Server{
+		// Request contexts derive from ctx, so in-flight scrapes get cancelled on shutdown.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	webConfigFile := ""
+	flags := &web.FlagConfig{
+		WebListenAddresses: &[]string{config.ListenAddress},
+		WebSystemdSocket:   &config.SystemdSocket,
+		WebConfigFile:      &webConfigFile,
+	}
+
+	listeners, err := listen(config)
+	if err != nil {
+		return fmt.Errorf("error starting HTTP server: %w", err)
+	}
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- web.ServeMultiple(listeners, srv, flags, config.Logger)
+	}()
+
+	var telemetrySrv *http.Server
+	if config.TelemetryAddress != "" {
+		level.Info(config.Logger).Log("msg", "Serving the exporter metrics on telemetry address", "address", config.TelemetryAddress)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(exposed(prometheus.DefaultGatherer, config), promhttp.HandlerOpts{EnableOpenMetrics: config.OpenMetricsEnabled()}))
+		telemetrySrv = &http.Server{Handler: mux}
+		telemetryFlags := &web.FlagConfig{
+			WebListenAddresses: &[]string{config.TelemetryAddress},
+			WebSystemdSocket:   new(bool),
+			WebConfigFile:      &webConfigFile,
+		}
+		telemetryListener, err := net.Listen("tcp", config.TelemetryAddress)
+		if err != nil {
+			srv.Close()
+			return fmt.Errorf("error starting telemetry HTTP server: %w", err)
+		}
+		go func() {
+			errCh <- web.Serve(telemetryListener, telemetrySrv, telemetryFlags, config.Logger)
+		}()
+	}
+	// systemd is only told the exporter is ready once it's listening.
+	go notifySystemd(ctx, checker.Check, config.Logger)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("error starting HTTP server: %w", err)
+	case <-ctx.Done():
+	}
+
+	level.Info(config.Logger).Log("msg", "Shutting down HTTP server", "timeout", config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down HTTP server: %w", err)
+	}
+	if telemetrySrv != nil {
+		if err := telemetrySrv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("error shutting down telemetry HTTP server: %w", err)
+		}
+	}
+	config.Close()
+	if err := config.ShutdownTracing(shutdownCtx); err != nil {
+		return fmt.Errorf("error flushing traces: %w", err)
+	}
+
+	return nil
+}
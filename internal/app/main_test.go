@@ -0,0 +1,61 @@
+package app
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	convey.Convey("A socket left behind is removed", t, func() {
+		path := filepath.Join(dir, "stale.sock")
+		listener, err := net.Listen("unix", path)
+		convey.So(err, convey.ShouldBeNil)
+		listener.(*net.UnixListener).SetUnlinkOnClose(false)
+		listener.Close()
+
+		convey.So(removeStaleSocket(path), convey.ShouldBeNil)
+		_, err = os.Lstat(path)
+		convey.So(os.IsNotExist(err), convey.ShouldBeTrue)
+	})
+
+	convey.Convey("A missing socket is fine", t, func() {
+		convey.So(removeStaleSocket(filepath.Join(dir, "missing.sock")), convey.ShouldBeNil)
+	})
+
+	convey.Convey("Anything else is left alone", t, func() {
+		path := filepath.Join(dir, "file")
+		convey.So(os.WriteFile(path, []byte("data"), 0o600), convey.ShouldBeNil)
+
+		convey.So(removeStaleSocket(path), convey.ShouldNotBeNil)
+		_, err := os.Lstat(path)
+		convey.So(err, convey.ShouldBeNil)
+	})
+}
+
+func TestBackgroundHandler(t *testing.T) {
+	handler := newBackgroundHandler(nil, setup.Config{})
+
+	convey.Convey("Serves the latest background collection", t, func() {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		convey.So(w.Code, convey.ShouldEqual, http.StatusOK)
+	})
+
+	convey.Convey("Rejects scoped scrapes, which it can't scope", t, func() {
+		for _, target := range []string{"/metrics?collect[]=workspaces", "/metrics?org[]=test-org"} {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+			convey.So(w.Code, convey.ShouldEqual, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package app
+
+import (
+	"context"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+)
+
+// runService runs the exporter until ctx is done, returning its error if it fails.
+func runService(ctx context.Context, config setup.Config, run func(context.Context, setup.Config) error) error {
+	return run(ctx, config)
+}
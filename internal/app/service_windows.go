@@ -0,0 +1,81 @@
+//go:build windows
+
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log/level"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// serviceFailedExitCode is the service-specific exit code reported when the exporter fails,
+// so the service manager considers the service failed and applies its recovery actions, e.g. restarting it.
+const serviceFailedExitCode = 1
+
+// runService runs the exporter until ctx is done or, when started by the Windows service manager,
+// until the service is stopped, returning its error if it fails.
+func runService(ctx context.Context, config setup.Config, run func(context.Context, setup.Config) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("error detecting the Windows service: %w", err)
+	}
+	if !isService {
+		return run(ctx, config)
+	}
+
+	service := &windowsService{ctx: ctx, config: config, run: run}
+	if err := svc.Run(setup.ServiceName, service); err != nil {
+		return fmt.Errorf("error running the Windows service: %w", err)
+	}
+
+	return service.err
+}
+
+// windowsService implements the svc.Handler interface, stopping the exporter on Stop/Shutdown requests.
+type windowsService struct {
+	ctx    context.Context
+	config setup.Config
+	run    func(context.Context, setup.Config) error
+	// err is the error the exporter failed with, if any.
+	err error
+}
+
+// Execute implements the svc.Handler interface.
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.run(ctx, s.config)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				level.Info(s.config.Logger).Log("msg", "Stopping the Windows service")
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				s.err = <-done
+				return false, 0
+			}
+		case s.err = <-done:
+			// The exporter only stops on its own when it fails, or once the context it was run with is done.
+			if s.err == nil {
+				return false, 0
+			}
+			level.Error(s.config.Logger).Log("msg", "The exporter failed, failing the Windows service", "err", s.err)
+			return true, serviceFailedExitCode
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// notifySystemd tells systemd the exporter is ready and keeps its watchdog fed until ctx is done,
+// when the exporter runs as a Type=notify service. It does nothing otherwise.
+// The watchdog is only fed while check, the readiness check of /readyz, passes, so systemd restarts
+// an exporter that can't reach the Terraform API.
+func notifySystemd(ctx context.Context, check func(context.Context) error, logger log.Logger) {
+	if ok, err := daemon.SdNotify(false, daemon.SdNotifyReady); err != nil {
+		level.Warn(logger).Log("msg", "Error notifying systemd", "err", err)
+		return
+	} else if !ok {
+		return
+	}
+
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil || interval == 0 {
+		return
+	}
+
+	// Notify twice per interval, as recommended by systemd.
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// A check reused from the cache doesn't wait, a new one waits up to its timeout.
+			checkCtx, cancel := context.WithTimeout(ctx, interval/2)
+			err := check(checkCtx)
+			cancel()
+			if err != nil {
+				level.Warn(logger).Log("msg", "Not feeding the systemd watchdog, the exporter isn't ready", "err", err)
+				continue
+			}
+			_, _ = daemon.SdNotify(false, daemon.SdNotifyWatchdog)
+		case <-ctx.Done():
+			_, _ = daemon.SdNotify(false, daemon.SdNotifyStopping)
+			return
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package audit tails the audit trail of a Terraform Cloud organization,
+// counting its events as they happen instead of shipping them to a separate log pipeline.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "tf"
+	subsystem = "audit"
+
+	// pageSize is the maximum page size allowed by the API.
+	pageSize = 100
+)
+
+// Tailer polls the audit trail for the events after its bookmark, the timestamp of the last event counted.
+type Tailer struct {
+	client       tfe.AuditTrails
+	bookmarkFile string
+	logger       log.Logger
+
+	since  time.Time
+	events *prometheus.CounterVec
+}
+
+// NewTailer returns a new Tailer starting from the bookmark saved to the file, if any,
+// or from now on otherwise, so past events aren't counted on every restart.
+func NewTailer(client tfe.AuditTrails, bookmarkFile string, logger log.Logger) (*Tailer, error) {
+	t := &Tailer{
+		client:       client,
+		bookmarkFile: bookmarkFile,
+		logger:       logger,
+		since:        time.Now().UTC(),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "events_total",
+			Help:      "Total number of events of the audit trail, by actor type, action and resource type.",
+		}, []string{"auth_type", "action", "resource_type"}),
+	}
+
+	if bookmarkFile == "" {
+		return t, nil
+	}
+	b, err := os.ReadFile(bookmarkFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading audit trail bookmark: %w", err)
+	}
+	if t.since, err = time.Parse(time.RFC3339Nano, strings.TrimSpace(string(b))); err != nil {
+		return nil, fmt.Errorf("parsing audit trail bookmark: %w", err)
+	}
+
+	return t, nil
+}
+
+// Describe implements the prometheus.Collector interface.
+func (t *Tailer) Describe(ch chan<- *prometheus.Desc) {
+	t.events.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (t *Tailer) Collect(ch chan<- prometheus.Metric) {
+	t.events.Collect(ch)
+}
+
+// Run polls the audit trail on every interval, until the context is cancelled.
+func (t *Tailer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.poll(ctx); err != nil {
+			level.Error(t.logger).Log("msg", "Error polling the audit trail", "since", t.since, "err", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll counts the events after the bookmark and moves it to the last one.
+// The bookmark only moves once every page is read, so a failing poll is retried from the same point.
+func (t *Tailer) poll(ctx context.Context) error {
+	last := t.since
+	var events []*tfe.AuditTrail
+	for page, totalPages := 1, 1; page <= totalPages; page++ {
+		list, err := t.client.List(ctx, &tfe.AuditTrailListOptions{
+			Since:       t.since,
+			ListOptions: &tfe.ListOptions{PageNumber: page, PageSize: pageSize},
+		})
+		if err != nil {
+			return fmt.Errorf("%w, (page=%d)", err, page)
+		}
+		if list.Pagination != nil {
+			totalPages = list.Pagination.TotalPages
+		}
+		events = append(events, list.Items...)
+	}
+
+	for _, e := range events {
+		// Events at the bookmark were already counted.
+		if !e.Timestamp.After(t.since) {
+			continue
+		}
+		t.events.WithLabelValues(e.Auth.Type, e.Resource.Action, e.Resource.Type).Inc()
+		if e.Timestamp.After(last) {
+			last = e.Timestamp
+		}
+	}
+	level.Debug(t.logger).Log("msg", "Polled the audit trail", "since", t.since, "events", len(events))
+
+	if last.Equal(t.since) {
+		return nil
+	}
+	t.since = last
+
+	return t.saveBookmark()
+}
+
+// saveBookmark writes the bookmark to a temporary file first, so it's never left half written.
+func (t *Tailer) saveBookmark() error {
+	if t.bookmarkFile == "" {
+		return nil
+	}
+
+	tmp := t.bookmarkFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(t.since.Format(time.RFC3339Nano)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("saving audit trail bookmark: %w", err)
+	}
+
+	return os.Rename(tmp, t.bookmarkFile)
+}
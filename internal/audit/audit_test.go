@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// fakeAuditTrails returns the events after since, two per page.
+type fakeAuditTrails struct {
+	events []*tfe.AuditTrail
+}
+
+func (f *fakeAuditTrails) List(ctx context.Context, options *tfe.AuditTrailListOptions) (*tfe.AuditTrailList, error) {
+	var events []*tfe.AuditTrail
+	for _, e := range f.events {
+		if e.Timestamp.After(options.Since) {
+			events = append(events, e)
+		}
+	}
+
+	start, end := (options.PageNumber-1)*2, options.PageNumber*2
+	if end > len(events) {
+		end = len(events)
+	}
+
+	return &tfe.AuditTrailList{
+		Pagination: &tfe.Pagination{TotalPages: (len(events) + 1) / 2},
+		Items:      events[start:end],
+	}, nil
+}
+
+func event(t time.Time, action string) *tfe.AuditTrail {
+	return &tfe.AuditTrail{
+		Timestamp: t,
+		Auth:      tfe.AuditTrailAuth{Type: "Client"},
+		Resource:  tfe.AuditTrailResource{Type: "workspace", Action: action},
+	}
+}
+
+func TestTailerPoll(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	bookmark := filepath.Join(t.TempDir(), "bookmark")
+	if err := os.WriteFile(bookmark, []byte(start.Format(time.RFC3339Nano)), 0o600); err != nil {
+		t.Fatalf("error writing test bookmark: %s", err)
+	}
+
+	client := &fakeAuditTrails{events: []*tfe.AuditTrail{
+		event(start.Add(-time.Minute), "create"),
+		event(start.Add(time.Minute), "create"),
+		event(start.Add(2*time.Minute), "update"),
+		event(start.Add(3*time.Minute), "update"),
+	}}
+	tailer, err := NewTailer(client, bookmark, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	convey.Convey("Events after the bookmark", t, func() {
+		convey.So(tailer.poll(context.Background()), convey.ShouldBeNil)
+		convey.So(testutil.ToFloat64(tailer.events.WithLabelValues("Client", "create", "workspace")), convey.ShouldEqual, 1)
+		convey.So(testutil.ToFloat64(tailer.events.WithLabelValues("Client", "update", "workspace")), convey.ShouldEqual, 2)
+
+		saved, err := os.ReadFile(bookmark)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(strings.TrimSpace(string(saved)), convey.ShouldEqual, start.Add(3*time.Minute).Format(time.RFC3339Nano))
+	})
+
+	convey.Convey("Events aren't counted twice", t, func() {
+		client.events = append(client.events, event(start.Add(4*time.Minute), "delete"))
+		convey.So(tailer.poll(context.Background()), convey.ShouldBeNil)
+		convey.So(testutil.ToFloat64(tailer.events.WithLabelValues("Client", "update", "workspace")), convey.ShouldEqual, 2)
+		convey.So(testutil.ToFloat64(tailer.events.WithLabelValues("Client", "delete", "workspace")), convey.ShouldEqual, 1)
+	})
+}
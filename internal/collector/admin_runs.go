@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// admin_runs is the Metric subsystem we use.
+	adminRunsSubsystem = "admin_runs"
+)
+
+// Metric descriptors.
+var (
+	AdminRunsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminRunsSubsystem, "count"),
+		"Number of runs of the Terraform Enterprise installation, by their current status",
+		[]string{"status"}, nil,
+	)
+	AdminRunsPending = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminRunsSubsystem, "pending_count"),
+		"Number of runs of the Terraform Enterprise installation waiting for their workspace or for capacity",
+		nil, nil,
+	)
+	AdminRunsActive = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminRunsSubsystem, "active_count"),
+		"Number of runs of the Terraform Enterprise installation using capacity, i.e. planning, applying or running checks",
+		nil, nil,
+	)
+)
+
+// adminRunsQueuePath lists a single run, only the status counts of the queue are needed.
+const adminRunsQueuePath = "admin/runs?page%5Bsize%5D=1"
+
+// activeRunStatuses are the statuses of the runs holding a worker of the installation.
+var activeRunStatuses = map[tfe.RunStatus]bool{
+	tfe.RunFetching:        true,
+	tfe.RunPlanning:        true,
+	tfe.RunPostPlanRunning: true,
+	tfe.RunCostEstimating:  true,
+	tfe.RunPolicyChecking:  true,
+	tfe.RunApplying:        true,
+}
+
+// ScrapeAdminRuns scrapes the run queue of the Terraform Enterprise installation.
+type ScrapeAdminRuns struct{}
+
+func init() {
+	Register(ScrapeAdminRuns{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeAdminRuns) Name() string {
+	return adminRunsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeAdminRuns) Help() string {
+	return "Scrape the run queue of Terraform Enterprise from the Admin Runs API, requires a site admin token: https://www.terraform.io/enterprise/api-docs/admin/runs"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeAdminRuns) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeAdminRuns) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AdminRunsCount
+	ch <- AdminRunsPending
+	ch <- AdminRunsActive
+}
+
+func (ScrapeAdminRuns) siteWide() {}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeAdminRuns) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) (err error) {
+	ctx, span := tracer.Start(ctx, "admin runs queue")
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	// The go-tfe client drops the status counts of the meta of the list.
+	var queue struct {
+		Meta struct {
+			StatusCounts map[string]int `json:"status-counts"`
+		} `json:"meta"`
+	}
+	err = config.Pool.Do(ctx, func(ctx context.Context) error {
+		return config.API.GetJSON(ctx, adminRunsQueuePath, &queue)
+	})
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]string, 0, len(queue.Meta.StatusCounts))
+	for status := range queue.Meta.StatusCounts {
+		if status != "total" {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+
+	var pending, active int
+	metrics := make([]prometheus.Metric, 0, len(statuses)+2)
+	for _, s := range statuses {
+		count := queue.Meta.StatusCounts[s]
+		// Same statuses as the ones of the runs scraper, e.g. plan_queued.
+		status := tfe.RunStatus(strings.ReplaceAll(s, "-", "_"))
+		switch {
+		case pendingStatuses[status]:
+			pending += count
+		case activeRunStatuses[status]:
+			active += count
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(AdminRunsCount, prometheus.GaugeValue, float64(count), string(status)))
+	}
+	metrics = append(metrics,
+		prometheus.MustNewConstMetric(AdminRunsPending, prometheus.GaugeValue, float64(pending)),
+		prometheus.MustNewConstMetric(AdminRunsActive, prometheus.GaugeValue, float64(active)),
+	)
+
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeAdminRuns(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/admin/runs":
+			if got := r.URL.Query().Get("page[size]"); got != "1" {
+				t.Errorf("unexpected page size: %s", got)
+			}
+			w.Write([]byte(`{
+				"meta":{
+					"status-counts":{"pending":2,"plan-queued":3,"planning":4,"applying":1,"applied":10,"total":20},
+					"pagination":{"current-page":1,"total-pages":20,"total-count":20}
+				},
+				"data":[{"id":"run-1","type":"runs","attributes":{"status":"applied"}}]
+			}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		API: api,
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeAdminRuns{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"status": "applied"}, value: 10, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"status": "applying"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"status": "pending"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"status": "plan_queued"}, value: 3, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"status": "planning"}, value: 4, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{}, value: 5, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{}, value: 5, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+	})
+}
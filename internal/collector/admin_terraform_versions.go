@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// admin_terraform_versions is the Metric subsystem we use.
+	adminTerraformVersionsSubsystem = "admin_terraform_versions"
+)
+
+// Metric descriptors.
+var (
+	AdminTerraformVersionsDeprecatedWorkspaces = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminTerraformVersionsSubsystem, "deprecated_workspaces_count"),
+		"Number of workspaces using a deprecated or disabled Terraform version of the installation, by version and state (deprecated or disabled)",
+		[]string{"version", "state"}, nil,
+	)
+)
+
+// adminTerraformVersionsFields are the only fields of the Terraform versions needed.
+var adminTerraformVersionsFields = setup.Fields{"terraform-versions": {"version", "deprecated", "enabled", "usage"}}
+
+// ScrapeAdminTerraformVersions scrapes the usage of the Terraform versions of the Terraform Enterprise installation.
+type ScrapeAdminTerraformVersions struct{}
+
+func init() {
+	Register(ScrapeAdminTerraformVersions{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeAdminTerraformVersions) Name() string {
+	return adminTerraformVersionsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeAdminTerraformVersions) Help() string {
+	return "Scrape the usage of the Terraform versions of Terraform Enterprise from the Admin Terraform Versions API, requires a site admin token: https://www.terraform.io/enterprise/api-docs/admin/terraform-versions"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeAdminTerraformVersions) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeAdminTerraformVersions) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AdminTerraformVersionsDeprecatedWorkspaces
+}
+
+func (ScrapeAdminTerraformVersions) siteWide() {}
+
+func getAdminTerraformVersionsPage(ctx context.Context, page int, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.AdminTerraformVersionsList, err error) {
+	ctx, span := tracer.Start(ctx, "admin terraform versions page", trace.WithAttributes(attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var versionsList *tfe.AdminTerraformVersionsList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		versionsList, err = config.Client.Admin.TerraformVersions.List(setup.WithFields(ctx, adminTerraformVersionsFields), &tfe.AdminTerraformVersionsListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return versionsList, fmt.Errorf("%w, (page=%d)", err, page)
+	}
+
+	for _, v := range versionsList.Items {
+		// Only the versions in use need attention, the API counts their workspaces.
+		if v.Usage == 0 || (v.Enabled && !v.Deprecated) {
+			continue
+		}
+		state := "deprecated"
+		if !v.Enabled {
+			state = "disabled"
+		}
+
+		select {
+		case ch <- prometheus.MustNewConstMetric(AdminTerraformVersionsDeprecatedWorkspaces, prometheus.GaugeValue, float64(v.Usage), v.Version, state):
+		case <-ctx.Done():
+			return versionsList, ctx.Err()
+		}
+	}
+
+	return versionsList, nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeAdminTerraformVersions) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	list, err := getAdminTerraformVersionsPage(ctx, 1, config, ch)
+	if err != nil {
+		return err
+	}
+
+	return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+		_, err := getAdminTerraformVersionsPage(ctx, page, config, ch)
+		return err
+	})
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeAdminTerraformVersions(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/admin/terraform-versions":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":4}},
+				"data":[
+					{"id":"tool-1","type":"terraform-versions","attributes":{"version":"1.4.0","enabled":true,"deprecated":false,"usage":10}},
+					{"id":"tool-2","type":"terraform-versions","attributes":{"version":"0.13.7","enabled":true,"deprecated":true,"usage":3}},
+					{"id":"tool-3","type":"terraform-versions","attributes":{"version":"0.12.31","enabled":false,"deprecated":true,"usage":1}},
+					{"id":"tool-4","type":"terraform-versions","attributes":{"version":"0.11.14","enabled":false,"deprecated":true,"usage":0}}
+				]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeAdminTerraformVersions{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"version": "0.13.7", "state": "deprecated"}, value: 3, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"version": "0.12.31", "state": "disabled"}, value: 1, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+		_, open := <-ch
+		convey.So(open, convey.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// admin_tool_versions is the Metric subsystem we use.
+	adminToolVersionsSubsystem = "admin_tool_versions"
+)
+
+// Metric descriptors.
+var (
+	AdminToolVersionsWorkspaces = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminToolVersionsSubsystem, "workspaces_count"),
+		"Number of workspaces of the Terraform Enterprise installation using each version of a tool (terraform, sentinel or opa), for the versions in use",
+		[]string{"tool", "version"}, nil,
+	)
+)
+
+// adminTools are the tools whose versions are managed by the installation, by the type of their versions.
+var adminTools = map[string]string{
+	"terraform": "terraform-versions",
+	"sentinel":  "sentinel-versions",
+	"opa":       "opa-versions",
+}
+
+// toolVersionsPage is a page of the versions of a tool, decoded as plain JSON so the same one fits every tool.
+type toolVersionsPage struct {
+	Data []struct {
+		Attributes struct {
+			Version string `json:"version"`
+			Usage   int    `json:"usage"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination *tfe.Pagination `json:"pagination"`
+	} `json:"meta"`
+}
+
+// ScrapeAdminToolVersions scrapes the number of workspaces using each version of the tools of the Terraform Enterprise installation.
+type ScrapeAdminToolVersions struct{}
+
+func init() {
+	Register(ScrapeAdminToolVersions{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeAdminToolVersions) Name() string {
+	return adminToolVersionsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeAdminToolVersions) Help() string {
+	return "Scrape the number of workspaces using each Terraform, Sentinel and OPA version of Terraform Enterprise from the Admin Terraform, Sentinel and OPA Versions APIs, requires a site admin token: https://www.terraform.io/enterprise/api-docs/admin/terraform-versions"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeAdminToolVersions) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeAdminToolVersions) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AdminToolVersionsWorkspaces
+}
+
+func (ScrapeAdminToolVersions) siteWide() {}
+
+// getToolVersions sends the number of workspaces using each version of the tool, reading the usage the API counts
+// for every version, instead of going through the workspaces of every organization.
+func getToolVersions(ctx context.Context, tool, versionsType string, config *setup.Config, ch chan<- prometheus.Metric) (err error) {
+	ctx, span := tracer.Start(ctx, "admin tool versions", trace.WithAttributes(attribute.String("tool", tool)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	ctx = setup.WithFields(ctx, setup.Fields{versionsType: {"version", "usage"}})
+	for page := 1; ; page++ {
+		query := url.Values{
+			"page[number]": {strconv.Itoa(page)},
+			"page[size]":   {strconv.Itoa(pageSize)},
+		}
+		var versions toolVersionsPage
+		err := config.Pool.Do(ctx, func(ctx context.Context) error {
+			return config.API.GetJSON(ctx, "admin/"+versionsType+"?"+query.Encode(), &versions)
+		})
+		// Older releases don't manage the Sentinel or OPA versions.
+		if errors.Is(err, tfe.ErrResourceNotFound) && tool != "terraform" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w, (tool=%s, page=%d)", err, tool, page)
+		}
+
+		for _, v := range versions.Data {
+			if v.Attributes.Usage == 0 {
+				continue
+			}
+
+			select {
+			case ch <- prometheus.MustNewConstMetric(AdminToolVersionsWorkspaces, prometheus.GaugeValue, float64(v.Attributes.Usage), tool, v.Attributes.Version):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if versions.Meta.Pagination == nil || page >= versions.Meta.Pagination.TotalPages || pageLimitReached(ctx, page) {
+			return nil
+		}
+	}
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeAdminToolVersions) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing tool doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for tool, versionsType := range adminTools {
+		tool, versionsType := tool, versionsType
+		g.Go(func() error {
+			return getToolVersions(ctx, tool, versionsType, config, ch)
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeAdminToolVersions(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/admin/terraform-versions":
+			if got := r.URL.Query().Get("page[size]"); got != "40" {
+				t.Errorf("unexpected page size: %s", got)
+			}
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":3}},
+				"data":[
+					{"id":"tool-1","type":"terraform-versions","attributes":{"version":"1.4.0","usage":10}},
+					{"id":"tool-2","type":"terraform-versions","attributes":{"version":"0.13.7","usage":3}},
+					{"id":"tool-3","type":"terraform-versions","attributes":{"version":"0.12.31","usage":0}}
+				]
+			}`))
+		case "/api/v2/admin/opa-versions":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"tool-4","type":"opa-versions","attributes":{"version":"0.44.0","usage":2}}]
+			}`))
+		case "/api/v2/admin/sentinel-versions":
+			// Not managed by older releases.
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		API: api,
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeAdminToolVersions{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// The tools are scraped concurrently, so their metrics come in any order.
+	var got []MetricResult
+	for m := range ch {
+		got = append(got, readMetric(m))
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldHaveLength, 3)
+		convey.So(got, convey.ShouldContain, MetricResult{labels: labelMap{"tool": "terraform", "version": "1.4.0"}, value: 10, metricType: dto.MetricType_GAUGE})
+		convey.So(got, convey.ShouldContain, MetricResult{labels: labelMap{"tool": "terraform", "version": "0.13.7"}, value: 3, metricType: dto.MetricType_GAUGE})
+		convey.So(got, convey.ShouldContain, MetricResult{labels: labelMap{"tool": "opa", "version": "0.44.0"}, value: 2, metricType: dto.MetricType_GAUGE})
+	})
+}
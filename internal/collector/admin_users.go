@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// admin_users is the Metric subsystem we use.
+	adminUsersSubsystem = "admin_users"
+)
+
+// Metric descriptors.
+var (
+	AdminUsersCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminUsersSubsystem, "count"),
+		"Number of users of the Terraform Enterprise installation, excluding the service accounts",
+		nil, nil,
+	)
+	AdminUsersSiteAdmins = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminUsersSubsystem, "site_admins_count"),
+		"Number of site administrators of the Terraform Enterprise installation",
+		nil, nil,
+	)
+	AdminUsersTwoFactorRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, adminUsersSubsystem, "two_factor_ratio"),
+		"Ratio of the users of the Terraform Enterprise installation with two-factor authentication enabled, excluding the service accounts",
+		nil, nil,
+	)
+)
+
+// adminUsersFields are the only fields of the users aggregated.
+var adminUsersFields = setup.Fields{"users": {"two-factor", "is-admin", "is-service-account"}}
+
+// ScrapeAdminUsers scrapes the users of the Terraform Enterprise installation, aggregated.
+type ScrapeAdminUsers struct{}
+
+func init() {
+	Register(ScrapeAdminUsers{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeAdminUsers) Name() string {
+	return adminUsersSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeAdminUsers) Help() string {
+	return "Scrape the users of Terraform Enterprise from the Admin Users API, requires a site admin token: https://www.terraform.io/enterprise/api-docs/admin/users"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeAdminUsers) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeAdminUsers) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AdminUsersCount
+	ch <- AdminUsersSiteAdmins
+	ch <- AdminUsersTwoFactorRatio
+}
+
+func (ScrapeAdminUsers) siteWide() {}
+
+// adminUserStats aggregates the users of the installation, from concurrent pages.
+type adminUserStats struct {
+	users, siteAdmins, twoFactor int64
+}
+
+func (s *adminUserStats) add(u *tfe.AdminUser) {
+	if u.IsAdmin {
+		atomic.AddInt64(&s.siteAdmins, 1)
+	}
+	// Service accounts can't enable two-factor authentication.
+	if u.IsServiceAccount {
+		return
+	}
+	atomic.AddInt64(&s.users, 1)
+	if u.TwoFactor != nil && u.TwoFactor.Enabled {
+		atomic.AddInt64(&s.twoFactor, 1)
+	}
+}
+
+func getAdminUsersPage(ctx context.Context, page int, config *setup.Config, stats *adminUserStats) (_ *tfe.AdminUserList, err error) {
+	ctx, span := tracer.Start(ctx, "admin users page", trace.WithAttributes(attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var usersList *tfe.AdminUserList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		usersList, err = config.Client.Admin.Users.List(setup.WithFields(ctx, adminUsersFields), &tfe.AdminUserListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return usersList, fmt.Errorf("%w, (page=%d)", err, page)
+	}
+
+	for _, u := range usersList.Items {
+		stats.add(u)
+	}
+
+	return usersList, nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeAdminUsers) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	stats := &adminUserStats{}
+	list, err := getAdminUsersPage(ctx, 1, config, stats)
+	if err != nil {
+		return err
+	}
+
+	err = fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+		_, err := getAdminUsersPage(ctx, page, config, stats)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(AdminUsersCount, prometheus.GaugeValue, float64(stats.users)),
+		prometheus.MustNewConstMetric(AdminUsersSiteAdmins, prometheus.GaugeValue, float64(stats.siteAdmins)),
+	}
+	if stats.users > 0 {
+		metrics = append(metrics, prometheus.MustNewConstMetric(AdminUsersTwoFactorRatio, prometheus.GaugeValue, float64(stats.twoFactor)/float64(stats.users)))
+	}
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
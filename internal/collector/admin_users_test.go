@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeAdminUsers(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/admin/users":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":4}},
+				"data":[
+					{"id":"user-1","type":"users","attributes":{"is-admin":true,"two-factor":{"enabled":true,"verified":true}}},
+					{"id":"user-2","type":"users","attributes":{"is-admin":false,"two-factor":{"enabled":true,"verified":true}}},
+					{"id":"user-3","type":"users","attributes":{"is-admin":false,"two-factor":{"enabled":false,"verified":false}}},
+					{"id":"user-4","type":"users","attributes":{"is-admin":false,"is-service-account":true}}
+				]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeAdminUsers{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{}, value: 3, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{}, value: 2.0 / 3, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+	})
+}
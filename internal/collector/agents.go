@@ -0,0 +1,241 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// agents is the Metric subsystem we use.
+	agentsSubsystem = "agents"
+)
+
+// Metric descriptors.
+var (
+	AgentsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "count"),
+		"Number of agents of the agent pool, by their status (idle, busy, unknown, errored or exited)",
+		[]string{"organization", "agent_pool", "status"}, nil,
+	)
+	AgentsUtilization = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "utilization_ratio"),
+		"Ratio of the agents of the agent pool running a job, of the ones available (busy or idle)",
+		[]string{"organization", "agent_pool"}, nil,
+	)
+	AgentsLastPingAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "last_ping_age_seconds"),
+		"Time since the last ping of the agent, for the agents that didn't exit",
+		[]string{"organization", "agent_pool", "agent"}, nil,
+	)
+	AgentsInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "info"),
+		"Information about the agent, as reported by the API, for the agents that didn't exit",
+		[]string{"organization", "agent_pool", "agent", "ip_address", "status"}, nil,
+	)
+	AgentsPoolInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "pool_info"),
+		"Information about the agent pool, and whether every workspace of the organization can use it (organization_scoped)",
+		[]string{"organization", "agent_pool", "organization_scoped"}, nil,
+	)
+	AgentsPoolCreated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "pool_created_timestamp_seconds"),
+		"Creation time of the agent pool, in seconds since the Unix epoch",
+		[]string{"organization", "agent_pool"}, nil,
+	)
+	AgentsPoolWorkspaces = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, agentsSubsystem, "pool_workspaces"),
+		"Number of workspaces using the agent pool",
+		[]string{"organization", "agent_pool"}, nil,
+	)
+)
+
+// agentStatuses are the statuses of the agents, always sent so they can be compared.
+var agentStatuses = []string{"idle", "busy", "unknown", "errored", "exited"}
+
+// agent is an agent of an agent pool, which the go-tfe client doesn't support.
+type agent struct {
+	ID         string    `jsonapi:"primary,agents"`
+	Name       string    `jsonapi:"attr,name"`
+	Status     string    `jsonapi:"attr,status"`
+	IPAddress  string    `jsonapi:"attr,ip-address"`
+	LastPingAt time.Time `jsonapi:"attr,last-ping-at,iso8601"`
+}
+
+// listedAgentPool is an agent pool, listed with the attributes the go-tfe client doesn't support.
+type listedAgentPool struct {
+	ID                 string           `jsonapi:"primary,agent-pools"`
+	Name               string           `jsonapi:"attr,name"`
+	OrganizationScoped bool             `jsonapi:"attr,organization-scoped"`
+	CreatedAt          time.Time        `jsonapi:"attr,created-at,iso8601"`
+	Workspaces         []*tfe.Workspace `jsonapi:"relation,workspaces"`
+}
+
+// ScrapeAgents scrapes metrics about the agents of the agent pools.
+type ScrapeAgents struct{}
+
+func init() {
+	Register(ScrapeAgents{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeAgents) Name() string {
+	return agentsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeAgents) Help() string {
+	return "Scrape the agents of the agent pools from the Agents API: https://www.terraform.io/cloud-docs/api-docs/agents"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeAgents) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeAgents) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AgentsCount
+	ch <- AgentsUtilization
+	ch <- AgentsLastPingAge
+	ch <- AgentsInfo
+	ch <- AgentsPoolInfo
+	ch <- AgentsPoolCreated
+	ch <- AgentsPoolWorkspaces
+}
+
+// listAgents returns every agent of the agent pool.
+func listAgents(ctx context.Context, pool *listedAgentPool, config *setup.Config) ([]*agent, error) {
+	var agents []*agent
+	for page := 1; ; page++ {
+		var pagination *tfe.Pagination
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			pagination, err = config.API.List(ctx, "agent-pools/"+url.PathEscape(pool.ID)+"/agents", tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			}, nil, &agents)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w, (agent_pool=%s, page=%d)", err, pool.Name, page)
+		}
+
+		if pagination == nil || page >= pagination.TotalPages || pageLimitReached(ctx, page) {
+			return agents, nil
+		}
+	}
+}
+
+func getAgentPool(ctx context.Context, organization string, pool *listedAgentPool, config *setup.Config, ch chan<- prometheus.Metric) error {
+	agents, err := listAgents(ctx, pool, config)
+	if err != nil {
+		return err
+	}
+
+	metrics := make([]prometheus.Metric, 0, 2*len(agents)+len(agentStatuses)+4)
+	metrics = append(metrics,
+		prometheus.MustNewConstMetric(AgentsPoolInfo, prometheus.GaugeValue, 1, organization, pool.Name, strconv.FormatBool(pool.OrganizationScoped)),
+		prometheus.MustNewConstMetric(AgentsPoolWorkspaces, prometheus.GaugeValue, float64(len(pool.Workspaces)), organization, pool.Name),
+	)
+	if !pool.CreatedAt.IsZero() {
+		metrics = append(metrics, prometheus.MustNewConstMetric(AgentsPoolCreated, prometheus.GaugeValue, float64(pool.CreatedAt.Unix()), organization, pool.Name))
+	}
+	byStatus := map[string]int{}
+	for _, a := range agents {
+		byStatus[a.Status]++
+		if a.Status == "exited" {
+			continue
+		}
+		name := a.Name
+		if name == "" {
+			name = a.ID
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(AgentsInfo, prometheus.GaugeValue, 1, organization, pool.Name, name, a.IPAddress, a.Status))
+		// Agents that stopped pinging but didn't exit are still registered, and likely half-dead.
+		if !a.LastPingAt.IsZero() {
+			metrics = append(metrics, prometheus.MustNewConstMetric(AgentsLastPingAge, prometheus.GaugeValue, time.Since(a.LastPingAt).Seconds(), organization, pool.Name, name))
+		}
+	}
+
+	for _, status := range agentStatuses {
+		metrics = append(metrics, prometheus.MustNewConstMetric(AgentsCount, prometheus.GaugeValue, float64(byStatus[status]), organization, pool.Name, status))
+	}
+	if available := byStatus["busy"] + byStatus["idle"]; available > 0 {
+		metrics = append(metrics, prometheus.MustNewConstMetric(AgentsUtilization, prometheus.GaugeValue, float64(byStatus["busy"])/float64(available), organization, pool.Name))
+	}
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getAgentPoolsPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.Pagination, err error) {
+	ctx, span := tracer.Start(ctx, "agent pools page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var pools []*listedAgentPool
+	var pagination *tfe.Pagination
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		pagination, err = config.API.List(ctx, "organizations/"+url.PathEscape(organization)+"/agent-pools", tfe.ListOptions{
+			PageSize:   pageSize,
+			PageNumber: page,
+		}, nil, &pools)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, pool := range pools {
+		pool := pool
+		g.Go(func() error {
+			return getAgentPool(ctx, organization, pool, config, ch)
+		})
+	}
+
+	return pagination, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeAgents) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			return listAllPages(ctx, func(ctx context.Context, page int) (*tfe.Pagination, error) {
+				return getAgentPoolsPage(ctx, page, name, config, ch)
+			})
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeAgents(t *testing.T) {
+	lastPing := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/agent-pools":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{
+					"id":"apool-1",
+					"type":"agent-pools",
+					"attributes":{"name":"default","organization-scoped":false,"created-at":"2023-01-01T00:00:00Z"},
+					"relationships":{"workspaces":{"data":[{"id":"ws-1","type":"workspaces"},{"id":"ws-2","type":"workspaces"}]}}
+				}]
+			}`))
+		case "/api/v2/agent-pools/apool-1/agents":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":4}},
+				"data":[
+					{"id":"agent-1","type":"agents","attributes":{"name":"a1","status":"busy","ip-address":"10.0.0.1","last-ping-at":"` + lastPing + `"}},
+					{"id":"agent-2","type":"agents","attributes":{"name":"","status":"idle","ip-address":"10.0.0.2","last-ping-at":"` + lastPing + `"}},
+					{"id":"agent-3","type":"agents","attributes":{"name":"a3","status":"idle","last-ping-at":null}},
+					{"id":"agent-4","type":"agents","attributes":{"name":"a4","status":"exited","last-ping-at":"` + lastPing + `"}}
+				]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		API:    api,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeAgents{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "organization_scoped": "false"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default"}, value: 1672531200, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "agent": "a1", "ip_address": "10.0.0.1", "status": "busy"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "agent": "a1"}, value: 3600, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "agent": "agent-2", "ip_address": "10.0.0.2", "status": "idle"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "agent": "agent-2"}, value: 3600, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "agent": "a3", "ip_address": "", "status": "idle"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "status": "idle"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "status": "busy"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "status": "unknown"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "status": "errored"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default", "status": "exited"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "agent_pool": "default"}, value: 1.0 / 3, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			if _, ok := expect.labels["agent"]; ok && expect.labels["status"] == "" {
+				// The age keeps growing while the test runs.
+				convey.So(got.value, convey.ShouldAlmostEqual, expect.value, 5)
+				got.value = expect.value
+			}
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+	})
+}
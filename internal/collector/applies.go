@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// applies is the Metric subsystem we use.
+	appliesSubsystem = "applies"
+)
+
+// Metric descriptors.
+var (
+	AppliesLastTimestamp = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, appliesSubsystem, "last_timestamp_seconds"),
+		"Time the last run of the workspace applied successfully, speculative plans are never applied",
+		[]string{"organization", "workspace"}, nil,
+	)
+	AppliesLastRunInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, appliesSubsystem, "last_run_info"),
+		"Last run of the workspace applied successfully, kept apart from its timestamp so a new run doesn't start a new series of it",
+		[]string{"organization", "workspace", "run"}, nil,
+	)
+)
+
+var (
+	// appliesWorkspacesFields are the only fields of the workspaces needed to list their runs.
+	appliesWorkspacesFields = setup.Fields{"workspaces": {"name"}}
+	// appliesRunsFields are the only fields of the runs needed to know when they were applied.
+	appliesRunsFields = setup.Fields{"runs": {"status-timestamps"}}
+	// appliedRunsQuery only lists the runs applied successfully.
+	appliedRunsQuery = url.Values{"filter[status]": {string(tfe.RunApplied)}}
+)
+
+// appliedRun is a run applied successfully, listed with a filter the go-tfe client doesn't support.
+type appliedRun struct {
+	ID               string                   `jsonapi:"primary,runs"`
+	StatusTimestamps *tfe.RunStatusTimestamps `jsonapi:"attr,status-timestamps"`
+}
+
+// ScrapeApplies scrapes the last successful apply of the workspaces.
+type ScrapeApplies struct{}
+
+func init() {
+	Register(ScrapeApplies{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeApplies) Name() string {
+	return appliesSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeApplies) Help() string {
+	return "Scrape the last successful apply of the workspaces from the Runs API: https://www.terraform.io/cloud-docs/api-docs/run"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeApplies) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeApplies) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AppliesLastTimestamp
+	ch <- AppliesLastRunInfo
+}
+
+func getLastApply(ctx context.Context, organization string, w *tfe.Workspace, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// Runs are listed from the newest, so the first applied one is the last.
+	var runs []*appliedRun
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		_, err = config.API.List(setup.WithFields(ctx, appliesRunsFields), "workspaces/"+url.PathEscape(w.ID)+"/runs", tfe.ListOptions{
+			PageSize: 1,
+		}, appliedRunsQuery, &runs)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w, (workspace=%s)", err, w.Name)
+	}
+	// Workspaces never applied don't have any.
+	if len(runs) == 0 || runs[0].StatusTimestamps == nil || runs[0].StatusTimestamps.AppliedAt.IsZero() {
+		return nil
+	}
+
+	for _, m := range []prometheus.Metric{
+		prometheus.MustNewConstMetric(AppliesLastTimestamp, prometheus.GaugeValue, float64(runs[0].StatusTimestamps.AppliedAt.Unix()), organization, w.Name),
+		prometheus.MustNewConstMetric(AppliesLastRunInfo, prometheus.GaugeValue, 1, organization, w.Name, runs[0].ID),
+	} {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getAppliesPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.WorkspaceList, err error) {
+	ctx, span := tracer.Start(ctx, "applies workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var workspacesList *tfe.WorkspaceList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(setup.WithQuery(ctx, config.WorkspacesSearch()), appliesWorkspacesFields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return workspacesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range workspacesList.Items {
+		w := w
+		g.Go(func() error {
+			return getLastApply(ctx, organization, w, config, ch)
+		})
+	}
+
+	return workspacesList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeApplies) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			list, err := getAppliesPage(ctx, 1, name, config, ch)
+			if err != nil {
+				return err
+			}
+
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getAppliesPage(ctx, page, name, config, ch)
+				return err
+			})
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeApplies(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/runs") && (r.URL.Query().Get("filter[status]") != "applied" || r.URL.Query().Get("page[size]") != "1") {
+			t.Errorf("unexpected runs query: %s", r.URL.RawQuery)
+		}
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"ws-1","type":"workspaces","attributes":{"name":"dev"}},
+					{"id":"ws-2","type":"workspaces","attributes":{"name":"new"}}
+				]
+			}`))
+		case "/api/v2/workspaces/ws-1/runs":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":3,"total-count":3}},
+				"data":[{"id":"run-1","type":"runs","attributes":{"status-timestamps":{"applied-at":"2022-05-01T10:00:00Z"}}}]
+			}`))
+		case "/api/v2/workspaces/ws-2/runs":
+			w.Write([]byte(`{"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":0}},"data":[]}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		API:    api,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeApplies{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// Workspaces never applied aren't exposed.
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "workspace": "dev"}, value: 1651399200, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "dev", "run": "run-1"}, value: 1, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+		_, ok := <-ch
+		convey.So(ok, convey.ShouldBeFalse)
+	})
+}
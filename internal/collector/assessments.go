@@ -0,0 +1,243 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// assessments is the Metric subsystem we use.
+	assessmentsSubsystem = "assessments"
+)
+
+// Metric descriptors.
+var (
+	AssessmentsEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, assessmentsSubsystem, "enabled"),
+		"Whether the health assessments (drift detection) are enabled on the workspace (1 for enabled, 0 for disabled)",
+		[]string{"organization", "workspace"}, nil,
+	)
+	AssessmentsCoverage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, assessmentsSubsystem, "coverage_ratio"),
+		"Ratio of the workspaces of the organization with health assessments enabled",
+		[]string{"organization"}, nil,
+	)
+	AssessmentsFailed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, assessmentsSubsystem, "failed"),
+		"Whether the last health assessment of the workspace failed to run, e.g. with invalid provider credentials, so its drift is unknown (1 for failed, 0 otherwise)",
+		[]string{"organization", "workspace"}, nil,
+	)
+	AssessmentsFailedCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, assessmentsSubsystem, "failed_count"),
+		"Number of workspaces of the organization whose last health assessment failed to run",
+		[]string{"organization"}, nil,
+	)
+	AssessmentsDriftedResources = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, assessmentsSubsystem, "drifted_resources"),
+		"Number of resources of the workspace drifted from their configuration, as found by its last successful health assessment",
+		[]string{"organization", "workspace"}, nil,
+	)
+)
+
+// assessmentsWorkspacesFields are the only fields of the workspaces needed for their health assessments.
+var assessmentsWorkspacesFields = setup.Fields{"workspaces": {"name", "assessments-enabled"}}
+
+// assessmentsWorkspace is a workspace with its health assessments setting, which the go-tfe client doesn't support.
+type assessmentsWorkspace struct {
+	ID                 string `jsonapi:"primary,workspaces"`
+	Name               string `jsonapi:"attr,name"`
+	AssessmentsEnabled bool   `jsonapi:"attr,assessments-enabled"`
+}
+
+// assessmentResult is the result of the last health assessment of a workspace, which the go-tfe client doesn't support.
+type assessmentResult struct {
+	Data struct {
+		Attributes struct {
+			Succeeded        bool `json:"succeeded"`
+			ResourcesDrifted int  `json:"resources-drifted"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ScrapeAssessments scrapes whether the health assessments are enabled on the workspaces, and their last results.
+type ScrapeAssessments struct{}
+
+func init() {
+	Register(ScrapeAssessments{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeAssessments) Name() string {
+	return assessmentsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeAssessments) Help() string {
+	return "Scrape the health assessments settings and results of the workspaces from the Workspaces and Assessment Results APIs: https://www.terraform.io/cloud-docs/workspaces/health"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeAssessments) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeAssessments) Describe(ch chan<- *prometheus.Desc) {
+	ch <- AssessmentsEnabled
+	ch <- AssessmentsCoverage
+	ch <- AssessmentsFailed
+	ch <- AssessmentsFailedCount
+	ch <- AssessmentsDriftedResources
+}
+
+// getAssessmentResult sends whether the last health assessment of the workspace failed, if it was ever assessed,
+// and otherwise how many of its resources drifted.
+func getAssessmentResult(ctx context.Context, organization string, w *assessmentsWorkspace, config *setup.Config, failed *int64, ch chan<- prometheus.Metric) error {
+	var result assessmentResult
+	err := config.Pool.Do(ctx, func(ctx context.Context) error {
+		return config.API.GetJSON(ctx, "workspaces/"+url.PathEscape(w.ID)+"/current-assessment-result", &result)
+	})
+	// Workspaces not assessed yet don't have any.
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w, (workspace=%s)", err, w.Name)
+	}
+
+	metrics := []prometheus.Metric{}
+	if result.Data.Attributes.Succeeded {
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(AssessmentsFailed, prometheus.GaugeValue, 0, organization, w.Name),
+			prometheus.MustNewConstMetric(AssessmentsDriftedResources, prometheus.GaugeValue, float64(result.Data.Attributes.ResourcesDrifted), organization, w.Name),
+		)
+	} else {
+		// The drift of a failed assessment is unknown, not zero.
+		atomic.AddInt64(failed, 1)
+		metrics = append(metrics, prometheus.MustNewConstMetric(AssessmentsFailed, prometheus.GaugeValue, 1, organization, w.Name))
+	}
+
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getAssessmentsPage(ctx context.Context, page int, organization string, config *setup.Config, enabled, total, failed *int64, ch chan<- prometheus.Metric) (_ *tfe.Pagination, err error) {
+	ctx, span := tracer.Start(ctx, "assessments page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var (
+		workspaces []*assessmentsWorkspace
+		pagination *tfe.Pagination
+	)
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		pagination, err = config.API.List(setup.WithFields(ctx, assessmentsWorkspacesFields), "organizations/"+organization+"/workspaces", tfe.ListOptions{
+			PageSize:   pageSize,
+			PageNumber: page,
+		}, config.WorkspacesSearch(), &workspaces)
+		return err
+	})
+	if err != nil {
+		return pagination, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range workspaces {
+		w := w
+		value := 0.0
+		if w.AssessmentsEnabled {
+			value = 1
+			atomic.AddInt64(enabled, 1)
+			g.Go(func() error {
+				return getAssessmentResult(ctx, organization, w, config, failed, ch)
+			})
+		}
+		atomic.AddInt64(total, 1)
+
+		select {
+		case ch <- prometheus.MustNewConstMetric(AssessmentsEnabled, prometheus.GaugeValue, value, organization, w.Name):
+		case <-ctx.Done():
+			// Cancelled by a failing assessment result, rather than by the scrape.
+			if err := g.Wait(); err != nil {
+				return pagination, err
+			}
+			return pagination, ctx.Err()
+		}
+	}
+
+	return pagination, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeAssessments) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			var enabled, total, failed int64
+			pagination, err := getAssessmentsPage(ctx, 1, name, config, &enabled, &total, &failed, ch)
+			if err != nil {
+				return err
+			}
+
+			totalPages := 1
+			if pagination != nil {
+				totalPages = pagination.TotalPages
+			}
+			err = fetchRemainingPages(ctx, totalPages, func(ctx context.Context, page int) error {
+				_, err := getAssessmentsPage(ctx, page, name, config, &enabled, &total, &failed, ch)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if total == 0 {
+				return nil
+			}
+			for _, m := range []prometheus.Metric{
+				prometheus.MustNewConstMetric(AssessmentsCoverage, prometheus.GaugeValue, float64(enabled)/float64(total), name),
+				prometheus.MustNewConstMetric(AssessmentsFailedCount, prometheus.GaugeValue, float64(failed), name),
+			} {
+				select {
+				case ch <- m:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
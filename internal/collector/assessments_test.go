@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeAssessments(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":4}},
+				"data":[
+					{"id":"ws-1","type":"workspaces","attributes":{"name":"prod","assessments-enabled":true}},
+					{"id":"ws-2","type":"workspaces","attributes":{"name":"dev","assessments-enabled":false}},
+					{"id":"ws-3","type":"workspaces","attributes":{"name":"stg","assessments-enabled":true}},
+					{"id":"ws-4","type":"workspaces","attributes":{"name":"new","assessments-enabled":true}}
+				]
+			}`))
+		case "/api/v2/workspaces/ws-1/current-assessment-result":
+			w.Write([]byte(`{"data":{"id":"asmtres-1","type":"assessment-results","attributes":{"drifted":true,"succeeded":true,"resources-drifted":3,"resources-undrifted":12}}}`))
+		case "/api/v2/workspaces/ws-3/current-assessment-result":
+			w.Write([]byte(`{"data":{"id":"asmtres-3","type":"assessment-results","attributes":{"drifted":false,"succeeded":false,"error-msg":"invalid credentials"}}}`))
+		case "/api/v2/workspaces/ws-4/current-assessment-result":
+			// Not assessed yet.
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		API: api,
+		CLI: setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeAssessments{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// The assessment results are read concurrently, so their order isn't deterministic.
+	got := []MetricResult{}
+	for m := range ch {
+		got = append(got, readMetric(m))
+	}
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "workspace": "prod"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "dev"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "stg"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "new"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "prod"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "prod"}, value: 3, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "stg"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 0.75, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 1, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldHaveLength, len(counterExpected))
+		for _, expect := range counterExpected {
+			convey.So(got, convey.ShouldContain, expect)
+		}
+	})
+}
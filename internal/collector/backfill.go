@@ -0,0 +1,241 @@
+package collector
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// backfillRunsFields are the only fields of the runs needed to replay their history.
+var backfillRunsFields = setup.Fields{"runs": {"status", "source", "is-destroy", "created-at", "status-timestamps"}}
+
+// backfillRun is a run of the history, with what's needed to tell its status at any time.
+type backfillRun struct {
+	id        string
+	workspace string
+	source    tfe.RunSource
+	status    tfe.RunStatus
+	isDestroy bool
+	createdAt time.Time
+	// transitions are the times the run reached each status, in order.
+	transitions []runTransition
+}
+
+type runTransition struct {
+	at     time.Time
+	status tfe.RunStatus
+}
+
+func newBackfillRun(w *tfe.Workspace, r *tfe.Run) backfillRun {
+	run := backfillRun{id: r.ID, workspace: w.Name, source: r.Source, status: r.Status, isDestroy: r.IsDestroy, createdAt: r.CreatedAt}
+	if ts := r.StatusTimestamps; ts != nil {
+		for _, t := range []runTransition{
+			{ts.PlanQueuedAt, tfe.RunPlanQueued},
+			{ts.PlanningAt, tfe.RunPlanning},
+			{ts.PlannedAt, tfe.RunPlanned},
+			{ts.PlannedAndFinishedAt, tfe.RunPlannedAndFinished},
+			{ts.CostEstimatingAt, tfe.RunCostEstimating},
+			{ts.CostEstimatedAt, tfe.RunCostEstimated},
+			{ts.PolicyCheckedAt, tfe.RunPolicyChecked},
+			{ts.PolicySoftFailedAt, tfe.RunPolicySoftFailed},
+			{ts.ConfirmedAt, tfe.RunConfirmed},
+			{ts.ApplyQueuedAt, tfe.RunApplyQueued},
+			{ts.ApplyingAt, tfe.RunApplying},
+			{ts.AppliedAt, tfe.RunApplied},
+			{ts.DiscardedAt, tfe.RunDiscarded},
+			{ts.ErroredAt, tfe.RunErrored},
+			{ts.CanceledAt, tfe.RunCanceled},
+			{ts.ForceCanceledAt, tfe.RunCanceled},
+		} {
+			if !t.at.IsZero() {
+				run.transitions = append(run.transitions, t)
+			}
+		}
+	}
+	sort.SliceStable(run.transitions, func(i, j int) bool { return run.transitions[i].at.Before(run.transitions[j].at) })
+
+	return run
+}
+
+// statusAt returns the status of the run at the given time: The last one it reached by then,
+// pending until it reached any, or its current one if it reached it without a timestamp.
+func (r backfillRun) statusAt(t time.Time) tfe.RunStatus {
+	status := tfe.RunPending
+	for _, transition := range r.transitions {
+		if transition.at.After(t) {
+			return status
+		}
+		status = transition.status
+	}
+	if len(r.transitions) > 0 && status != r.status && !t.Before(r.transitions[len(r.transitions)-1].at) {
+		// e.g. policy_override, which has no timestamp.
+		return r.status
+	}
+
+	return status
+}
+
+// appliedAt returns when the run was applied, or the zero time if it never was.
+func (r backfillRun) appliedAt() time.Time {
+	for _, transition := range r.transitions {
+		if transition.status == tfe.RunApplied {
+			return transition.at
+		}
+	}
+
+	return time.Time{}
+}
+
+// backfillFamilies accumulates the samples of the history by metric.
+type backfillFamilies map[string]*dto.MetricFamily
+
+// add adds a sample of the metric at the given time.
+func (f backfillFamilies) add(t time.Time, desc *prometheus.Desc, value float64, labels ...string) error {
+	doc, _ := parseDesc(desc)
+	family, ok := f[doc.Name]
+	if !ok {
+		family = &dto.MetricFamily{Name: &doc.Name, Help: &doc.Help, Type: dto.MetricType_GAUGE.Enum()}
+		f[doc.Name] = family
+	}
+
+	m := &dto.Metric{}
+	if err := prometheus.NewMetricWithTimestamp(t, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)).Write(m); err != nil {
+		return err
+	}
+	family.Metric = append(family.Metric, m)
+
+	return nil
+}
+
+// sorted returns the families sorted by name, with the samples of every series together and in time order,
+// as expected by promtool tsdb create-blocks-from openmetrics.
+func (f backfillFamilies) sorted() []*dto.MetricFamily {
+	families := make([]*dto.MetricFamily, 0, len(f))
+	for _, family := range f {
+		series := func(m *dto.Metric) string {
+			pairs := make([]string, 0, len(m.Label))
+			for _, l := range m.Label {
+				pairs = append(pairs, l.GetName()+"="+l.GetValue())
+			}
+			return strings.Join(pairs, ",")
+		}
+		sort.SliceStable(family.Metric, func(i, j int) bool {
+			if si, sj := series(family.Metric[i]), series(family.Metric[j]); si != sj {
+				return si < sj
+			}
+			return family.Metric[i].GetTimestampMs() < family.Metric[j].GetTimestampMs()
+		})
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+
+	return families
+}
+
+// replay adds the samples the runs and applies scrapers would have exposed for the organization at every step
+// between from and to, given its runs sorted by creation time.
+func (f backfillFamilies) replay(organization string, runs []backfillRun, from, to time.Time, step, lookback time.Duration) error {
+	applied := make([]backfillRun, 0, len(runs))
+	for _, r := range runs {
+		if !r.appliedAt().IsZero() {
+			applied = append(applied, r)
+		}
+	}
+	sort.SliceStable(applied, func(i, j int) bool { return applied[i].appliedAt().Before(applied[j].appliedAt()) })
+
+	var (
+		first, next int
+		lastApply   = map[string]backfillRun{}
+		nextApply   int
+	)
+	for t := from; t.Before(to); t = t.Add(step) {
+		// The window of the runs created within the lookback before the step: runs[first:next].
+		for next < len(runs) && !runs[next].createdAt.After(t) {
+			next++
+		}
+		for first < next && !runs[first].createdAt.After(t.Add(-lookback)) {
+			first++
+		}
+		for nextApply < len(applied) && !applied[nextApply].appliedAt().After(t) {
+			lastApply[applied[nextApply].workspace] = applied[nextApply]
+			nextApply++
+		}
+
+		byStatus, bySource, errored := map[runStatus]int{}, map[tfe.RunSource]int{}, 0
+		for _, r := range runs[first:next] {
+			status := r.statusAt(t)
+			byStatus[runStatus{status, r.isDestroy}]++
+			bySource[r.source]++
+			if status == tfe.RunErrored {
+				errored++
+			}
+		}
+
+		for status, count := range byStatus {
+			if err := f.add(t, RunsCount, float64(count), organization, string(status.status), strconv.FormatBool(status.isDestroy)); err != nil {
+				return err
+			}
+		}
+		for source, count := range bySource {
+			if err := f.add(t, RunsSourceCount, float64(count), organization, string(source)); err != nil {
+				return err
+			}
+		}
+		if total := next - first; total > 0 {
+			if err := f.add(t, RunsErrorRatio, float64(errored)/float64(total), organization); err != nil {
+				return err
+			}
+		}
+		for workspace, r := range lastApply {
+			if err := f.add(t, AppliesLastTimestamp, float64(r.appliedAt().Unix()), organization, workspace); err != nil {
+				return err
+			}
+			if err := f.add(t, AppliesLastRunInfo, 1, organization, workspace, r.id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Backfill returns the history of the runs and applies of the organizations between from and to, as the samples
+// the runs and applies scrapers would have exposed every step, with their timestamps.
+// Only the runs still listed by the API are known, and applies are only known from the start of the history.
+func Backfill(ctx context.Context, config *setup.Config, from, to time.Time, step time.Duration) ([]*dto.MetricFamily, error) {
+	families := backfillFamilies{}
+	for _, organization := range config.Organizations {
+		var (
+			mu   sync.Mutex
+			runs []backfillRun
+		)
+		err := visitRecentRuns(ctx, organization, from.Add(-config.RunsLookback), backfillRunsFields, config, func(ctx context.Context, w *tfe.Workspace, r *tfe.Run) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if r.CreatedAt.Before(to) {
+				runs = append(runs, newBackfillRun(w, r))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(runs, func(i, j int) bool { return runs[i].createdAt.Before(runs[j].createdAt) })
+
+		if err := families.replay(organization, runs, from, to, step, config.RunsLookback); err != nil {
+			return nil, err
+		}
+	}
+
+	return families.sorted(), nil
+}
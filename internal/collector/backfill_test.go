@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackfillRunStatusAt(t *testing.T) {
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	run := newBackfillRun(&tfe.Workspace{Name: "prod"}, &tfe.Run{
+		ID:        "run-1",
+		Status:    tfe.RunApplied,
+		CreatedAt: at("2023-01-01T10:30:00Z"),
+		StatusTimestamps: &tfe.RunStatusTimestamps{
+			PlanningAt: at("2023-01-01T10:31:00Z"),
+			PlannedAt:  at("2023-01-01T10:32:00Z"),
+			AppliedAt:  at("2023-01-01T10:40:00Z"),
+		},
+	})
+
+	convey.Convey("The status of a run is the last one it reached", t, func() {
+		convey.So(run.statusAt(at("2023-01-01T10:30:30Z")), convey.ShouldEqual, tfe.RunPending)
+		convey.So(run.statusAt(at("2023-01-01T10:31:00Z")), convey.ShouldEqual, tfe.RunPlanning)
+		convey.So(run.statusAt(at("2023-01-01T10:35:00Z")), convey.ShouldEqual, tfe.RunPlanned)
+		convey.So(run.statusAt(at("2023-01-01T11:00:00Z")), convey.ShouldEqual, tfe.RunApplied)
+		convey.So(run.appliedAt(), convey.ShouldResemble, at("2023-01-01T10:40:00Z"))
+	})
+}
+
+func TestBackfill(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"ws-1","type":"workspaces","attributes":{"name":"prod"}}]
+			}`))
+		case "/api/v2/workspaces/ws-1/runs":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":3}},
+				"data":[
+					{"id":"run-3","type":"runs","attributes":{"status":"pending","source":"tfe-api","created-at":"2023-01-01T12:30:00Z"}},
+					{"id":"run-2","type":"runs","attributes":{"status":"applied","source":"tfe-api","created-at":"2023-01-01T10:30:00Z",
+						"status-timestamps":{"planning-at":"2023-01-01T10:31:00Z","planned-at":"2023-01-01T10:32:00Z","applied-at":"2023-01-01T10:40:00Z"}}},
+					{"id":"run-1","type":"runs","attributes":{"status":"errored","source":"tfe-ui","is-destroy":true,"created-at":"2023-01-01T08:30:00Z",
+						"status-timestamps":{"errored-at":"2023-01-01T08:35:00Z"}}}
+				]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}, RunsLookback: 24 * time.Hour},
+	}
+
+	from, _ := time.Parse(time.RFC3339, "2023-01-01T09:00:00Z")
+	families, err := Backfill(context.Background(), config, from, from.Add(3*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	// The samples of every metric as labels@time=value, in the order they're written.
+	got := map[string][]string{}
+	names := []string{}
+	for _, family := range families {
+		names = append(names, family.GetName())
+		for _, m := range family.GetMetric() {
+			labels := []string{}
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetValue())
+			}
+			at := time.UnixMilli(m.GetTimestampMs()).UTC().Format("15:04")
+			got[family.GetName()] = append(got[family.GetName()], fmt.Sprintf("%s@%s=%v", strings.Join(labels, ","), at, m.GetGauge().GetValue()))
+		}
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(names, convey.ShouldResemble, []string{"tf_applies_last_run_info", "tf_applies_last_timestamp_seconds", "tf_runs_count", "tf_runs_error_ratio", "tf_runs_source_count"})
+		convey.So(got["tf_runs_count"], convey.ShouldResemble, []string{
+			"false,test-org,applied@11:00=1",
+			"true,test-org,errored@09:00=1", "true,test-org,errored@10:00=1", "true,test-org,errored@11:00=1",
+		})
+		convey.So(got["tf_runs_source_count"], convey.ShouldResemble, []string{
+			"test-org,tfe-api@11:00=1",
+			"test-org,tfe-ui@09:00=1", "test-org,tfe-ui@10:00=1", "test-org,tfe-ui@11:00=1",
+		})
+		convey.So(got["tf_runs_error_ratio"], convey.ShouldResemble, []string{
+			"test-org@09:00=1", "test-org@10:00=1", "test-org@11:00=0.5",
+		})
+		convey.So(got["tf_applies_last_timestamp_seconds"], convey.ShouldResemble, []string{
+			fmt.Sprintf("test-org,prod@11:00=%v", float64(time.Date(2023, 1, 1, 10, 40, 0, 0, time.UTC).Unix())),
+		})
+		convey.So(got["tf_applies_last_run_info"], convey.ShouldResemble, []string{
+			"test-org,run-2,prod@11:00=1",
+		})
+	})
+}
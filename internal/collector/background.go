@@ -0,0 +1,185 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Metric descriptors.
+var (
+	collectionAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "collection_age_seconds"),
+		"Seconds since the last background collection finished.",
+		nil, nil,
+	)
+	deletedEntitiesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "deleted_entities_total"),
+		"Number of organizations and workspaces that disappeared between background collections, by kind (organization or workspace).",
+		[]string{"kind"}, nil,
+	)
+)
+
+// entityKinds are the kinds of the entities whose deletion is detected.
+var entityKinds = []string{"organization", "workspace"}
+
+// Background runs the exporter on a fixed interval and keeps the latest collected metrics,
+// so they can be served instantly instead of waiting for the Terraform API on every request.
+// It implements the prometheus.Collector interface.
+type Background struct {
+	config   setup.Config
+	scrapers []Scraper
+	metrics  Metrics
+	cache    *Cache
+
+	mu       sync.RWMutex
+	snapshot []prometheus.Metric
+	updated  time.Time
+	// entities are the keys of the entities found by the last collection that could tell them, by kind.
+	entities map[string]map[string]bool
+	deleted  map[string]float64
+	// search is the encoded --workspaces.search-* of the last collection, as the workspaces found by another search aren't comparable.
+	search string
+}
+
+// NewBackground returns a new Background collector for the provided Config that runs the given scrapers.
+func NewBackground(config setup.Config, scrapers []Scraper, metrics Metrics, cache *Cache) *Background {
+	return &Background{
+		config:   config,
+		scrapers: scrapers,
+		metrics:  metrics,
+		cache:    cache,
+		entities: map[string]map[string]bool{},
+		deleted:  map[string]float64{},
+	}
+}
+
+// Run collects metrics every interval until the context is cancelled.
+// Each collection is given at most one interval to finish.
+func (b *Background) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b.collect(ctx, interval)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *Background) collect(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	level.Debug(b.config.Logger).Log("msg", "Starting background collection")
+	start := time.Now()
+
+	e := New(ctx, b.config, b.scrapers, b.metrics, b.cache)
+	snapshot := collectMetrics(e)
+	found := b.found(e, snapshot, start)
+
+	b.mu.Lock()
+	b.snapshot = snapshot
+	b.updated = time.Now()
+	if search := e.config.WorkspacesSearch().Encode(); search != b.search {
+		delete(b.entities, "workspace")
+		b.search = search
+	}
+	b.forgetDeleted(found)
+	b.mu.Unlock()
+
+	level.Debug(b.config.Logger).Log("msg", "Finished background collection", "metrics", len(snapshot), "duration", time.Since(start))
+}
+
+// found returns the keys of the entities found by the collection, by kind, for the kinds it can tell:
+// The organizations, when they're discovered instead of configured, and the workspaces (organization/name)
+// when the workspaces scraper succeeded and listed every page, as a failure or a list truncated by --max-pages
+// would make the missing ones look deleted.
+func (b *Background) found(e *Exporter, snapshot []prometheus.Metric, start time.Time) map[string]map[string]bool {
+	found := map[string]map[string]bool{}
+	if e.discovered != nil {
+		found["organization"] = map[string]bool{}
+		for _, organization := range e.discovered {
+			found["organization"][organization] = true
+		}
+	}
+
+	for _, status := range b.metrics.Status.Scrapers() {
+		if status.Scraper != workspacesSubsystem || !status.Success || status.Truncated || status.LastRun.Before(start) {
+			continue
+		}
+
+		found["workspace"] = map[string]bool{}
+		for _, m := range snapshot {
+			if m.Desc() != WorkspacesInfo {
+				continue
+			}
+			var metric dto.Metric
+			if err := m.Write(&metric); err != nil {
+				continue
+			}
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			found["workspace"][labels["organization"]+"/"+labels["name"]] = true
+		}
+	}
+
+	return found
+}
+
+// forgetDeleted counts the entities found by the previous collection but not by this one as deleted,
+// and stops exposing the series of the exporter about the deleted organizations.
+// Must be called with the lock held.
+func (b *Background) forgetDeleted(found map[string]map[string]bool) {
+	for kind, keys := range found {
+		for key := range b.entities[kind] {
+			if keys[key] {
+				continue
+			}
+
+			level.Info(b.config.Logger).Log("msg", "Entity deleted since the last collection", "kind", kind, "key", key)
+			b.deleted[kind]++
+			if kind == "organization" {
+				b.metrics.LastScrapeTimestamp.DeletePartialMatch(prometheus.Labels{"organization": key})
+				b.metrics.ScrapeErrors.DeletePartialMatch(prometheus.Labels{"organization": key})
+			}
+		}
+		b.entities[kind] = keys
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+// The collector is unchecked, as the metrics depend on what the scrapers find.
+func (b *Background) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements the prometheus.Collector interface.
+func (b *Background) Collect(ch chan<- prometheus.Metric) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.updated.IsZero() {
+		return
+	}
+
+	for _, m := range b.snapshot {
+		ch <- m
+	}
+	ch <- prometheus.MustNewConstMetric(collectionAgeDesc, prometheus.GaugeValue, time.Since(b.updated).Seconds())
+	ch <- prometheus.MustNewConstMetric(bufferedMetricsDesc, prometheus.GaugeValue, float64(len(b.snapshot)), "background")
+	for _, kind := range entityKinds {
+		ch <- prometheus.MustNewConstMetric(deletedEntitiesDesc, prometheus.CounterValue, b.deleted[kind], kind)
+	}
+}
@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// fakeWorkspacesScraper is a workspaces Scraper emitting the info of the given workspaces of every organization.
+type fakeWorkspacesScraper struct {
+	workspaces *[]string
+	err        *error
+	// truncated stops the list at the first page, when --max-pages allows a single one.
+	truncated *bool
+}
+
+func (s fakeWorkspacesScraper) Name() string    { return workspacesSubsystem }
+func (s fakeWorkspacesScraper) Help() string    { return "Fake workspaces scraper" }
+func (s fakeWorkspacesScraper) Version() string { return "v2" }
+func (s fakeWorkspacesScraper) Describe(ch chan<- *prometheus.Desc) {
+	ch <- WorkspacesInfo
+}
+func (s fakeWorkspacesScraper) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	if *s.err != nil {
+		return *s.err
+	}
+	if s.truncated != nil && *s.truncated {
+		pageLimitReached(ctx, 1)
+	}
+	for _, organization := range config.Organizations {
+		for _, name := range *s.workspaces {
+			ch <- prometheus.MustNewConstMetric(WorkspacesInfo, prometheus.GaugeValue, 1, "ws-"+name, name, organization, "1.4.0", "", "", "", "", "")
+		}
+	}
+	return nil
+}
+
+// deletedEntities returns the deleted entities counted by the background collector, by kind.
+func deletedEntities(b *Background) map[string]float64 {
+	deleted := map[string]float64{}
+	for _, m := range collectMetrics(b) {
+		if m.Desc() == deletedEntitiesDesc {
+			got := readMetric(m)
+			deleted[got.labels["kind"]] = got.value
+		}
+	}
+
+	return deleted
+}
+
+func TestBackgroundDeletedWorkspaces(t *testing.T) {
+	config := setup.Config{
+		CLI:    setup.CLI{Organizations: []string{"test-org"}, MaxPages: map[string]int{workspacesSubsystem: 1}},
+		Logger: log.NewNopLogger(),
+	}
+	workspaces, err, truncated := []string{"dev", "prod"}, error(nil), false
+	b := NewBackground(config, []Scraper{fakeWorkspacesScraper{&workspaces, &err, &truncated}}, NewMetrics(), nil)
+
+	convey.Convey("Workspaces missing from the next collection are counted as deleted", t, func() {
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 0})
+
+		workspaces = []string{"prod"}
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 1})
+	})
+
+	convey.Convey("Failed collections don't count the missing workspaces as deleted", t, func() {
+		err = errors.New("test error")
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 1})
+
+		err = nil
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 1})
+	})
+
+	convey.Convey("Collections truncated by --max-pages don't count the missing workspaces as deleted", t, func() {
+		workspaces, truncated = []string{}, true
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 1})
+
+		workspaces, truncated = []string{"prod"}, false
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 1})
+	})
+
+	convey.Convey("Workspaces no longer searched aren't counted as deleted", t, func() {
+		workspaces = []string{}
+		b.config.WorkspacesSearchName = "dev"
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 1})
+	})
+}
+
+func TestBackgroundDeletedOrganizations(t *testing.T) {
+	organizations := []string{"org-1", "org-2"}
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations":
+			data := []string{}
+			for _, o := range organizations {
+				data = append(data, fmt.Sprintf(`{"id":%q,"type":"organizations","attributes":{"name":%q}}`, o, o))
+			}
+			w.Write([]byte(`{"data":[` + strings.Join(data, ",") + `]}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := setup.Config{
+		Client: *client,
+		Logger: log.NewNopLogger(),
+	}
+	metrics := NewMetrics()
+	b := NewBackground(config, []Scraper{fakeScraper{name: "ok"}}, metrics, nil)
+
+	convey.Convey("Organizations missing from the next collection are counted as deleted, and their series dropped", t, func() {
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 0, "workspace": 0})
+
+		organizations = []string{"org-2"}
+		b.collect(context.Background(), time.Minute)
+		convey.So(deletedEntities(b), convey.ShouldResemble, map[string]float64{"organization": 1, "workspace": 0})
+
+		scraped := []string{}
+		for _, m := range collectMetrics(metrics.LastScrapeTimestamp) {
+			scraped = append(scraped, readMetric(m).labels["organization"])
+		}
+		convey.So(scraped, convey.ShouldResemble, []string{"org-2"})
+	})
+}
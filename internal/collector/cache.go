@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric descriptors.
+var (
+	bufferedMetricsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "buffered_metrics"),
+		"Number of metrics held in memory, by buffer (cache or background).",
+		[]string{"buffer"}, nil,
+	)
+)
+
+// Cache keeps the metrics produced by each Scraper for a configurable TTL, so
+// frequent scrapes can be answered without hitting the Terraform API again.
+// It is safe for concurrent use and is meant to be shared between http requests.
+// It implements the prometheus.Collector interface, reporting the number of metrics it holds.
+type Cache struct {
+	mu      sync.Mutex
+	ttls    map[string]time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	metrics []prometheus.Metric
+	expires time.Time
+}
+
+// NewCache creates a new Cache using the given TTL per Scraper name.
+// Scrapers without a TTL (or with a TTL <= 0) are never cached.
+func NewCache(ttls map[string]time.Duration) *Cache {
+	return &Cache{
+		ttls:    ttls,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// cacheKey identifies the results of a scraper for a given set of organizations.
+func cacheKey(scraper string, organizations []string) string {
+	return scraper + "/" + strings.Join(organizations, ",")
+}
+
+// Enabled reports whether results of the given scraper should be cached.
+func (c *Cache) Enabled(scraper string) bool {
+	return c != nil && c.ttls[scraper] > 0
+}
+
+// Get returns the cached metrics for the scraper and organizations, if they haven't expired yet.
+func (c *Cache) Get(scraper string, organizations []string) ([]prometheus.Metric, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(scraper, organizations)
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.metrics, true
+}
+
+// Set stores the metrics for the scraper and organizations, if the scraper has a TTL configured.
+func (c *Cache) Set(scraper string, organizations []string, metrics []prometheus.Metric) {
+	if !c.Enabled(scraper) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Expired entries of other organizations may never be requested again, e.g. once they're deleted.
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[cacheKey(scraper, organizations)] = cacheEntry{
+		metrics: metrics,
+		expires: time.Now().Add(c.ttls[scraper]),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Cache) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bufferedMetricsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Cache) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Expired entries are counted too, as they are kept until they are requested again or another entry is set.
+	size := 0
+	for _, entry := range c.entries {
+		size += len(entry.metrics)
+	}
+
+	ch <- prometheus.MustNewConstMetric(bufferedMetricsDesc, prometheus.GaugeValue, float64(size), "cache")
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCache(t *testing.T) {
+	desc := prometheus.NewDesc("test_metric", "Test metric", nil, nil)
+	metrics := []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)}
+
+	convey.Convey("Cache", t, func() {
+		cache := NewCache(map[string]time.Duration{"cached": time.Minute, "expired": time.Nanosecond})
+
+		convey.Convey("stores results of scrapers with a TTL", func() {
+			cache.Set("cached", []string{"org"}, metrics)
+			got, ok := cache.Get("cached", []string{"org"})
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(got, convey.ShouldResemble, metrics)
+
+			_, ok = cache.Get("cached", []string{"other-org"})
+			convey.So(ok, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("ignores scrapers without a TTL", func() {
+			convey.So(cache.Enabled("uncached"), convey.ShouldBeFalse)
+			cache.Set("uncached", []string{"org"}, metrics)
+			_, ok := cache.Get("uncached", []string{"org"})
+			convey.So(ok, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("drops expired results", func() {
+			cache.Set("expired", []string{"org"}, metrics)
+			time.Sleep(time.Millisecond)
+			_, ok := cache.Get("expired", []string{"org"})
+			convey.So(ok, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("reports the number of metrics it holds", func() {
+			cache.Set("cached", []string{"org"}, metrics)
+			cache.Set("cached", []string{"other-org"}, metrics)
+
+			ch := make(chan prometheus.Metric, 1)
+			cache.Collect(ch)
+			convey.So(readMetric(<-ch), convey.ShouldResemble, MetricResult{labels: labelMap{"buffer": "cache"}, value: 2, metricType: dto.MetricType_GAUGE})
+		})
+
+		convey.Convey("is disabled when nil", func() {
+			var nilCache *Cache
+			convey.So(nilCache.Enabled("cached"), convey.ShouldBeFalse)
+			_, ok := nilCache.Get("cached", []string{"org"})
+			convey.So(ok, convey.ShouldBeFalse)
+		})
+	})
+}
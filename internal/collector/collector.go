@@ -3,6 +3,12 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +20,11 @@ import (
 	tfe "github.com/hashicorp/go-tfe"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -30,35 +41,126 @@ type Exporter struct {
 	config   setup.Config
 	scrapers []Scraper
 	metrics  Metrics
+	cache    *Cache
+	// configHash is the hash of the configuration, before the organizations get discovered.
+	configHash string
+	// discovered are the organizations listed by the scrape, unless they're configured or listing them failed.
+	discovered []string
 }
 
 // Metrics represents exporter metrics which values can be carried between http requests.
 type Metrics struct {
-	TotalScrapes prometheus.Counter
-	ScrapeErrors *prometheus.CounterVec
-	Error        prometheus.Gauge
+	TotalScrapes        prometheus.Counter
+	ScrapeErrors        *prometheus.CounterVec
+	Error               prometheus.Gauge
+	LastScrapeTimestamp *prometheus.GaugeVec
+	// Status keeps the outcome of the last run of every scraper.
+	Status *Status
+	// Discovery keeps the organizations listed when they aren't configured, for --organizations-discovery-ttl.
+	Discovery *Discovery
 }
 
 var (
-	// scrapers lists all possible collection methods.
+	// Scrapers lists all possible collection methods, add to it with Register.
 	Scrapers = []Scraper{}
+	// tracer creates the spans of the scrapes, it's a no-op unless tracing is enabled.
+	tracer = otel.Tracer("github.com/kaizendorks/terraform-cloud-exporter/internal/collector")
 	// Metric descriptors.
 	scrapeDurationDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, exporter, "collector_duration_seconds"),
 		"Collector time duration.",
 		[]string{"collector"}, nil,
 	)
+	scraperDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_duration_seconds"),
+		"Duration of the last scrape of each scraper.",
+		[]string{"scraper"}, nil,
+	)
+	scraperSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_success"),
+		"Whether the last scrape of each scraper succeeded (1 for success, 0 for failure).",
+		[]string{"scraper"}, nil,
+	)
+	scraperAPIRequestsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_api_requests"),
+		"Number of requests sent to the API by the last scrape of each scraper.",
+		[]string{"scraper"}, nil,
+	)
+	scraperPagesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_pages_fetched"),
+		"Number of pages of lists fetched from the API by the last scrape of each scraper.",
+		[]string{"scraper"}, nil,
+	)
+	scraperItemsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_items_listed"),
+		"Number of items of the pages fetched from the API by the last scrape of each scraper.",
+		[]string{"scraper"}, nil,
+	)
+	scraperPagesTruncatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_pages_truncated"),
+		"Whether the last scrape of each scraper with a page limit stopped any list before its last page (1 for truncated, 0 otherwise).",
+		[]string{"scraper"}, nil,
+	)
 )
 
-// New returns a new Terraform API exporter for the provided Config.
-func New(ctx context.Context, config setup.Config, metrics Metrics) *Exporter {
+// New returns a new Terraform API exporter for the provided Config that runs the given scrapers.
+// The cache is optional and can be shared between exporters to reuse scraper results.
+func New(ctx context.Context, config setup.Config, scrapers []Scraper, metrics Metrics, cache *Cache) *Exporter {
 	return &Exporter{
 		ctx:      ctx,
 		logger:   config.Logger,
 		config:   config,
-		scrapers: Scrapers,
+		scrapers: scrapers,
 		metrics:  metrics,
+		cache:    cache,
+
+		configHash: config.CLI.Hash(),
+	}
+}
+
+// Register adds the Scraper to the registered ones, so it can be selected with --collect like the builtin ones.
+// It's meant to be called from the init function of the package of the Scraper, and panics if its name is already used.
+func Register(scraper Scraper) {
+	for _, registered := range Scrapers {
+		if registered.Name() == scraper.Name() {
+			panic(fmt.Sprintf("scraper already registered: %s", scraper.Name()))
+		}
+	}
+
+	Scrapers = append(Scrapers, scraper)
+}
+
+// FilterScrapers returns the registered Scrapers matching the given names.
+func FilterScrapers(names []string) ([]Scraper, error) {
+	scrapers := []Scraper{}
+	for _, name := range names {
+		found := false
+		for _, scraper := range Scrapers {
+			if scraper.Name() == name {
+				scrapers = append(scrapers, scraper)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown scraper: %s", name)
+		}
 	}
+
+	return scrapers, nil
+}
+
+// DefaultScrapers returns the registered Scrapers run unless others are selected: All but the site-wide ones,
+// which need a Terraform Enterprise site admin token.
+func DefaultScrapers() []Scraper {
+	scrapers := []Scraper{}
+	for _, scraper := range Scrapers {
+		if _, ok := scraper.(siteScraper); !ok {
+			scrapers = append(scrapers, scraper)
+		}
+	}
+
+	return scrapers
 }
 
 // Describe implements the prometheus.Collector interface.
@@ -66,32 +168,57 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- e.metrics.TotalScrapes.Desc()
 	ch <- e.metrics.Error.Desc()
 	e.metrics.ScrapeErrors.Describe(ch)
+	e.metrics.LastScrapeTimestamp.Describe(ch)
 }
 
 // Collect implements the prometheus.Collector interface.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	e.scrape(e.ctx, ch)
+	budget := setup.NewRequestBudget(e.config.MaxAPIRequestsPerScrape)
+	e.scrape(setup.WithRequestBudget(e.ctx, budget), ch)
+	e.collectInstanceInfo(ch)
+	e.collectConfigInfo(ch)
+	e.collectCircuitBreaker(ch)
+	e.collectEndpoints(ch)
+	e.collectRequestBudget(budget, ch)
 
 	ch <- e.metrics.TotalScrapes
 	ch <- e.metrics.Error
 	e.metrics.ScrapeErrors.Collect(ch)
+	e.metrics.LastScrapeTimestamp.Collect(ch)
 }
 
 func (e *Exporter) scrape(ctx context.Context, ch chan<- prometheus.Metric) {
+	ctx, span := tracer.Start(ctx, "scrape")
+	defer span.End()
+
 	e.metrics.TotalScrapes.Inc()
 	if len(e.config.Organizations) == 0 {
-		// Note: At some point this will return a paginated response.
-		oo, err := e.config.Client.Organizations.List(ctx, &tfe.OrganizationListOptions{})
-		if err != nil {
-			e.metrics.Error.Set(1)
-			level.Error(e.logger).Log("msg", "Unable to List Organizations", "err", err)
-			return
-		}
+		organizations, ok := e.metrics.Discovery.Organizations()
+		if !ok {
+			// Note: At some point this will return a paginated response.
+			var oo *tfe.OrganizationList
+			err := e.config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+				// Only the names of the organizations are needed.
+				ctx = setup.WithFields(ctx, setup.Fields{"organizations": {"name"}})
+				oo, err = e.config.Client.Organizations.List(ctx, &tfe.OrganizationListOptions{})
+				return err
+			})
+			if err != nil {
+				e.metrics.Error.Set(1)
+				level.Error(e.logger).Log("msg", "Unable to List Organizations", "err", err)
+				recordError(span, err)
+				return
+			}
 
-		for _, o := range oo.Items {
-			e.config.Organizations = append(e.config.Organizations, o.Name)
+			for _, o := range oo.Items {
+				organizations = append(organizations, o.Name)
+			}
+			e.metrics.Discovery.Set(organizations, e.config.OrganizationsDiscoveryTTL)
 		}
+		e.config.Organizations = organizations
+		e.discovered = e.config.Shard.Filter(e.config.Organizations)
 	}
+	e.config.Organizations = e.config.Shard.Filter(e.config.Organizations)
 
 	e.metrics.Error.Set(0)
 
@@ -102,17 +229,205 @@ func (e *Exporter) scrape(ctx context.Context, ch chan<- prometheus.Metric) {
 		go func(scraper Scraper) {
 			defer wg.Done()
 			label := "collect." + scraper.Name()
+			ctx, span := tracer.Start(ctx, label, trace.WithAttributes(attribute.String("scraper", scraper.Name())))
+			defer span.End()
+			ctx, pages := withPageLimit(ctx, e.config.MaxPages[scraper.Name()])
+			ctx, stats := setup.WithRequestStats(ctx)
+
 			scrapeTime := time.Now()
-			if err := scraper.Scrape(ctx, &e.config, ch); err != nil {
-				level.Error(e.logger).Log("msg", "Error from scraper", "scraper", scraper.Name(), "err", err)
-				e.metrics.ScrapeErrors.WithLabelValues(label).Inc()
+			// Errors are logged and counted per organization, the metrics of the others are still exposed.
+			err := e.scrapeOrganizations(ctx, scraper, ch)
+			recordError(span, err)
+			if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
 				e.metrics.Error.Set(1)
 			}
-			ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(scrapeTime).Seconds(), label)
+			duration := time.Since(scrapeTime).Seconds()
+			success := 0.0
+			if err == nil {
+				success = 1
+			}
+			ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration, label)
+			ch <- prometheus.MustNewConstMetric(scraperDurationDesc, prometheus.GaugeValue, duration, scraper.Name())
+			ch <- prometheus.MustNewConstMetric(scraperSuccessDesc, prometheus.GaugeValue, success, scraper.Name())
+			ch <- prometheus.MustNewConstMetric(scraperAPIRequestsDesc, prometheus.GaugeValue, float64(stats.Requests()), scraper.Name())
+			ch <- prometheus.MustNewConstMetric(scraperPagesDesc, prometheus.GaugeValue, float64(stats.Pages()), scraper.Name())
+			ch <- prometheus.MustNewConstMetric(scraperItemsDesc, prometheus.GaugeValue, float64(stats.Items()), scraper.Name())
+			e.metrics.Status.record(ScraperStatus{
+				Scraper:   scraper.Name(),
+				LastRun:   scrapeTime,
+				Duration:  duration,
+				Requests:  stats.Requests(),
+				Pages:     stats.Pages(),
+				Items:     stats.Items(),
+				Truncated: pages.Truncated(),
+			}, err)
+			if pages != nil {
+				truncated := 0.0
+				if pages.Truncated() {
+					truncated = 1
+				}
+				ch <- prometheus.MustNewConstMetric(scraperPagesTruncatedDesc, prometheus.GaugeValue, truncated, scraper.Name())
+			}
 		}(scraper)
 	}
 }
 
+// scrapeOrganizations runs the scraper for every organization concurrently,
+// so a failing organization doesn't prevent the others from being scraped.
+func (e *Exporter) scrapeOrganizations(ctx context.Context, scraper Scraper, ch chan<- prometheus.Metric) error {
+	if _, ok := scraper.(siteScraper); ok {
+		return e.scrapeSite(ctx, scraper, ch)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+		last   error
+	)
+	for _, organization := range e.config.Organizations {
+		wg.Add(1)
+		go func(organization string) {
+			defer wg.Done()
+			config := e.config
+			config.Organizations = []string{organization}
+			ctx, status := setup.WithStatusRecorder(ctx)
+			err := e.scrapeCached(ctx, scraper, &config, ch)
+			if err == nil {
+				return
+			}
+
+			if errors.Is(ctx.Err(), context.Canceled) {
+				// The scrape was abandoned (e.g. client disconnected or exporter shutting down).
+				level.Debug(e.logger).Log("msg", "Scrape cancelled", "scraper", scraper.Name(), "organization", organization, "err", err)
+			} else {
+				reason := errorReason(err, status.LastErrorStatus())
+				level.Error(e.logger).Log("msg", "Error from scraper", "scraper", scraper.Name(), "organization", organization, "reason", reason, "err", err)
+				e.metrics.ScrapeErrors.WithLabelValues(scraper.Name(), organization, reason).Inc()
+			}
+
+			mu.Lock()
+			failed = append(failed, organization)
+			last = err
+			mu.Unlock()
+		}(organization)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("failed organizations: %s, last error: %w", strings.Join(failed, ","), last)
+	}
+	return nil
+}
+
+// scrapeSite runs a site-wide scraper once, without organizations.
+// Only the first shard runs it, so the replicas don't expose the same series.
+func (e *Exporter) scrapeSite(ctx context.Context, scraper Scraper, ch chan<- prometheus.Metric) error {
+	if e.config.Shard.Index != 0 {
+		return nil
+	}
+
+	config := e.config
+	config.Organizations = nil
+	ctx, status := setup.WithStatusRecorder(ctx)
+	err := e.scrapeCached(ctx, scraper, &config, ch)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		level.Debug(e.logger).Log("msg", "Scrape cancelled", "scraper", scraper.Name(), "err", err)
+	} else {
+		reason := errorReason(err, status.LastErrorStatus())
+		level.Error(e.logger).Log("msg", "Error from scraper", "scraper", scraper.Name(), "reason", reason, "err", err)
+		e.metrics.ScrapeErrors.WithLabelValues(scraper.Name(), "", reason).Inc()
+	}
+	return err
+}
+
+// scrapeCached serves the scraper results from the cache when possible,
+// otherwise it runs the scraper and stores its results for later scrapes.
+func (e *Exporter) scrapeCached(ctx context.Context, scraper Scraper, config *setup.Config, ch chan<- prometheus.Metric) error {
+	if !e.cache.Enabled(scraper.Name()) {
+		return e.run(ctx, scraper, config, ch)
+	}
+
+	if metrics, ok := e.cache.Get(scraper.Name(), config.Organizations); ok {
+		level.Debug(e.logger).Log("msg", "Serving scraper results from cache", "scraper", scraper.Name(), "organizations", len(config.Organizations))
+		for _, m := range metrics {
+			ch <- m
+		}
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	recorder := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range recorder {
+			metrics = append(metrics, m)
+			ch <- m
+		}
+	}()
+
+	err := e.run(ctx, scraper, config, recorder)
+	close(recorder)
+	<-done
+
+	// Partial results aren't cached, so the next scrapes still report the truncation.
+	if err == nil && !pagesTruncated(ctx) {
+		e.cache.Set(scraper.Name(), config.Organizations, metrics)
+	}
+	return err
+}
+
+// run calls the scraper and records when it last succeeded for each organization.
+func (e *Exporter) run(ctx context.Context, scraper Scraper, config *setup.Config, ch chan<- prometheus.Metric) error {
+	if err := scraper.Scrape(ctx, config, ch); err != nil {
+		return err
+	}
+
+	for _, organization := range config.Organizations {
+		e.metrics.LastScrapeTimestamp.WithLabelValues(scraper.Name(), organization).SetToCurrentTime()
+	}
+	return nil
+}
+
+// errorReason classifies scrape errors by their cause: 401, 403, 404, 429, 5xx, timeout, circuit_open, budget_exceeded or other.
+func errorReason(err error, status int) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, setup.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, setup.ErrRequestBudgetExceeded):
+		return "budget_exceeded"
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return "timeout"
+	case status == http.StatusUnauthorized || errors.Is(err, tfe.ErrUnauthorized):
+		return "401"
+	case status == http.StatusForbidden:
+		return "403"
+	case status == http.StatusNotFound || errors.Is(err, tfe.ErrResourceNotFound):
+		return "404"
+	case status == http.StatusTooManyRequests:
+		return "429"
+	case status >= 500:
+		return "5xx"
+	default:
+		return "other"
+	}
+}
+
+// recordError marks the span as failed if there was an error.
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
 // NewMetrics creates new Metrics instance.
 func NewMetrics() Metrics {
 	return Metrics{
@@ -126,13 +441,21 @@ func NewMetrics() Metrics {
 			Namespace: namespace,
 			Subsystem: exporter,
 			Name:      "scrape_errors_total",
-			Help:      "Total number of times an error occurred scraping the Terraform API.",
-		}, []string{"collector"}),
+			Help:      "Total number of times an error occurred scraping the Terraform API, by scraper, organization and reason (401, 403, 404, 429, 5xx, timeout, circuit_open, budget_exceeded or other).",
+		}, []string{"scraper", "organization", "reason"}),
 		Error: prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: exporter,
 			Name:      "last_scrape_error",
 			Help:      "Whether the last scrape of metrics from Terraform API resulted in an error (1 for error, 0 for success).",
 		}),
+		LastScrapeTimestamp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "last_scrape_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful scrape of each scraper and organization.",
+		}, []string{"scraper", "organization"}),
+		Status:    NewStatus(),
+		Discovery: NewDiscovery(),
 	}
 }
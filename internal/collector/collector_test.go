@@ -1,8 +1,24 @@
 package collector
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
 )
 
 type labelMap map[string]string
@@ -31,3 +47,230 @@ func readMetric(m prometheus.Metric) MetricResult {
 	}
 	panic("Unsupported metric type")
 }
+
+// fakeScraper is a Scraper that fails with err (for all organizations, or only failOrganization if set),
+// or emits a single test metric per organization.
+type fakeScraper struct {
+	name             string
+	err              error
+	failOrganization string
+}
+
+var fakeScraperDesc = prometheus.NewDesc("test_fake_scraper", "Test metric", []string{"scraper", "organization"}, nil)
+
+func (s fakeScraper) Name() string    { return s.name }
+func (s fakeScraper) Help() string    { return "Fake scraper" }
+func (s fakeScraper) Version() string { return "v2" }
+func (s fakeScraper) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fakeScraperDesc
+}
+func (s fakeScraper) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	for _, organization := range config.Organizations {
+		if s.err != nil && (s.failOrganization == "" || s.failOrganization == organization) {
+			return s.err
+		}
+		ch <- prometheus.MustNewConstMetric(fakeScraperDesc, prometheus.GaugeValue, 1, s.name, organization)
+	}
+	return nil
+}
+
+// fakeSiteScraper is a site-wide Scraper emitting a single test metric.
+type fakeSiteScraper struct {
+	fakeScraper
+}
+
+func (s fakeSiteScraper) siteWide() {}
+func (s fakeSiteScraper) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(fakeScraperDesc, prometheus.GaugeValue, float64(len(config.Organizations)), s.name, "")
+	return nil
+}
+
+// collectByName runs the exporter and returns the collected metrics grouped by their fully-qualified name.
+func collectByName(e *Exporter) map[string][]MetricResult {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		e.Collect(ch)
+	}()
+
+	result := map[string][]MetricResult{}
+	for m := range ch {
+		name := fqNameRegexp.FindStringSubmatch(m.Desc().String())[1]
+		result[name] = append(result[name], readMetric(m))
+	}
+
+	return result
+}
+
+var fqNameRegexp = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+func TestExporterScraperMetrics(t *testing.T) {
+	config := setup.Config{
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+		Logger: log.NewNopLogger(),
+	}
+	scrapers := []Scraper{fakeScraper{name: "ok"}, fakeScraper{name: "failing", err: errors.New("test error")}}
+
+	metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+
+	convey.Convey("Scraper metrics", t, func() {
+		convey.So(metrics["test_fake_scraper"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"scraper": "ok", "organization": "test-org"}, value: 1, metricType: dto.MetricType_GAUGE},
+		})
+		convey.So(metrics["tf_exporter_scrape_success"], convey.ShouldHaveLength, 2)
+		for _, m := range metrics["tf_exporter_scrape_success"] {
+			if m.labels["scraper"] == "ok" {
+				convey.So(m.value, convey.ShouldEqual, 1)
+			} else {
+				convey.So(m.value, convey.ShouldEqual, 0)
+			}
+		}
+		convey.So(metrics["tf_exporter_scrape_duration_seconds"], convey.ShouldHaveLength, 2)
+		// The fake scrapers don't send requests.
+		convey.So(metrics["tf_exporter_scrape_api_requests"], convey.ShouldHaveLength, 2)
+		convey.So(metrics["tf_exporter_scrape_api_requests"][0].value, convey.ShouldEqual, 0)
+		convey.So(metrics["tf_exporter_scrape_pages_fetched"], convey.ShouldHaveLength, 2)
+		convey.So(metrics["tf_exporter_scrape_items_listed"], convey.ShouldHaveLength, 2)
+		convey.So(metrics["tf_exporter_last_scrape_error"][0].value, convey.ShouldEqual, 1)
+		convey.So(metrics["tf_exporter_last_scrape_timestamp_seconds"], convey.ShouldHaveLength, 1)
+		convey.So(metrics["tf_exporter_last_scrape_timestamp_seconds"][0].labels, convey.ShouldResemble, labelMap{"scraper": "ok", "organization": "test-org"})
+	})
+}
+
+func TestExporterRequestBudget(t *testing.T) {
+	scrapers := []Scraper{fakeScraper{name: "ok"}}
+
+	convey.Convey("Without a budget", t, func() {
+		config := setup.Config{CLI: setup.CLI{Organizations: []string{"test-org"}}, Logger: log.NewNopLogger()}
+		metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+		convey.So(metrics["tf_exporter_scrape_truncated"], convey.ShouldBeEmpty)
+	})
+
+	convey.Convey("With a budget", t, func() {
+		config := setup.Config{CLI: setup.CLI{Organizations: []string{"test-org"}, MaxAPIRequestsPerScrape: 10}, Logger: log.NewNopLogger()}
+		metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+		convey.So(metrics["tf_exporter_scrape_truncated"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{}, value: 0, metricType: dto.MetricType_GAUGE},
+		})
+	})
+}
+
+func TestExporterConfigInfo(t *testing.T) {
+	config := setup.Config{
+		CLI: setup.CLI{
+			Organizations: []string{"org1", "org2"},
+			CacheTTL:      map[string]time.Duration{"workspaces": time.Minute, "runs": 5 * time.Minute},
+			APIToken:      "secret",
+		},
+		Logger: log.NewNopLogger(),
+	}
+	scrapers := []Scraper{fakeScraper{name: "workspaces"}, fakeScraper{name: "runs"}}
+
+	metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+
+	convey.Convey("Config info", t, func() {
+		convey.So(metrics["tf_exporter_config_info"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{
+				"organizations": "2",
+				"scrapers":      "runs,workspaces",
+				"page_size":     "40",
+				"cache_ttl":     "runs=5m0s;workspaces=1m0s",
+				"hash":          config.CLI.Hash(),
+			}, value: 1, metricType: dto.MetricType_GAUGE},
+		})
+	})
+}
+
+func TestExporterEndpoints(t *testing.T) {
+	scrapers := []Scraper{fakeScraper{name: "ok"}}
+
+	convey.Convey("Without failover", t, func() {
+		config := setup.Config{CLI: setup.CLI{Organizations: []string{"test-org"}}, Logger: log.NewNopLogger()}
+		metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+		convey.So(metrics["tf_exporter_api_endpoint_active"], convey.ShouldBeEmpty)
+	})
+
+	convey.Convey("With failover", t, func() {
+		endpoints, err := setup.NewEndpoints("https://tfe.example.com/", []string{"https://tfe-dr.example.com/"})
+		convey.So(err, convey.ShouldBeNil)
+		config := setup.Config{CLI: setup.CLI{Organizations: []string{"test-org"}}, Endpoints: endpoints, Logger: log.NewNopLogger()}
+		metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+		convey.So(metrics["tf_exporter_api_endpoint_active"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"address": "https://tfe.example.com"}, value: 1, metricType: dto.MetricType_GAUGE},
+			{labels: labelMap{"address": "https://tfe-dr.example.com"}, value: 0, metricType: dto.MetricType_GAUGE},
+		})
+	})
+}
+
+func TestExporterPartialFailures(t *testing.T) {
+	config := setup.Config{
+		CLI:    setup.CLI{Organizations: []string{"org-1", "org-2"}},
+		Logger: log.NewNopLogger(),
+	}
+	scrapers := []Scraper{fakeScraper{name: "partial", err: errors.New("test error"), failOrganization: "org-1"}}
+
+	metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+
+	convey.Convey("Partial failures", t, func() {
+		convey.So(metrics["test_fake_scraper"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"scraper": "partial", "organization": "org-2"}, value: 1, metricType: dto.MetricType_GAUGE},
+		})
+		convey.So(metrics["tf_exporter_last_scrape_timestamp_seconds"], convey.ShouldHaveLength, 1)
+		convey.So(metrics["tf_exporter_last_scrape_timestamp_seconds"][0].labels["organization"], convey.ShouldEqual, "org-2")
+		convey.So(metrics["tf_exporter_scrape_success"][0].value, convey.ShouldEqual, 0)
+		convey.So(metrics["tf_exporter_scrape_errors_total"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"scraper": "partial", "organization": "org-1", "reason": "other"}, value: 1, metricType: dto.MetricType_COUNTER},
+		})
+	})
+}
+
+func TestExporterSiteScrapers(t *testing.T) {
+	config := setup.Config{
+		CLI:    setup.CLI{Organizations: []string{"org-1", "org-2"}},
+		Logger: log.NewNopLogger(),
+	}
+	scrapers := []Scraper{fakeSiteScraper{fakeScraper{name: "site"}}}
+
+	convey.Convey("Site-wide scrapers run once, without organizations", t, func() {
+		metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+		convey.So(metrics["test_fake_scraper"], convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"scraper": "site", "organization": ""}, value: 0, metricType: dto.MetricType_GAUGE},
+		})
+	})
+
+	convey.Convey("Site-wide scrapers only run on the first shard", t, func() {
+		config.Shard = setup.Shard{Index: 1, Total: 2}
+		metrics := collectByName(New(context.Background(), config, scrapers, NewMetrics(), nil))
+		convey.So(metrics["test_fake_scraper"], convey.ShouldBeEmpty)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	registered := Scrapers
+	defer func() { Scrapers = registered }()
+
+	convey.Convey("Registered scrapers can be selected", t, func() {
+		Register(fakeScraper{name: "plugin"})
+		scrapers, err := FilterScrapers([]string{"plugin"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(scrapers, convey.ShouldResemble, []Scraper{fakeScraper{name: "plugin"}})
+	})
+
+	convey.Convey("Names can't be registered twice", t, func() {
+		convey.So(func() { Register(fakeScraper{name: "workspaces"}) }, convey.ShouldPanic)
+	})
+}
+
+func TestErrorReason(t *testing.T) {
+	convey.Convey("Error reasons", t, func() {
+		convey.So(errorReason(fmt.Errorf("%w, organization=test-org", tfe.ErrUnauthorized), 0), convey.ShouldEqual, "401")
+		convey.So(errorReason(errors.New("forbidden"), http.StatusForbidden), convey.ShouldEqual, "403")
+		convey.So(errorReason(tfe.ErrResourceNotFound, 0), convey.ShouldEqual, "404")
+		convey.So(errorReason(errors.New("rate limited"), http.StatusTooManyRequests), convey.ShouldEqual, "429")
+		convey.So(errorReason(errors.New("bad gateway"), http.StatusBadGateway), convey.ShouldEqual, "5xx")
+		convey.So(errorReason(fmt.Errorf("%w", context.DeadlineExceeded), 0), convey.ShouldEqual, "timeout")
+		convey.So(errorReason(fmt.Errorf("giving up: %w", setup.ErrCircuitOpen), 0), convey.ShouldEqual, "circuit_open")
+		convey.So(errorReason(fmt.Errorf("giving up: %w", setup.ErrRequestBudgetExceeded), 0), convey.ShouldEqual, "budget_exceeded")
+		convey.So(errorReason(errors.New("unknown"), 0), convey.ShouldEqual, "other")
+	})
+}
@@ -0,0 +1,281 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// cost_estimates is the Metric subsystem we use.
+	costEstimatesSubsystem = "cost_estimates"
+)
+
+// Metric descriptors.
+var (
+	CostEstimatesDeltaMonthlyCost = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, costEstimatesSubsystem, "delta_monthly_cost"),
+		"Change of the estimated monthly cost of the workspace the current run would apply, in USD",
+		[]string{"organization", "workspace"}, nil,
+	)
+	CostEstimatesRunInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, costEstimatesSubsystem, "run_info"),
+		"Current run of the workspace the cost estimate belongs to, kept apart from its cost so a new run doesn't start a new series of it",
+		[]string{"organization", "workspace", "run"}, nil,
+	)
+	CostEstimatesOrganizationMonthlyCost = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, costEstimatesSubsystem, "organization_monthly_cost"),
+		"Estimated monthly cost of the organization, in USD, the sum of the finished cost estimates of the current runs of its workspaces",
+		[]string{"organization"}, nil,
+	)
+	CostEstimatesEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, costEstimatesSubsystem, "enabled"),
+		"Whether cost estimation is enabled for the workspaces of the organization, a setting of the organization (1 for enabled, 0 for disabled)",
+		[]string{"organization"}, nil,
+	)
+	CostEstimatesWorkspaces = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, costEstimatesSubsystem, "workspaces_count"),
+		"Number of workspaces of the organization",
+		[]string{"organization"}, nil,
+	)
+	CostEstimatesWorkspacesEstimated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, costEstimatesSubsystem, "workspaces_estimated_count"),
+		"Number of workspaces of the organization whose current run got a cost estimate",
+		[]string{"organization"}, nil,
+	)
+)
+
+var (
+	// costEstimatesWorkspacesFields are the only fields of the workspaces (and their current run) needed to find their cost estimate.
+	costEstimatesWorkspacesFields = setup.Fields{
+		"workspaces": {"name", "current-run"},
+		"runs":       {"cost-estimate"},
+	}
+	// costEstimatesFields are the only fields of the cost estimates turned into metrics.
+	costEstimatesFields = setup.Fields{"cost-estimates": {"status", "delta-monthly-cost", "proposed-monthly-cost"}}
+	// costEstimatesOrganizationFields are the only fields of the organizations needed to know whether cost estimation is enabled.
+	costEstimatesOrganizationFields = setup.Fields{"organizations": {"cost-estimation-enabled"}}
+)
+
+// ScrapeCostEstimates scrapes the cost estimates of the current runs of the workspaces.
+type ScrapeCostEstimates struct{}
+
+func init() {
+	Register(ScrapeCostEstimates{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeCostEstimates) Name() string {
+	return costEstimatesSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeCostEstimates) Help() string {
+	return "Scrape the cost estimates of the current runs from the Cost Estimates API: https://www.terraform.io/cloud-docs/api-docs/cost-estimates"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeCostEstimates) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeCostEstimates) Describe(ch chan<- *prometheus.Desc) {
+	ch <- CostEstimatesDeltaMonthlyCost
+	ch <- CostEstimatesRunInfo
+	ch <- CostEstimatesOrganizationMonthlyCost
+	ch <- CostEstimatesEnabled
+	ch <- CostEstimatesWorkspaces
+	ch <- CostEstimatesWorkspacesEstimated
+}
+
+// organizationEstimates adds up the cost estimates of the workspaces of an organization, from concurrent pages:
+// The estimated monthly cost, and the number of workspaces estimated.
+type organizationEstimates struct {
+	mu        sync.Mutex
+	cost      float64
+	estimated int
+}
+
+func (e *organizationEstimates) add(cost float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.cost += cost
+}
+
+func (e *organizationEstimates) addEstimated() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.estimated++
+}
+
+func (e *organizationEstimates) value() (float64, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.cost, e.estimated
+}
+
+// getCostEstimate returns the finished cost estimate of the current run of the workspace, or nil if there's none.
+func getCostEstimate(ctx context.Context, w *tfe.Workspace, config *setup.Config) (*tfe.CostEstimate, error) {
+	// Cost estimation is disabled, or the run didn't get to it yet.
+	if w.CurrentRun == nil || w.CurrentRun.CostEstimate == nil {
+		return nil, nil
+	}
+
+	var estimate *tfe.CostEstimate
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		estimate, err = config.Client.CostEstimates.Read(setup.WithFields(ctx, costEstimatesFields), w.CurrentRun.CostEstimate.ID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w, (workspace=%s, run=%s)", err, w.Name, w.CurrentRun.ID)
+	}
+	if estimate.Status != tfe.CostEstimateFinished {
+		return nil, nil
+	}
+
+	return estimate, nil
+}
+
+func getWorkspaceCostEstimate(ctx context.Context, organization string, w *tfe.Workspace, config *setup.Config, estimates *organizationEstimates, ch chan<- prometheus.Metric) error {
+	if w.CurrentRun != nil && w.CurrentRun.CostEstimate != nil {
+		estimates.addEstimated()
+	}
+
+	estimate, err := getCostEstimate(ctx, w, config)
+	if err != nil || estimate == nil {
+		return err
+	}
+
+	delta, err := strconv.ParseFloat(estimate.DeltaMonthlyCost, 64)
+	if err != nil {
+		return fmt.Errorf("%w, (workspace=%s, run=%s)", err, w.Name, w.CurrentRun.ID)
+	}
+	proposed, err := strconv.ParseFloat(estimate.ProposedMonthlyCost, 64)
+	if err != nil {
+		return fmt.Errorf("%w, (workspace=%s, run=%s)", err, w.Name, w.CurrentRun.ID)
+	}
+	estimates.add(proposed)
+
+	for _, m := range []prometheus.Metric{
+		prometheus.MustNewConstMetric(CostEstimatesDeltaMonthlyCost, prometheus.GaugeValue, delta, organization, w.Name),
+		prometheus.MustNewConstMetric(CostEstimatesRunInfo, prometheus.GaugeValue, 1, organization, w.Name, w.CurrentRun.ID),
+	} {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getCostEstimatesPage(ctx context.Context, page int, organization string, config *setup.Config, estimates *organizationEstimates, ch chan<- prometheus.Metric) (_ *tfe.WorkspaceList, err error) {
+	ctx, span := tracer.Start(ctx, "cost estimates workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var workspacesList *tfe.WorkspaceList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(setup.WithQuery(ctx, config.WorkspacesSearch()), costEstimatesWorkspacesFields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+			Include: []tfe.WSIncludeOpt{tfe.WSCurrentRun},
+		})
+		return err
+	})
+	if err != nil {
+		return workspacesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range workspacesList.Items {
+		w := w
+		g.Go(func() error {
+			return getWorkspaceCostEstimate(ctx, organization, w, config, estimates, ch)
+		})
+	}
+
+	return workspacesList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeCostEstimates) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			var organization *tfe.Organization
+			err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+				organization, err = config.Client.Organizations.Read(setup.WithFields(ctx, costEstimatesOrganizationFields), name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%w, (organization=%s)", err, name)
+			}
+
+			estimates := &organizationEstimates{}
+			list, err := getCostEstimatesPage(ctx, 1, name, config, estimates, ch)
+			if err != nil {
+				return err
+			}
+
+			err = fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getCostEstimatesPage(ctx, page, name, config, estimates, ch)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			// The totals are only sent once every workspace is added up, partial ones would be misleading.
+			enabled := 0.0
+			if organization.CostEstimationEnabled {
+				enabled = 1
+			}
+			cost, estimated := estimates.value()
+			for _, m := range []prometheus.Metric{
+				prometheus.MustNewConstMetric(CostEstimatesOrganizationMonthlyCost, prometheus.GaugeValue, cost, name),
+				prometheus.MustNewConstMetric(CostEstimatesEnabled, prometheus.GaugeValue, enabled, name),
+				prometheus.MustNewConstMetric(CostEstimatesWorkspaces, prometheus.GaugeValue, float64(list.Pagination.TotalCount), name),
+				prometheus.MustNewConstMetric(CostEstimatesWorkspacesEstimated, prometheus.GaugeValue, float64(estimated), name),
+			} {
+				select {
+				case ch <- m:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeCostEstimates(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			if got := r.URL.Query().Get("include"); got != "current_run" {
+				t.Errorf("unexpected include: %s", got)
+			}
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":4}},
+				"data":[
+					{"id":"ws-a","type":"workspaces","attributes":{"name":"network"},"relationships":{"current-run":{"data":{"id":"run-a","type":"runs"}}}},
+					{"id":"ws-b","type":"workspaces","attributes":{"name":"cluster"},"relationships":{"current-run":{"data":{"id":"run-b","type":"runs"}}}},
+					{"id":"ws-c","type":"workspaces","attributes":{"name":"app"},"relationships":{"current-run":{"data":{"id":"run-c","type":"runs"}}}},
+					{"id":"ws-d","type":"workspaces","attributes":{"name":"empty"},"relationships":{"current-run":{"data":null}}}
+				],
+				"included":[
+					{"id":"run-a","type":"runs","relationships":{"cost-estimate":{"data":{"id":"ce-a","type":"cost-estimates"}}}},
+					{"id":"run-b","type":"runs","relationships":{"cost-estimate":{"data":{"id":"ce-b","type":"cost-estimates"}}}},
+					{"id":"run-c","type":"runs","relationships":{"cost-estimate":{"data":null}}}
+				]
+			}`))
+		case "/api/v2/organizations/test-org":
+			w.Write([]byte(`{"data":{"id":"test-org","type":"organizations","attributes":{"name":"test-org","cost-estimation-enabled":true}}}`))
+		case "/api/v2/cost-estimates/ce-a":
+			w.Write([]byte(`{"data":{"id":"ce-a","type":"cost-estimates","attributes":{"status":"finished","delta-monthly-cost":"-12.5","proposed-monthly-cost":"100.25"}}}`))
+		case "/api/v2/cost-estimates/ce-b":
+			w.Write([]byte(`{"data":{"id":"ce-b","type":"cost-estimates","attributes":{"status":"errored","delta-monthly-cost":"","proposed-monthly-cost":""}}}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeCostEstimates{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// The workspaces are read concurrently, so their order isn't deterministic.
+	got := []MetricResult{}
+	for m := range ch {
+		got = append(got, readMetric(m))
+	}
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "workspace": "network"}, value: -12.5, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "network", "run": "run-a"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 100.25, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 4, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 2, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldHaveLength, len(counterExpected))
+		for _, expect := range counterExpected {
+			convey.So(got, convey.ShouldContain, expect)
+		}
+	})
+}
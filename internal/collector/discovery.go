@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// Discovery keeps the organizations listed when they aren't configured, so they're only listed again once their
+// TTL expires or they're refreshed, instead of on every scrape. It is safe for concurrent use and is meant
+// to be shared between http requests.
+type Discovery struct {
+	mu            sync.Mutex
+	organizations []string
+	expires       time.Time
+}
+
+// NewDiscovery creates a new Discovery, without any organization listed yet.
+func NewDiscovery() *Discovery {
+	return &Discovery{}
+}
+
+// Organizations returns the organizations listed last, if they haven't expired yet.
+func (d *Discovery) Organizations() ([]string, bool) {
+	if d == nil {
+		return nil, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.organizations == nil || time.Now().After(d.expires) {
+		return nil, false
+	}
+
+	return append([]string{}, d.organizations...), true
+}
+
+// Set keeps the organizations listed for the TTL. They aren't kept with a TTL <= 0.
+func (d *Discovery) Set(organizations []string, ttl time.Duration) {
+	if d == nil || ttl <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.organizations = append([]string{}, organizations...)
+	d.expires = time.Now().Add(ttl)
+}
+
+// Refresh forgets the organizations listed, so the next scrape lists them again.
+func (d *Discovery) Refresh() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.organizations = nil
+}
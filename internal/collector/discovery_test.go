@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiscovery(t *testing.T) {
+	convey.Convey("Keeps the organizations for the TTL", t, func() {
+		d := NewDiscovery()
+		_, ok := d.Organizations()
+		convey.So(ok, convey.ShouldBeFalse)
+
+		d.Set([]string{"org-1"}, time.Minute)
+		organizations, ok := d.Organizations()
+		convey.So(ok, convey.ShouldBeTrue)
+		convey.So(organizations, convey.ShouldResemble, []string{"org-1"})
+
+		d.Refresh()
+		_, ok = d.Organizations()
+		convey.So(ok, convey.ShouldBeFalse)
+	})
+
+	convey.Convey("Doesn't keep them without a TTL, or once expired", t, func() {
+		d := NewDiscovery()
+		d.Set([]string{"org-1"}, 0)
+		_, ok := d.Organizations()
+		convey.So(ok, convey.ShouldBeFalse)
+
+		d.Set([]string{"org-1"}, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		_, ok = d.Organizations()
+		convey.So(ok, convey.ShouldBeFalse)
+	})
+}
+
+func TestExporterDiscovery(t *testing.T) {
+	listed := 0
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations":
+			listed++
+			w.Write([]byte(`{"data":[{"id":"org-1","type":"organizations","attributes":{"name":"org-1"}}]}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	scrapers := []Scraper{fakeScraper{name: "ok"}}
+	scrape := func(config setup.Config, metrics Metrics) []MetricResult {
+		return collectByName(New(context.Background(), config, scrapers, metrics, nil))["test_fake_scraper"]
+	}
+
+	convey.Convey("The organizations are listed on every scrape without a TTL", t, func() {
+		listed = 0
+		config := setup.Config{Client: *client, Logger: log.NewNopLogger()}
+		metrics := NewMetrics()
+		scrape(config, metrics)
+		scrape(config, metrics)
+		convey.So(listed, convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("The organizations are listed once within the TTL, until they're refreshed", t, func() {
+		listed = 0
+		config := setup.Config{Client: *client, CLI: setup.CLI{OrganizationsDiscoveryTTL: time.Hour}, Logger: log.NewNopLogger()}
+		metrics := NewMetrics()
+		convey.So(scrape(config, metrics), convey.ShouldHaveLength, 1)
+		convey.So(scrape(config, metrics), convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"scraper": "ok", "organization": "org-1"}, value: 1, metricType: dto.MetricType_GAUGE},
+		})
+		convey.So(listed, convey.ShouldEqual, 1)
+
+		metrics.Discovery.Refresh()
+		scrape(config, metrics)
+		convey.So(listed, convey.ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricDoc documents a metric that a Scraper, or the exporter itself, can emit.
+type MetricDoc struct {
+	// Scraper emitting the metric, or "exporter" for the metrics of the exporter itself.
+	Scraper string   `json:"scraper"`
+	Name    string   `json:"name"`
+	Help    string   `json:"help"`
+	Labels  []string `json:"labels"`
+}
+
+// exporterDescs are the metrics of the exporter itself, emitted regardless of the scrapers.
+// TestDocs fails on any descriptor defined in collector.go, instance.go, cache.go or background.go that isn't sent here.
+func exporterDescs(ch chan<- *prometheus.Desc) {
+	metrics := NewMetrics()
+	(&Exporter{metrics: metrics}).Describe(ch)
+	(&Cache{}).Describe(ch)
+	ch <- scrapeDurationDesc
+	ch <- scraperDurationDesc
+	ch <- scraperSuccessDesc
+	ch <- scraperAPIRequestsDesc
+	ch <- scraperPagesDesc
+	ch <- scraperItemsDesc
+	ch <- scraperPagesTruncatedDesc
+	ch <- instanceInfoDesc
+	ch <- configInfoDesc
+	ch <- scrapeTruncatedDesc
+	ch <- circuitOpenDesc
+	ch <- apiEndpointActiveDesc
+	ch <- collectionAgeDesc
+	ch <- deletedEntitiesDesc
+}
+
+// Docs returns the documentation of every metric the exporter and the given scrapers can emit,
+// generated from their descriptors. It fails on any descriptor that can't be parsed, instead of leaving it undocumented.
+func Docs(scrapers []Scraper) ([]MetricDoc, error) {
+	docs, err := describe("exporter", exporterDescs)
+	if err != nil {
+		return nil, err
+	}
+	for _, scraper := range scrapers {
+		scraperDocs, err := describe(scraper.Name(), scraper.Describe)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, scraperDocs...)
+	}
+
+	return docs, nil
+}
+
+func describe(scraper string, describe func(ch chan<- *prometheus.Desc)) ([]MetricDoc, error) {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		defer close(ch)
+		describe(ch)
+	}()
+
+	docs := []MetricDoc{}
+	var err error
+	// The channel is drained on errors, so the describing goroutine finishes.
+	for desc := range ch {
+		doc, parseErr := parseDesc(desc)
+		if parseErr != nil {
+			if err == nil {
+				err = fmt.Errorf("%w, (scraper=%s)", parseErr, scraper)
+			}
+			continue
+		}
+		doc.Scraper = scraper
+		docs = append(docs, doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// descRegexp matches the string representation of a prometheus.Desc, as it has no getters for its fields.
+// The representation isn't part of the API of client_golang, so parseDesc fails if an upgrade changes it.
+var descRegexp = regexp.MustCompile(`^Desc\{fqName: ("(?:[^"\\]|\\.)*"), help: ("(?:[^"\\]|\\.)*"), constLabels: \{.*\}, variableLabels: \[(.*)\]\}$`)
+
+func parseDesc(desc *prometheus.Desc) (MetricDoc, error) {
+	match := descRegexp.FindStringSubmatch(desc.String())
+	if match == nil {
+		return MetricDoc{}, fmt.Errorf("unable to parse metric descriptor: %s", desc)
+	}
+
+	name, err := strconv.Unquote(match[1])
+	if err != nil {
+		return MetricDoc{}, fmt.Errorf("unable to parse the name of metric descriptor %s: %w", desc, err)
+	}
+	// Invalid descriptors, e.g. prometheus.NewInvalidDesc, have no name.
+	if name == "" {
+		return MetricDoc{}, fmt.Errorf("invalid metric descriptor: %s", desc)
+	}
+	help, err := strconv.Unquote(match[2])
+	if err != nil {
+		return MetricDoc{}, fmt.Errorf("unable to parse the help of metric descriptor %s: %w", desc, err)
+	}
+
+	return MetricDoc{Name: name, Help: help, Labels: strings.Fields(match[3])}, nil
+}
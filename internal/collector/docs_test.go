@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestDocs(t *testing.T) {
+	convey.Convey("Documents the metrics of the exporter and the scrapers", t, func() {
+		docs, err := Docs([]Scraper{fakeScraper{name: "fake"}})
+		convey.So(err, convey.ShouldBeNil)
+
+		convey.So(docs, convey.ShouldContain, MetricDoc{Scraper: "fake", Name: "test_fake_scraper", Help: "Test metric", Labels: []string{"scraper", "organization"}})
+		convey.So(docs, convey.ShouldContain, MetricDoc{Scraper: "exporter", Name: "tf_exporter_scrape_success", Help: "Whether the last scrape of each scraper succeeded (1 for success, 0 for failure).", Labels: []string{"scraper"}})
+	})
+
+	convey.Convey("Parses any help text", t, func() {
+		desc := prometheus.NewDesc("test_quoted", `Help with "quotes", {braces} and [brackets].`, nil, prometheus.Labels{"const": "value"})
+		doc, err := parseDesc(desc)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(doc, convey.ShouldResemble, MetricDoc{Name: "test_quoted", Help: `Help with "quotes", {braces} and [brackets].`, Labels: []string{}})
+	})
+
+	convey.Convey("Documents every metric of the registered scrapers", t, func() {
+		_, err := Docs(Scrapers)
+		convey.So(err, convey.ShouldBeNil)
+	})
+
+	convey.Convey("Documents every metric of the exporter itself", t, func() {
+		defined, sent := exporterDescNames(t)
+		convey.So(defined, convey.ShouldNotBeEmpty)
+		for _, name := range defined {
+			convey.So(sent, convey.ShouldContainKey, name)
+		}
+	})
+
+	convey.Convey("Fails on descriptors it can't parse", t, func() {
+		_, err := describe("broken", func(ch chan<- *prometheus.Desc) {
+			ch <- prometheus.NewDesc("test_ok", "Test metric", nil, nil)
+			ch <- prometheus.NewInvalidDesc(errors.New("test error"))
+		})
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+// exporterDescFiles define the descriptors of the metrics of the exporter itself.
+var exporterDescFiles = []string{"collector.go", "instance.go", "cache.go", "background.go"}
+
+// exporterDescNames returns the package variables of exporterDescFiles holding a prometheus.NewDesc,
+// and the identifiers used by exporterDescs and the Describe methods of those files, which send them.
+func exporterDescNames(t *testing.T) ([]string, map[string]bool) {
+	fset := token.NewFileSet()
+	defined := []string{}
+	sent := map[string]bool{}
+	for _, name := range append(exporterDescFiles, "docs.go") {
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					value, ok := spec.(*ast.ValueSpec)
+					if !ok {
+						continue
+					}
+					for i, ident := range value.Names {
+						if i < len(value.Values) && isNewDesc(value.Values[i]) {
+							defined = append(defined, ident.Name)
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Name.Name != "exporterDescs" && decl.Name.Name != "Describe" {
+					continue
+				}
+				ast.Inspect(decl.Body, func(node ast.Node) bool {
+					if ident, ok := node.(*ast.Ident); ok {
+						sent[ident.Name] = true
+					}
+					return true
+				})
+			}
+		}
+	}
+
+	return defined, sent
+}
+
+func isNewDesc(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := fun.X.(*ast.Ident)
+
+	return ok && pkg.Name == "prometheus" && fun.Sel.Name == "NewDesc"
+}
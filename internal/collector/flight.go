@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Flight coalesces concurrent collections of the same scrapers and organizations, so simultaneous
+// scrapes (e.g. from a HA pair of Prometheus servers) are served from a single collection,
+// instead of multiplying the requests to the Terraform API.
+// Exporters sharing a Flight are expected to share their Metrics and Cache.
+type Flight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a collection shared by the callers waiting for it.
+type flightCall struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+
+	done    chan struct{}
+	metrics []prometheus.Metric
+}
+
+// NewFlight returns a new Flight.
+func NewFlight() *Flight {
+	return &Flight{calls: map[string]*flightCall{}}
+}
+
+// Collector returns a prometheus.Collector serving the metrics of the exporter, sharing the
+// collection with any concurrent call for the same scrapers and organizations.
+// The collection isn't bound to the context of the exporter that started it, but runs until the contexts
+// of every exporter waiting for it are done, i.e. until the longest deadline among them.
+func (f *Flight) Collector(e *Exporter) prometheus.Collector {
+	return &flightCollector{flight: f, exporter: e}
+}
+
+type flightCollector struct {
+	flight   *Flight
+	exporter *Exporter
+}
+
+// Describe implements the prometheus.Collector interface.
+// The collector is unchecked, as the metrics depend on what the scrapers find.
+func (c *flightCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements the prometheus.Collector interface.
+// Nothing is collected if the context of the exporter is done before the shared collection finishes.
+func (c *flightCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := c.exporter.ctx
+	call := c.flight.join(c.exporter)
+
+	select {
+	case <-call.done:
+		for _, m := range call.metrics {
+			ch <- m
+		}
+	case <-ctx.Done():
+		c.flight.leave(flightKey(c.exporter), call)
+	}
+}
+
+// join returns the collection of the scrapers and organizations of the exporter in progress,
+// or starts it, detached from the context of the exporter.
+func (f *Flight) join(e *Exporter) *flightCall {
+	key := flightKey(e)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call, ok := f.calls[key]
+	if !ok {
+		call = &flightCall{done: make(chan struct{})}
+		call.ctx, call.cancel = context.WithCancel(detachedContext{e.ctx})
+		f.calls[key] = call
+
+		shared := *e
+		shared.ctx = call.ctx
+		go func() {
+			defer close(call.done)
+			call.metrics = collectMetrics(&shared)
+			call.cancel()
+
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.calls[key] == call {
+				delete(f.calls, key)
+			}
+		}()
+	}
+	call.waiters++
+
+	return call
+}
+
+// leave stops waiting for the collection, cancelling it once nobody waits for it anymore.
+// A cancelled collection isn't joined by the later callers.
+func (f *Flight) leave(key string, call *flightCall) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	call.cancel()
+	if f.calls[key] == call {
+		delete(f.calls, key)
+	}
+}
+
+// detachedContext keeps the values of its parent, e.g. its trace span, without its deadline and cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// flightKey identifies the collections of the same scrapers and organizations.
+func flightKey(e *Exporter) string {
+	names := make([]string, 0, len(e.scrapers))
+	for _, scraper := range e.scrapers {
+		names = append(names, scraper.Name())
+	}
+	sort.Strings(names)
+
+	organizations := append([]string{}, e.config.Organizations...)
+	sort.Strings(organizations)
+
+	return strings.Join(names, ",") + "/" + strings.Join(organizations, ",")
+}
+
+// collectMetrics runs the collector and returns all the metrics it collected.
+func collectMetrics(c prometheus.Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		c.Collect(ch)
+	}()
+
+	metrics := []prometheus.Metric{}
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
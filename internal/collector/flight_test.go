@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// slowScraper counts its scrapes, taking some time to finish each one.
+type slowScraper struct {
+	fakeScraper
+	scrapes *int32
+}
+
+func (s slowScraper) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	atomic.AddInt32(s.scrapes, 1)
+	time.Sleep(50 * time.Millisecond)
+	return s.fakeScraper.Scrape(ctx, config, ch)
+}
+
+// blockingScraper signals when it starts scraping, and waits to be released or for its context to be done.
+type blockingScraper struct {
+	fakeScraper
+	started  chan struct{}
+	released chan struct{}
+}
+
+func (s blockingScraper) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	s.started <- struct{}{}
+	select {
+	case <-s.released:
+		return s.fakeScraper.Scrape(ctx, config, ch)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// scrapeSucceeded returns whether the metrics report the scraper as successful.
+func scrapeSucceeded(metrics []prometheus.Metric, scraper string) bool {
+	for _, m := range metrics {
+		if got := readMetric(m); m.Desc() == scraperSuccessDesc && got.labels["scraper"] == scraper {
+			return got.value == 1
+		}
+	}
+
+	return false
+}
+
+func TestFlightContext(t *testing.T) {
+	config := setup.Config{
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+		Logger: log.NewNopLogger(),
+	}
+	scraper := blockingScraper{fakeScraper: fakeScraper{name: "blocking"}, started: make(chan struct{}), released: make(chan struct{})}
+	metrics, flight := NewMetrics(), NewFlight()
+
+	convey.Convey("The shared collection outlives the caller that started it, while others wait for it", t, func() {
+		first, cancel := context.WithCancel(context.Background())
+		firstDone := make(chan []prometheus.Metric)
+		go func() {
+			firstDone <- collectMetrics(flight.Collector(New(first, config, []Scraper{scraper}, metrics, nil)))
+		}()
+		<-scraper.started
+
+		secondDone := make(chan []prometheus.Metric)
+		go func() {
+			secondDone <- collectMetrics(flight.Collector(New(context.Background(), config, []Scraper{scraper}, metrics, nil)))
+		}()
+		// The second caller has joined once its exporter waits for the shared collection.
+		for {
+			flight.mu.Lock()
+			waiters := flight.calls["blocking/test-org"].waiters
+			flight.mu.Unlock()
+			if waiters == 2 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		cancel()
+		convey.So(<-firstDone, convey.ShouldBeEmpty)
+		close(scraper.released)
+		convey.So(scrapeSucceeded(<-secondDone, "blocking"), convey.ShouldBeTrue)
+	})
+
+	convey.Convey("The shared collection is cancelled once every caller stops waiting for it", t, func() {
+		scraper.released = make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan []prometheus.Metric)
+		go func() {
+			done <- collectMetrics(flight.Collector(New(ctx, config, []Scraper{scraper}, metrics, nil)))
+		}()
+		<-scraper.started
+
+		cancel()
+		convey.So(<-done, convey.ShouldBeEmpty)
+		for {
+			if status := metrics.Status.Scrapers(); len(status) == 1 && status[0].LastError != "" {
+				convey.So(status[0].LastError, convey.ShouldContainSubstring, context.Canceled.Error())
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+func TestFlight(t *testing.T) {
+	config := setup.Config{
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+		Logger: log.NewNopLogger(),
+	}
+	var scrapes int32
+	scrapers := []Scraper{slowScraper{fakeScraper: fakeScraper{name: "slow"}, scrapes: &scrapes}}
+	metrics, flight := NewMetrics(), NewFlight()
+
+	convey.Convey("Concurrent collections are coalesced", t, func() {
+		var wg sync.WaitGroup
+		results := make([][]prometheus.Metric, 3)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = collectMetrics(flight.Collector(New(context.Background(), config, scrapers, metrics, nil)))
+			}(i)
+		}
+		wg.Wait()
+
+		convey.So(atomic.LoadInt32(&scrapes), convey.ShouldEqual, 1)
+		for _, result := range results {
+			convey.So(result, convey.ShouldResemble, results[0])
+		}
+
+		// Later collections run again.
+		collectMetrics(flight.Collector(New(context.Background(), config, scrapers, metrics, nil)))
+		convey.So(atomic.LoadInt32(&scrapes), convey.ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,178 @@
+package collector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultNamespace is the namespace of the metrics of the exporter, unless renamed with WithNamespace.
+const DefaultNamespace = namespace
+
+// WithNamespace returns a Gatherer renaming the metrics of the exporter to use the given namespace
+// instead of the default one (e.g. tfc_prod_workspaces_info instead of tf_workspaces_info).
+// Metrics outside the namespace, like the Go runtime or client metrics, are left untouched.
+func WithNamespace(g prometheus.Gatherer, ns string) prometheus.Gatherer {
+	if ns == "" || ns == namespace {
+		return g
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, mf := range families {
+			name := RenameNamespace(mf.GetName(), ns)
+			mf.Name = &name
+		}
+
+		return families, err
+	})
+}
+
+// RenameNamespace returns the name of the metric of the exporter using the given namespace.
+func RenameNamespace(name, ns string) string {
+	if ns == "" || !strings.HasPrefix(name, namespace+"_") {
+		return name
+	}
+
+	return ns + strings.TrimPrefix(name, namespace)
+}
+
+// WithConstLabels returns a Gatherer adding the given labels to every metric (e.g. environment=prod),
+// unless the metric already has a label with the same name.
+func WithConstLabels(g prometheus.Gatherer, labels map[string]string) prometheus.Gatherer {
+	if len(labels) == 0 {
+		return g
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, mf := range families {
+			for _, m := range mf.Metric {
+				m.Label = addLabels(m.Label, labels)
+			}
+		}
+
+		return families, err
+	})
+}
+
+func addLabels(pairs []*dto.LabelPair, labels map[string]string) []*dto.LabelPair {
+	existing := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		existing[pair.GetName()] = true
+	}
+
+	for name, value := range labels {
+		if existing[name] {
+			continue
+		}
+		name, value := name, value
+		pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+
+	return pairs
+}
+
+// LabelNormalization normalizes the values of labels of the metrics of the exporter (e.g. the names of the
+// workspaces, with spaces or unicode characters) for the systems downstream that don't cope well with them.
+type LabelNormalization struct {
+	// Labels are the labels whose values are normalized by Disallowed and MaxLength.
+	Labels []string
+	// LowercaseOrganizations lowercases the values of the organization label.
+	LowercaseOrganizations bool
+	// Disallowed matches the characters to replace with Replacement (nil to keep every character).
+	Disallowed  *regexp.Regexp
+	Replacement string
+	// MaxLength trims the values to this number of characters (0 to keep them whole).
+	MaxLength int
+	// Hashed are the labels whose values are replaced by their hash, keyed by HashKey so they can't be
+	// told by hashing the names they could be, e.g. to hide the names of the organizations from a shared monitoring.
+	Hashed  []string
+	HashKey []byte
+}
+
+// Enabled reports whether the normalization changes any label at all.
+func (n LabelNormalization) Enabled() bool {
+	return n.LowercaseOrganizations || len(n.Hashed) > 0 || (len(n.Labels) > 0 && (n.Disallowed != nil || n.MaxLength > 0))
+}
+
+// Normalize returns the normalized value of the label.
+func (n LabelNormalization) Normalize(label, value string) string {
+	if label == "organization" && n.LowercaseOrganizations {
+		value = strings.ToLower(value)
+	}
+	for _, l := range n.Labels {
+		if l != label {
+			continue
+		}
+		if n.Disallowed != nil {
+			value = n.Disallowed.ReplaceAllLiteralString(value, n.Replacement)
+		}
+		if runes := []rune(value); n.MaxLength > 0 && len(runes) > n.MaxLength {
+			value = string(runes[:n.MaxLength])
+		}
+		break
+	}
+	for _, l := range n.Hashed {
+		if l == label {
+			return hashLabelValue(value, n.HashKey)
+		}
+	}
+
+	return value
+}
+
+// hashLabelValue returns a short hash of the value, keyed by the key if any.
+func hashLabelValue(value string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// WithLabelNormalization returns a Gatherer normalizing the values of the labels of the metrics of the exporter,
+// so every scraper exposes them the same way. Metrics outside the namespace are left untouched.
+// The series that end up with the same labels once normalized are merged, keeping the first one.
+func WithLabelNormalization(g prometheus.Gatherer, n LabelNormalization) prometheus.Gatherer {
+	if !n.Enabled() {
+		return g
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, mf := range families {
+			if !strings.HasPrefix(mf.GetName(), namespace+"_") {
+				continue
+			}
+
+			seen := make(map[string]bool, len(mf.Metric))
+			metrics := mf.Metric[:0]
+			for _, m := range mf.Metric {
+				// The pairs are shared with the collected metrics, so they're replaced rather than changed.
+				var key strings.Builder
+				pairs := make([]*dto.LabelPair, 0, len(m.Label))
+				for _, pair := range m.Label {
+					value := n.Normalize(pair.GetName(), pair.GetValue())
+					pairs = append(pairs, &dto.LabelPair{Name: pair.Name, Value: &value})
+					key.WriteString(pair.GetName() + "=" + value + "\xff")
+				}
+				m.Label = pairs
+				if seen[key.String()] {
+					continue
+				}
+				seen[key.String()] = true
+				metrics = append(metrics, m)
+			}
+			mf.Metric = metrics
+		}
+
+		return families, err
+	})
+}
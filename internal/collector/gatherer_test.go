@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithNamespace(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "test_metric", Help: "Test metric"}),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "tfe_other_metric", Help: "Test metric"}),
+	)
+
+	names := func(g prometheus.Gatherer) []string {
+		families, err := g.Gather()
+		convey.So(err, convey.ShouldBeNil)
+		result := []string{}
+		for _, mf := range families {
+			result = append(result, mf.GetName())
+		}
+		return result
+	}
+
+	convey.Convey("Renames the metrics of the exporter", t, func() {
+		convey.So(names(WithNamespace(registry, "tfc_prod")), convey.ShouldResemble, []string{"tfc_prod_test_metric", "tfe_other_metric"})
+	})
+
+	convey.Convey("Keeps the default namespace", t, func() {
+		convey.So(names(WithNamespace(registry, "")), convey.ShouldResemble, []string{"tf_test_metric", "tfe_other_metric"})
+		convey.So(names(WithNamespace(registry, "tf")), convey.ShouldResemble, []string{"tf_test_metric", "tfe_other_metric"})
+	})
+}
+
+func TestWithConstLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_metric", Help: "Test metric"}, []string{"organization"})
+	gauge.WithLabelValues("test-org").Set(1)
+	registry.MustRegister(gauge)
+
+	convey.Convey("Adds the labels to every metric", t, func() {
+		families, err := WithConstLabels(registry, map[string]string{"environment": "prod", "organization": "ignored"}).Gather()
+		convey.So(err, convey.ShouldBeNil)
+
+		labels := labelMap{}
+		for _, pair := range families[0].Metric[0].Label {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		convey.So(labels, convey.ShouldResemble, labelMap{"environment": "prod", "organization": "test-org"})
+	})
+}
+
+func TestWithLabelNormalization(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: "test_metric", Help: "Test metric"}, []string{"organization", "workspace", "status"})
+	gauge.WithLabelValues("Test-Org", "my workspace ñ", "Applied").Set(1)
+	gauge.WithLabelValues("Test-Org", "my_workspace_ñ", "Applied").Set(2)
+	gauge.WithLabelValues("test-org", "a-very-long-workspace-name", "applied").Set(3)
+	other := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "other_metric", Help: "Test metric"}, []string{"organization"})
+	other.WithLabelValues("Test-Org").Set(1)
+	registry.MustRegister(gauge, other)
+
+	normalization := LabelNormalization{
+		Labels:                 []string{"organization", "workspace"},
+		LowercaseOrganizations: true,
+		Disallowed:             regexp.MustCompile(`[^a-zA-Z0-9_.-]`),
+		Replacement:            "_",
+		MaxLength:              12,
+	}
+	series := func(g prometheus.Gatherer) map[string][]labelMap {
+		families, err := g.Gather()
+		convey.So(err, convey.ShouldBeNil)
+		result := map[string][]labelMap{}
+		for _, mf := range families {
+			for _, m := range mf.Metric {
+				labels := labelMap{}
+				for _, pair := range m.Label {
+					labels[pair.GetName()] = pair.GetValue()
+				}
+				result[mf.GetName()] = append(result[mf.GetName()], labels)
+			}
+		}
+		return result
+	}
+
+	convey.Convey("Normalizes the labels of the metrics of the exporter, merging the series ending up the same", t, func() {
+		convey.So(series(WithLabelNormalization(registry, normalization)), convey.ShouldResemble, map[string][]labelMap{
+			"other_metric": {{"organization": "Test-Org"}},
+			"tf_test_metric": {
+				{"organization": "test-org", "workspace": "my_workspace", "status": "Applied"},
+				{"organization": "test-org", "workspace": "a-very-long-", "status": "applied"},
+			},
+		})
+	})
+
+	convey.Convey("Trims the values by characters", t, func() {
+		convey.So(LabelNormalization{Labels: []string{"workspace"}, MaxLength: 3}.Normalize("workspace", "ñañaña"), convey.ShouldEqual, "ñañ")
+	})
+
+	convey.Convey("Hashes the values of the labels, keyed by the key", t, func() {
+		hashed := LabelNormalization{Hashed: []string{"organization", "workspace"}, HashKey: []byte("secret")}
+		convey.So(hashed.Normalize("organization", "test-org"), convey.ShouldHaveLength, 12)
+		convey.So(hashed.Normalize("organization", "test-org"), convey.ShouldEqual, hashed.Normalize("organization", "test-org"))
+		convey.So(hashed.Normalize("organization", "test-org"), convey.ShouldNotEqual, hashed.Normalize("organization", "other-org"))
+		convey.So(hashed.Normalize("id", "ws-123"), convey.ShouldEqual, "ws-123")
+
+		unkeyed := LabelNormalization{Hashed: []string{"organization"}}
+		convey.So(unkeyed.Normalize("organization", "test-org"), convey.ShouldNotEqual, hashed.Normalize("organization", "test-org"))
+
+		metrics := series(WithLabelNormalization(registry, hashed))["tf_test_metric"]
+		convey.So(metrics, convey.ShouldHaveLength, 3)
+		convey.So(metrics[0]["organization"], convey.ShouldEqual, hashed.Normalize("organization", "Test-Org"))
+		convey.So(metrics[0]["status"], convey.ShouldEqual, "Applied")
+	})
+
+	convey.Convey("Keeps the labels when disabled", t, func() {
+		convey.So(LabelNormalization{Labels: []string{"workspace"}}.Enabled(), convey.ShouldBeFalse)
+		convey.So(series(WithLabelNormalization(registry, LabelNormalization{}))["tf_test_metric"], convey.ShouldHaveLength, 3)
+	})
+}
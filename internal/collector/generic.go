@@ -0,0 +1,396 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"gopkg.in/yaml.v2"
+)
+
+// organizationPlaceholder is replaced by the name of every organization in the paths of the generic endpoints.
+const organizationPlaceholder = "{organization}"
+
+// GenericEndpoints declares the generic scrapers, read from --generic-scrapers-file.
+type GenericEndpoints struct {
+	Scrapers []GenericEndpoint `yaml:"scrapers"`
+}
+
+// GenericEndpoint declares a scraper of a JSON:API endpoint the exporter doesn't support yet.
+type GenericEndpoint struct {
+	// Name of the scraper, to select it with --collect.
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	// Path of the endpoint, relative to /api/v2/. Paths with {organization} are scraped for every organization,
+	// the others are site-wide.
+	Path string `yaml:"path"`
+	// Pagination of the endpoint: page (the default) to fetch every page with page[number], or none.
+	Pagination string          `yaml:"pagination"`
+	Metrics    []GenericMetric `yaml:"metrics"`
+}
+
+// GenericMetric declares a metric of every resource of the endpoint, mapping its fields to the value and labels.
+// Fields are referenced by their path in the resource, e.g. id, attributes.name or relationships.project.data.id.
+type GenericMetric struct {
+	// Name of the metric, without the namespace.
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	// Type of the metric: gauge (the default) or counter.
+	Type string `yaml:"type"`
+	// Value is the field holding the value of the metric, a number or a boolean (Omit for 1, e.g. for info metrics).
+	Value string `yaml:"value"`
+	// Labels maps the labels of the metric to the fields holding their values. The id label, with the ID of
+	// the resource, is added unless a label already holds it.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// genericMetric is a declared metric with its descriptor.
+type genericMetric struct {
+	GenericMetric
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	// labels are the names of the declared labels, in the order of the descriptor.
+	labels []string
+}
+
+// genericDocument is a page of the endpoint, decoded as plain JSON: data is either a resource or a list of them.
+type genericDocument struct {
+	Data json.RawMessage `json:"data"`
+	Meta struct {
+		Pagination *tfe.Pagination `json:"pagination"`
+	} `json:"meta"`
+}
+
+// ScrapeGeneric scrapes a JSON:API endpoint as declared in --generic-scrapers-file.
+type ScrapeGeneric struct {
+	endpoint GenericEndpoint
+	metrics  []genericMetric
+}
+
+// scrapeGenericSite scrapes a site-wide endpoint, one without organizations.
+type scrapeGenericSite struct {
+	ScrapeGeneric
+}
+
+func (scrapeGenericSite) siteWide() {}
+
+// RegisterGenericScrapers registers the scrapers declared in the YAML file, so they're run like the builtin ones.
+func RegisterGenericScrapers(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var endpoints GenericEndpoints
+	if err := yaml.UnmarshalStrict(b, &endpoints); err != nil {
+		return err
+	}
+
+	scrapers := []Scraper{}
+	names, metrics := map[string]bool{}, map[string]bool{}
+	for _, endpoint := range endpoints.Scrapers {
+		scraper, err := NewGenericScraper(endpoint)
+		if err != nil {
+			return err
+		}
+		if _, err := FilterScrapers([]string{endpoint.Name}); err == nil || names[endpoint.Name] {
+			return fmt.Errorf("scraper already registered: %s", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+		// Metrics declared twice would fail to be registered.
+		for _, m := range endpoint.Metrics {
+			if metrics[m.Name] {
+				return fmt.Errorf("metric declared twice in the generic scrapers: %s", m.Name)
+			}
+			metrics[m.Name] = true
+		}
+		scrapers = append(scrapers, scraper)
+	}
+
+	for _, scraper := range scrapers {
+		Register(scraper)
+	}
+
+	return nil
+}
+
+// NewGenericScraper returns the scraper of the declared endpoint, site-wide unless its path has {organization}.
+func NewGenericScraper(endpoint GenericEndpoint) (Scraper, error) {
+	if endpoint.Name == "" || endpoint.Path == "" {
+		return nil, fmt.Errorf("generic scraper without name or path: %+v", endpoint)
+	}
+	switch endpoint.Pagination {
+	case "":
+		endpoint.Pagination = "page"
+	case "page", "none":
+	default:
+		return nil, fmt.Errorf("unknown pagination of the generic scraper %s: %s", endpoint.Name, endpoint.Pagination)
+	}
+	if len(endpoint.Metrics) == 0 {
+		return nil, fmt.Errorf("generic scraper without metrics: %s", endpoint.Name)
+	}
+
+	organizations := strings.Contains(endpoint.Path, organizationPlaceholder)
+	s := ScrapeGeneric{endpoint: endpoint}
+	for _, m := range endpoint.Metrics {
+		metric, err := newGenericMetric(endpoint.Name, m, organizations)
+		if err != nil {
+			return nil, err
+		}
+		s.metrics = append(s.metrics, metric)
+	}
+
+	if !organizations {
+		return scrapeGenericSite{s}, nil
+	}
+	return s, nil
+}
+
+func newGenericMetric(scraper string, m GenericMetric, organizations bool) (genericMetric, error) {
+	name := prometheus.BuildFQName(namespace, "", m.Name)
+	if m.Name == "" || !model.IsValidMetricName(model.LabelValue(name)) {
+		return genericMetric{}, fmt.Errorf("invalid metric name of the generic scraper %s: %q", scraper, m.Name)
+	}
+
+	metric := genericMetric{GenericMetric: m}
+	switch m.Type {
+	case "", "gauge":
+		metric.valueType = prometheus.GaugeValue
+	case "counter":
+		metric.valueType = prometheus.CounterValue
+	default:
+		return genericMetric{}, fmt.Errorf("unknown type of the metric %s: %s", name, m.Type)
+	}
+
+	labels := []string{}
+	if organizations {
+		labels = append(labels, "organization")
+	}
+	identified := false
+	for label, field := range m.Labels {
+		if !model.LabelName(label).IsValid() || label == "organization" || (label == "id" && field != "id") {
+			return genericMetric{}, fmt.Errorf("invalid label of the metric %s: %q", name, label)
+		}
+		identified = identified || field == "id"
+		metric.labels = append(metric.labels, label)
+	}
+	// The resources sharing the values of the declared labels are told apart by their ID, as duplicated series
+	// would fail the whole scrape.
+	if !identified {
+		metric.Labels = map[string]string{"id": "id"}
+		for label, field := range m.Labels {
+			metric.Labels[label] = field
+		}
+		metric.labels = append(metric.labels, "id")
+	}
+	sort.Strings(metric.labels)
+
+	help := m.Help
+	if help == "" {
+		help = "Resources of the generic scraper " + scraper
+	}
+	metric.desc = prometheus.NewDesc(name, help, append(labels, metric.labels...), nil)
+
+	return metric, nil
+}
+
+// Name of the Scraper. Should be unique.
+func (s ScrapeGeneric) Name() string {
+	return s.endpoint.Name
+}
+
+// Help describes the role of the Scraper.
+func (s ScrapeGeneric) Help() string {
+	if s.endpoint.Help != "" {
+		return s.endpoint.Help
+	}
+	return "Scrape the resources of " + s.endpoint.Path + ", declared in --generic-scrapers-file"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeGeneric) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (s ScrapeGeneric) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range s.metrics {
+		ch <- m.desc
+	}
+}
+
+// resourceField returns the field of the resource at the dotted path, or nil if it has none.
+func resourceField(resource map[string]interface{}, path string) interface{} {
+	var v interface{} = resource
+	for _, key := range strings.Split(path, ".") {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = object[key]
+	}
+
+	return v
+}
+
+// resourceLabel formats the field as the value of a label, empty if the resource doesn't have it.
+func resourceLabel(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// resourceValue converts the field to the value of a metric, if it's a number, a boolean or a number as a string.
+func resourceValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// send sends the metrics of the resource. Resources without the field of the value of a metric are left out of it.
+func (s ScrapeGeneric) send(ctx context.Context, organization []string, resource map[string]interface{}, ch chan<- prometheus.Metric) error {
+	for _, m := range s.metrics {
+		value := 1.0
+		if m.Value != "" {
+			var ok bool
+			if value, ok = resourceValue(resourceField(resource, m.Value)); !ok {
+				continue
+			}
+		}
+
+		labels := append([]string{}, organization...)
+		for _, label := range m.labels {
+			labels = append(labels, resourceLabel(resourceField(resource, m.Labels[label])))
+		}
+
+		select {
+		case ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, value, labels...):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// getGenericPage fetches a page of the endpoint, sending the metrics of its resources.
+func (s ScrapeGeneric) getGenericPage(ctx context.Context, page int, organization []string, path string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.Pagination, err error) {
+	ctx, span := tracer.Start(ctx, "generic endpoint page", trace.WithAttributes(attribute.String("scraper", s.Name()), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if s.endpoint.Pagination == "page" {
+		query := u.Query()
+		query.Set("page[number]", strconv.Itoa(page))
+		query.Set("page[size]", strconv.Itoa(pageSize))
+		u.RawQuery = query.Encode()
+	}
+
+	var doc genericDocument
+	err = config.Pool.Do(ctx, func(ctx context.Context) error {
+		return config.API.GetJSON(ctx, u.String(), &doc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w, (path=%s, page=%d)", err, path, page)
+	}
+
+	resources := []map[string]interface{}{}
+	if err := json.Unmarshal(doc.Data, &resources); err != nil {
+		var resource map[string]interface{}
+		if err := json.Unmarshal(doc.Data, &resource); err != nil {
+			return nil, fmt.Errorf("unexpected data, (path=%s, page=%d): %w", path, page, err)
+		}
+		resources = append(resources, resource)
+	}
+
+	for _, resource := range resources {
+		if err := s.send(ctx, organization, resource, ch); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc.Meta.Pagination, nil
+}
+
+// getGeneric fetches every page of the endpoint at the path.
+func (s ScrapeGeneric) getGeneric(ctx context.Context, organization []string, path string, config *setup.Config, ch chan<- prometheus.Metric) error {
+	pagination, err := s.getGenericPage(ctx, 1, organization, path, config, ch)
+	if err != nil || s.endpoint.Pagination == "none" || pagination == nil {
+		return err
+	}
+
+	return fetchRemainingPages(ctx, pagination.TotalPages, func(ctx context.Context, page int) error {
+		_, err := s.getGenericPage(ctx, page, organization, path, config, ch)
+		return err
+	})
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (s ScrapeGeneric) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			path := strings.ReplaceAll(s.endpoint.Path, organizationPlaceholder, url.PathEscape(name))
+			return s.getGeneric(ctx, []string{name}, path, config, ch)
+		})
+	}
+
+	return g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (s scrapeGenericSite) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	return s.getGeneric(ctx, nil, s.endpoint.Path, config, ch)
+}
@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeGeneric(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path + "?page=" + r.URL.Query().Get("page[number]") {
+		case "/api/v2/organizations/test-org/agent-pools?page=1":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":2,"total-count":2}},
+				"data":[{"id":"apool-1","type":"agent-pools","attributes":{"name":"pool-1","agent-count":3,"organization-scoped":true}}]
+			}`))
+		case "/api/v2/organizations/test-org/agent-pools?page=2":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":2,"total-pages":2,"total-count":2}},
+				"data":[{"id":"apool-2","type":"agent-pools","attributes":{"name":"pool-2"}}]
+			}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+	}))
+	defer mockAPI.Close()
+
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		API: api,
+		CLI: setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	scraper, err := NewGenericScraper(GenericEndpoint{
+		Name: "agent_pools_generic",
+		Path: "organizations/{organization}/agent-pools",
+		Metrics: []GenericMetric{
+			{Name: "agent_pools_agents", Value: "attributes.agent-count", Labels: map[string]string{"name": "attributes.name"}},
+			{Name: "agent_pools_info", Labels: map[string]string{"id": "id", "scoped": "attributes.organization-scoped"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error creating the generic scraper: %s", err)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = scraper.Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	got := []MetricResult{}
+	for m := range ch {
+		got = append(got, readMetric(m))
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"organization": "test-org", "id": "apool-1", "name": "pool-1"}, value: 3, metricType: dto.MetricType_GAUGE},
+			{labels: labelMap{"organization": "test-org", "id": "apool-1", "scoped": "true"}, value: 1, metricType: dto.MetricType_GAUGE},
+			// The resources without the value of a metric are left out of it.
+			{labels: labelMap{"organization": "test-org", "id": "apool-2", "scoped": ""}, value: 1, metricType: dto.MetricType_GAUGE},
+		})
+	})
+}
+
+func TestNewGenericScraper(t *testing.T) {
+	metrics := []GenericMetric{{Name: "releases_info", Labels: map[string]string{"version": "attributes.version"}}}
+
+	convey.Convey("Endpoints without organizations are site-wide", t, func() {
+		scraper, err := NewGenericScraper(GenericEndpoint{Name: "releases", Path: "admin/release", Pagination: "none", Metrics: metrics})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(scraper, convey.ShouldImplement, (*siteScraper)(nil))
+	})
+
+	convey.Convey("Invalid declarations are rejected", t, func() {
+		for _, endpoint := range []GenericEndpoint{
+			{Path: "admin/release", Metrics: metrics},
+			{Name: "releases", Path: "admin/release"},
+			{Name: "releases", Path: "admin/release", Pagination: "cursor", Metrics: metrics},
+			{Name: "releases", Path: "admin/release", Metrics: []GenericMetric{{Name: "releases-info"}}},
+			{Name: "releases", Path: "admin/release", Metrics: []GenericMetric{{Name: "releases_info", Type: "histogram"}}},
+			{Name: "releases", Path: "{organization}", Metrics: []GenericMetric{{Name: "releases_info", Labels: map[string]string{"organization": "id"}}}},
+			{Name: "releases", Path: "admin/release", Metrics: []GenericMetric{{Name: "releases_info", Labels: map[string]string{"id": "attributes.version"}}}},
+		} {
+			_, err := NewGenericScraper(endpoint)
+			convey.So(err, convey.ShouldNotBeNil)
+		}
+	})
+}
+
+func TestRegisterGenericScrapers(t *testing.T) {
+	registered := Scrapers
+	defer func() { Scrapers = registered }()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing the file: %s", err)
+		}
+		return path
+	}
+
+	convey.Convey("Declared scrapers are registered", t, func() {
+		err := RegisterGenericScrapers(write("valid.yml", `
+scrapers:
+  - name: agent_pools_generic
+    path: organizations/{organization}/agent-pools
+    metrics:
+      - name: agent_pools_agents
+        value: attributes.agent-count
+`))
+		convey.So(err, convey.ShouldBeNil)
+		_, err = FilterScrapers([]string{"agent_pools_generic"})
+		convey.So(err, convey.ShouldBeNil)
+	})
+
+	convey.Convey("Names of the builtin scrapers can't be used", t, func() {
+		err := RegisterGenericScrapers(write("taken.yml", `
+scrapers:
+  - name: workspaces
+    path: organizations/{organization}/workspaces
+    metrics:
+      - name: workspaces_generic
+`))
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+
+	convey.Convey("Scrapers and metrics declared twice are rejected", t, func() {
+		err := RegisterGenericScrapers(write("twice.yml", `
+scrapers:
+  - name: releases
+    path: admin/release
+    metrics:
+      - name: releases_info
+  - name: releases
+    path: admin/releases
+    metrics:
+      - name: releases_count
+`))
+		convey.So(err, convey.ShouldNotBeNil)
+
+		err = RegisterGenericScrapers(write("metrics-twice.yml", `
+scrapers:
+  - name: releases
+    path: admin/release
+    metrics:
+      - name: releases_info
+  - name: releases_latest
+    path: admin/release/latest
+    metrics:
+      - name: releases_info
+`))
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+
+	convey.Convey("Unknown keys are rejected", t, func() {
+		err := RegisterGenericScrapers(write("unknown.yml", `
+scrapers:
+  - name: releases
+    url: admin/release
+`))
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
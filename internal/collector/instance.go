@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric descriptors.
+var (
+	instanceInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "instance", "info"),
+		"Information about the Terraform Cloud/Enterprise instance, as reported by the API.",
+		[]string{"api_version", "app_name", "tfe_version"}, nil,
+	)
+	configInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "config_info"),
+		"Information about the configuration of the exporter, without secrets, and a hash of it to compare replicas.",
+		[]string{"organizations", "scrapers", "page_size", "cache_ttl", "hash"}, nil,
+	)
+	circuitOpenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "circuit_open"),
+		"Whether the requests to the API are being rejected after consecutive failures (1 for open, 0 for closed).",
+		nil, nil,
+	)
+	apiEndpointActiveDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "api_endpoint_active"),
+		"Whether the requests to the API are sent to each of its replicas, when failing over between them (1 for in use, 0 otherwise).",
+		[]string{"address"}, nil,
+	)
+	scrapeTruncatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "scrape_truncated"),
+		"Whether the last scrape stopped once the API request budget was spent, exposing partial results (1 for truncated, 0 otherwise).",
+		nil, nil,
+	)
+)
+
+// collectInstanceInfo sends the info metric of the instance, once the API has answered any request.
+func (e *Exporter) collectInstanceInfo(ch chan<- prometheus.Metric) {
+	if e.config.APIInfo == nil {
+		return
+	}
+
+	apiVersion, appName, tfeVersion := e.config.APIInfo.Get()
+	if apiVersion == "" && appName == "" && tfeVersion == "" {
+		return
+	}
+	// Terraform Cloud doesn't report a release, and older releases don't report the app name.
+	if appName == "" {
+		appName = "na"
+	}
+	if tfeVersion == "" {
+		tfeVersion = "na"
+	}
+
+	ch <- prometheus.MustNewConstMetric(instanceInfoDesc, prometheus.GaugeValue, 1, apiVersion, appName, tfeVersion)
+}
+
+// collectCircuitBreaker sends the state of the circuit breaker, if enabled.
+func (e *Exporter) collectCircuitBreaker(ch chan<- prometheus.Metric) {
+	if e.config.Breaker == nil {
+		return
+	}
+
+	open := 0.0
+	if e.config.Breaker.Open() {
+		open = 1
+	}
+	ch <- prometheus.MustNewConstMetric(circuitOpenDesc, prometheus.GaugeValue, open)
+}
+
+// collectEndpoints sends the replica of the API in use, if failover is enabled.
+func (e *Exporter) collectEndpoints(ch chan<- prometheus.Metric) {
+	active := e.config.Endpoints.Active()
+	for _, address := range e.config.Endpoints.Addresses() {
+		inUse := 0.0
+		if address == active {
+			inUse = 1
+		}
+		ch <- prometheus.MustNewConstMetric(apiEndpointActiveDesc, prometheus.GaugeValue, inUse, address)
+	}
+}
+
+// collectConfigInfo sends the info metric of the configuration: The number of organizations scraped, the scrapers run,
+// the page size and the cache TTLs, besides the hash of the whole configuration.
+func (e *Exporter) collectConfigInfo(ch chan<- prometheus.Metric) {
+	scrapers := make([]string, 0, len(e.scrapers))
+	for _, scraper := range e.scrapers {
+		scrapers = append(scrapers, scraper.Name())
+	}
+	sort.Strings(scrapers)
+
+	ttls := make([]string, 0, len(e.config.CacheTTL))
+	for scraper, ttl := range e.config.CacheTTL {
+		ttls = append(ttls, scraper+"="+ttl.String())
+	}
+	sort.Strings(ttls)
+	cacheTTL := strings.Join(ttls, ";")
+	if cacheTTL == "" {
+		cacheTTL = "na"
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		configInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		strconv.Itoa(len(e.config.Organizations)),
+		strings.Join(scrapers, ","),
+		strconv.Itoa(pageSize),
+		cacheTTL,
+		e.configHash,
+	)
+}
+
+// collectRequestBudget sends whether the scrape was truncated by the request budget, if enabled.
+func (e *Exporter) collectRequestBudget(budget *setup.RequestBudget, ch chan<- prometheus.Metric) {
+	if budget == nil {
+		return
+	}
+
+	truncated := 0.0
+	if budget.Exceeded() {
+		truncated = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeTruncatedDesc, prometheus.GaugeValue, truncated)
+}
@@ -0,0 +1,244 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// inventorySyncMargin is subtracted from the time of the previous listing of the changed workspaces,
+	// so the changes the API timestamps a bit earlier (e.g. clock skew) aren't missed.
+	inventorySyncMargin = time.Minute
+
+	// changesPageSize is the page size of the lists of the changed workspaces, which only fetch their ID and timestamp.
+	changesPageSize = 100
+)
+
+// changesFields are the only fields of the workspaces needed to tell whether they changed.
+var changesFields = setup.Fields{
+	"workspaces": {"latest-change-at"},
+}
+
+// workspacesInventory keeps the workspaces of an organization listed by the previous scrapes, for --workspaces.full-refresh-interval.
+type workspacesInventory struct {
+	mu         sync.Mutex
+	workspaces map[string]*tfe.Workspace
+	// synced is when the changed workspaces were last listed, refreshed when every workspace was.
+	synced    time.Time
+	refreshed time.Time
+}
+
+// changedWorkspace is a workspace listed by its latest change, to tell whether it changed since the previous listing.
+type changedWorkspace struct {
+	ID             string    `jsonapi:"primary,workspaces"`
+	LatestChangeAt time.Time `jsonapi:"attr,latest-change-at,iso8601"`
+}
+
+// workspacesInventories keeps the inventories of every instance, organization, search and options, across the scrapes.
+var workspacesInventories = struct {
+	sync.Mutex
+	m map[string]*workspacesInventory
+}{m: map[string]*workspacesInventory{}}
+
+// inventoryOf returns the inventory of the workspaces of the organization, empty if it wasn't listed yet.
+func inventoryOf(organization string, config *setup.Config) *workspacesInventory {
+	// Workspaces fetched with other includes or fields (e.g. once --run-commit-info is enabled) lack some metrics.
+	include, fields := workspacesOptions(config)
+	key := fmt.Sprintf("%s/%s?%s include=%v fields=%v", config.Instance, organization, config.WorkspacesSearch().Encode(), include, fields)
+
+	workspacesInventories.Lock()
+	defer workspacesInventories.Unlock()
+
+	inv, ok := workspacesInventories.m[key]
+	if !ok {
+		inv = &workspacesInventory{workspaces: map[string]*tfe.Workspace{}}
+		workspacesInventories.m[key] = inv
+	}
+
+	return inv
+}
+
+// scrapeWorkspacesInventory sends the metrics of the workspaces of the organization from its inventory, once the
+// workspaces changed since the previous scrape are fetched and merged into it. Every workspace is listed again once
+// the --workspaces.full-refresh-interval elapses, forgetting the deleted ones (and the ones no longer searched).
+func scrapeWorkspacesInventory(ctx context.Context, organization string, config *setup.Config, ch chan<- prometheus.Metric) error {
+	inv := inventoryOf(organization, config)
+	// Concurrent scrapes of the organization wait for the one updating the inventory.
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	var err error
+	if inv.refreshed.IsZero() || time.Since(inv.refreshed) >= config.WorkspacesFullRefreshInterval {
+		err = inv.refresh(ctx, organization, config)
+	} else {
+		err = inv.sync(ctx, organization, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, w := range inv.workspaces {
+		if err := sendWorkspace(ctx, w, config, ch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refresh lists every workspace of the organization, replacing the inventory. A list truncated by --max-pages is
+// merged into it instead, and listed again on the next scrape.
+func (inv *workspacesInventory) refresh(ctx context.Context, organization string, config *setup.Config) error {
+	start := time.Now()
+	var mu sync.Mutex
+	workspaces := map[string]*tfe.Workspace{}
+	fetch := func(ctx context.Context, page int) (*tfe.WorkspaceList, error) {
+		list, err := listWorkspacesPage(ctx, page, organization, config)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, w := range list.Items {
+			workspaces[w.ID] = w
+		}
+
+		return list, nil
+	}
+
+	list, err := fetch(ctx, 1)
+	if err != nil {
+		return err
+	}
+	err = fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+		_, err := fetch(ctx, page)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if pagesTruncated(ctx) {
+		for id, w := range workspaces {
+			inv.workspaces[id] = w
+		}
+		return nil
+	}
+
+	inv.workspaces = workspaces
+	inv.synced = start
+	inv.refreshed = start
+	return nil
+}
+
+// sync fetches the workspaces of the organization changed since the previous listing, merging them into the inventory.
+// Every workspace is listed by its latest change, only fetching their ID and timestamp, so the ones no longer listed
+// (deleted, or no longer searched) are forgotten as well.
+func (inv *workspacesInventory) sync(ctx context.Context, organization string, config *setup.Config) (err error) {
+	ctx, span := tracer.Start(ctx, "workspaces changes", trace.WithAttributes(attribute.String("organization", organization)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	start := time.Now()
+	since := inv.synced.Add(-inventorySyncMargin)
+	query := config.WorkspacesSearch()
+	query.Set("sort", "-latest-change-at")
+
+	var mu sync.Mutex
+	listed := map[string]bool{}
+	changed := []string{}
+	err = listAllPages(ctx, func(ctx context.Context, page int) (*tfe.Pagination, error) {
+		var workspaces []*changedWorkspace
+		var pagination *tfe.Pagination
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			pagination, err = config.API.List(setup.WithFields(ctx, changesFields), "organizations/"+url.PathEscape(organization)+"/workspaces", tfe.ListOptions{
+				PageSize:   changesPageSize,
+				PageNumber: page,
+			}, query, &workspaces)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, w := range workspaces {
+			listed[w.ID] = true
+			// Workspaces missing from the inventory (e.g. once a list was truncated) are fetched too.
+			if _, ok := inv.workspaces[w.ID]; !ok || !w.LatestChangeAt.Before(since) {
+				changed = append(changed, w.ID)
+			}
+		}
+
+		return pagination, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// A list truncated by --max-pages only holds the latest changes: the ones left are listed again on the next
+	// scrape, from the same timestamp, and the workspaces not listed are kept.
+	if pagesTruncated(ctx) {
+		start = inv.synced
+	} else {
+		for id := range inv.workspaces {
+			if !listed[id] {
+				delete(inv.workspaces, id)
+			}
+		}
+	}
+
+	// The changed workspaces are fetched concurrently, bounded by the Pool.
+	include, fields := workspacesOptions(config)
+	g, gctx := errgroup.WithContext(ctx)
+	for _, id := range changed {
+		id := id
+		g.Go(func() error {
+			var w *tfe.Workspace
+			err := config.Pool.Do(gctx, func(ctx context.Context) (err error) {
+				w, err = config.Client.Workspaces.ReadByIDWithOptions(setup.WithFields(ctx, fields), id, &tfe.WorkspaceReadOptions{
+					Include: include,
+				})
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			// Workspaces deleted since they were listed are forgotten.
+			if errors.Is(err, tfe.ErrResourceNotFound) {
+				delete(inv.workspaces, id)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w, (organization=%s, workspace=%s)", err, organization, id)
+			}
+			inv.workspaces[id] = w
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return err
+	}
+
+	inv.synced = start
+	return nil
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeWorkspacesInventory(t *testing.T) {
+	requests := []string{}
+	// changes are the workspaces listed by their latest change, as ID=timestamp.
+	changes := []string{}
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch {
+		case r.URL.Path == "/api/v2/organizations/inventory-org/workspaces" && r.URL.Query().Get("sort") == "-latest-change-at":
+			requests = append(requests, "changes")
+			data := []string{}
+			for _, change := range changes {
+				id, at, _ := strings.Cut(change, "=")
+				data = append(data, fmt.Sprintf(`{"id":%q,"type":"workspaces","attributes":{"latest-change-at":%q}}`, id, at))
+			}
+			w.Write([]byte(fmt.Sprintf(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":%d}},
+				"data":[%s]
+			}`, len(data), strings.Join(data, ","))))
+		case r.URL.Path == "/api/v2/organizations/inventory-org/workspaces":
+			requests = append(requests, "list")
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"ws-1","type":"workspaces","attributes":{"name":"dev","terraform-version":"1.4.0"},"relationships":{"organization":{"data":{"id":"inventory-org","type":"organizations"}}}},
+					{"id":"ws-2","type":"workspaces","attributes":{"name":"stg","terraform-version":"1.3.0"},"relationships":{"organization":{"data":{"id":"inventory-org","type":"organizations"}}}}
+				]
+			}`))
+		case r.URL.Path == "/api/v2/workspaces/ws-2":
+			requests = append(requests, "ws-2")
+			w.Write([]byte(`{
+				"data":{"id":"ws-2","type":"workspaces","attributes":{"name":"stg","terraform-version":"1.4.0"},"relationships":{"organization":{"data":{"id":"inventory-org","type":"organizations"}}}}
+			}`))
+		case r.URL.Path == "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		API:    api,
+		CLI:    setup.CLI{Organizations: []string{"inventory-org"}, WorkspacesFullRefreshInterval: time.Hour},
+	}
+	versions := func() []string {
+		requests = nil
+		ch := make(chan prometheus.Metric)
+		go func() {
+			defer close(ch)
+			if err := (ScrapeWorkspaces{}).Scrape(context.Background(), config, ch); err != nil {
+				t.Errorf("error calling function on test: %s", err)
+			}
+		}()
+
+		got := []string{}
+		for m := range ch {
+			if m.Desc() == WorkspacesInfo {
+				labels := readMetric(m).labels
+				got = append(got, labels["name"]+"="+labels["terraform_version"])
+			}
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	convey.Convey("The first scrape lists every workspace", t, func() {
+		convey.So(versions(), convey.ShouldResemble, []string{"dev=1.4.0", "stg=1.3.0"})
+		convey.So(requests, convey.ShouldResemble, []string{"list"})
+	})
+
+	convey.Convey("The next scrapes only fetch the workspaces changed, merged with the ones listed before", t, func() {
+		changes = []string{"ws-2=" + time.Now().UTC().Format(time.RFC3339), "ws-1=2020-10-10T10:10:10.101Z"}
+		convey.So(versions(), convey.ShouldResemble, []string{"dev=1.4.0", "stg=1.4.0"})
+		convey.So(requests, convey.ShouldResemble, []string{"changes", "ws-2"})
+	})
+
+	convey.Convey("The workspaces no longer listed are forgotten", t, func() {
+		changes = []string{"ws-2=2020-10-10T10:10:10.101Z"}
+		convey.So(versions(), convey.ShouldResemble, []string{"stg=1.4.0"})
+		convey.So(requests, convey.ShouldResemble, []string{"changes"})
+	})
+
+	convey.Convey("Enabling more metrics lists every workspace again", t, func() {
+		config.RunCommitInfo = true
+		convey.So(versions(), convey.ShouldResemble, []string{"dev=1.4.0", "stg=1.3.0"})
+		convey.So(requests, convey.ShouldResemble, []string{"list"})
+	})
+}
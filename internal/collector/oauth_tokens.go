@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// oauth_tokens is the Metric subsystem we use.
+	oauthTokensSubsystem = "oauth_tokens"
+)
+
+// Metric descriptors.
+var (
+	OAuthTokensInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, oauthTokensSubsystem, "info"),
+		"Information about the OAuth tokens of the VCS providers connected to the organization",
+		[]string{"organization", "id", "service_provider", "service_provider_user", "created_at", "has_ssh_key"}, nil,
+	)
+)
+
+// oauthClientsFields are the only fields of the OAuth clients needed to know the service provider of their tokens.
+var oauthClientsFields = setup.Fields{"oauth-clients": {"service-provider"}}
+
+// ScrapeOAuthTokens scrapes metrics about the OAuth tokens of the VCS providers.
+type ScrapeOAuthTokens struct{}
+
+func init() {
+	Register(ScrapeOAuthTokens{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeOAuthTokens) Name() string {
+	return oauthTokensSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeOAuthTokens) Help() string {
+	return "Scrape information from the OAuth Tokens API: https://www.terraform.io/cloud-docs/api-docs/oauth-tokens"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeOAuthTokens) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeOAuthTokens) Describe(ch chan<- *prometheus.Desc) {
+	ch <- OAuthTokensInfo
+}
+
+// getServiceProviders returns the service providers of the OAuth clients of the organization, by their ID.
+// Organizations only connect a few VCS providers, so a single page is enough.
+func getServiceProviders(ctx context.Context, organization string, config *setup.Config) (map[string]tfe.ServiceProviderType, error) {
+	var clientsList *tfe.OAuthClientList
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		clientsList, err = config.Client.OAuthClients.List(setup.WithFields(ctx, oauthClientsFields), organization, &tfe.OAuthClientListOptions{
+			ListOptions: tfe.ListOptions{PageSize: pageSize},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w, (organization=%s)", err, organization)
+	}
+
+	providers := make(map[string]tfe.ServiceProviderType, len(clientsList.Items))
+	for _, c := range clientsList.Items {
+		providers[c.ID] = c.ServiceProvider
+	}
+
+	return providers, nil
+}
+
+func getOAuthTokensPage(ctx context.Context, page int, organization string, providers map[string]tfe.ServiceProviderType, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.OAuthTokenList, err error) {
+	ctx, span := tracer.Start(ctx, "oauth tokens page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var tokensList *tfe.OAuthTokenList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		tokensList, err = config.Client.OAuthTokens.List(ctx, organization, &tfe.OAuthTokenListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return tokensList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	for _, t := range tokensList.Items {
+		var provider tfe.ServiceProviderType
+		if t.OAuthClient != nil {
+			provider = providers[t.OAuthClient.ID]
+		}
+
+		select {
+		case ch <- prometheus.MustNewConstMetric(
+			OAuthTokensInfo,
+			prometheus.GaugeValue,
+			1,
+			organization,
+			t.ID,
+			string(provider),
+			t.ServiceProviderUser,
+			t.CreatedAt.String(),
+			strconv.FormatBool(t.HasSSHKey),
+		):
+		case <-ctx.Done():
+			return tokensList, ctx.Err()
+		}
+	}
+
+	return tokensList, nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeOAuthTokens) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			providers, err := getServiceProviders(ctx, name, config)
+			if err != nil {
+				return err
+			}
+
+			list, err := getOAuthTokensPage(ctx, 1, name, providers, config, ch)
+			if err != nil {
+				return err
+			}
+
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getOAuthTokensPage(ctx, page, name, providers, config, ch)
+				return err
+			})
+		})
+	}
+
+	return g.Wait()
+}
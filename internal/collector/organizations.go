@@ -9,7 +9,12 @@ import (
 
 	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
 
+	tfe "github.com/hashicorp/go-tfe"
+
 	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -24,13 +29,18 @@ var (
 		"Information about existing organizations",
 		[]string{"name", "created_at", "email", "external_id", "owners_team_saml_role_id", "saml_enabled", "two_factor_conformant"}, nil,
 	)
+	OrganizationsTeamsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, organizationsSubsystem, "teams_count"),
+		"Number of teams of the organization",
+		[]string{"name"}, nil,
+	)
 )
 
 // ScrapeOrganizations scrapes metrics about the organizations.
 type ScrapeOrganizations struct{}
 
 func init() {
-	Scrapers = append(Scrapers, ScrapeOrganizations{})
+	Register(ScrapeOrganizations{})
 }
 
 // Name of the Scraper. Should be unique.
@@ -48,10 +58,50 @@ func (ScrapeOrganizations) Version() string {
 	return "v2"
 }
 
-func getOrganization(ctx context.Context, name string, config *setup.Config, ch chan<- prometheus.Metric) error {
-	o, err := config.Client.Organizations.Read(ctx, name)
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeOrganizations) Describe(ch chan<- *prometheus.Desc) {
+	ch <- OrganizationsInfo
+	ch <- OrganizationsTeamsCount
+}
+
+// getTeamsCount returns the number of teams of the organization, from the pagination of a single team.
+func getTeamsCount(ctx context.Context, name string, config *setup.Config) (int, error) {
+	var teamsList *tfe.TeamList
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		teamsList, err = config.Client.Teams.List(ctx, name, &tfe.TeamListOptions{
+			ListOptions: tfe.ListOptions{PageSize: 1},
+		})
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w, organization=%s", err, name)
+	}
+	if teamsList.Pagination == nil {
+		return len(teamsList.Items), nil
+	}
+
+	return teamsList.Pagination.TotalCount, nil
+}
+
+func getOrganization(ctx context.Context, name string, config *setup.Config, ch chan<- prometheus.Metric) (err error) {
+	ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var o *tfe.Organization
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		o, err = config.Client.Organizations.Read(ctx, name)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w, organization=%s", err, name)
+	}
+
+	teams, err := getTeamsCount(ctx, name, config)
 	if err != nil {
-		return fmt.Errorf("%v, organization=%s", err, name)
+		return err
 	}
 
 	select {
@@ -71,12 +121,19 @@ func getOrganization(ctx context.Context, name string, config *setup.Config, ch
 		return ctx.Err()
 	}
 
+	select {
+	case ch <- prometheus.MustNewConstMetric(OrganizationsTeamsCount, prometheus.GaugeValue, float64(teams), o.Name):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
 	return nil
 }
 
 // Scrape collects data from Terraform API and sends it over channel as prometheus metric.
 func (ScrapeOrganizations) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
-	g, ctx := errgroup.WithContext(ctx)
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
 	for _, name := range config.Organizations {
 		name := name
 		g.Go(func() error {
@@ -19,6 +19,14 @@ import (
 func TestScrapeOrganizations(t *testing.T) {
 	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/teams":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":3,"total-count":3}},
+				"data":[{"id":"team-1","type":"teams","attributes":{"name":"owners"}}]
+			}`))
+			return
+		}
 		w.Write([]byte(`{
 			"data": {
 				"id":"test-org",
@@ -59,6 +67,7 @@ func TestScrapeOrganizations(t *testing.T) {
 
 	counterExpected := []MetricResult{
 		{labels: labelMap{"created_at": "1010-10-10 10:10:10.101 +0000 UTC", "email": "test-email", "external_id": "test-external-id", "name": "test-org", "owners_team_saml_role_id": "test-role-id", "saml_enabled": "true", "two_factor_conformant": "false"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "test-org"}, value: 3, metricType: dto.MetricType_GAUGE},
 	}
 	convey.Convey("Metrics comparison", t, func() {
 		for _, expect := range counterExpected {
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// pageLimitKey is the context key of the pageLimit.
+type pageLimitKey struct{}
+
+// pageLimit caps the number of pages fetched of every list, so a scraper can't walk an unexpectedly long one.
+type pageLimit struct {
+	max       int
+	truncated int32
+}
+
+// withPageLimit returns a copy of ctx whose lists stop after max pages, and the limit to check whether any did.
+// The limit is nil, and the lists complete, if max <= 0.
+func withPageLimit(ctx context.Context, max int) (context.Context, *pageLimit) {
+	if max <= 0 {
+		return ctx, nil
+	}
+
+	limit := &pageLimit{max: max}
+	return context.WithValue(ctx, pageLimitKey{}, limit), limit
+}
+
+func (l *pageLimit) truncate() {
+	atomic.StoreInt32(&l.truncated, 1)
+}
+
+// Truncated reports whether any list stopped before its last page.
+func (l *pageLimit) Truncated() bool {
+	return l != nil && atomic.LoadInt32(&l.truncated) == 1
+}
+
+// pagesTruncated reports whether any list stopped before its last page, by the limit of the context.
+func pagesTruncated(ctx context.Context) bool {
+	limit, _ := ctx.Value(pageLimitKey{}).(*pageLimit)
+	return limit.Truncated()
+}
+
+// pageLimitReached reports whether a list with more pages should stop after the given one, for the lists
+// fetched page by page. It's only meant to be called when there are more pages.
+func pageLimitReached(ctx context.Context, page int) bool {
+	limit, ok := ctx.Value(pageLimitKey{}).(*pageLimit)
+	if !ok || page < limit.max {
+		return false
+	}
+
+	limit.truncate()
+	return true
+}
+
+// fetchRemainingPages calls fetch concurrently for the pages 2 to totalPages, once the first page
+// has been fetched and the total number of pages is known. The pages over the limit of the context aren't fetched.
+// The concurrent requests are bounded by the Pool of the Config, so fetch is expected to use it.
+// The first error cancels the fetch of the remaining pages.
+func fetchRemainingPages(ctx context.Context, totalPages int, fetch func(ctx context.Context, page int) error) error {
+	if limit, ok := ctx.Value(pageLimitKey{}).(*pageLimit); ok && totalPages > limit.max {
+		limit.truncate()
+		totalPages = limit.max
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for page := 2; page <= totalPages && ctx.Err() == nil; page++ {
+		page := page
+		g.Go(func() error {
+			return fetch(ctx, page)
+		})
+	}
+
+	return g.Wait()
+}
+
+// listAllPages fetches the first page, and then the remaining ones concurrently with fetchRemainingPages.
+func listAllPages(ctx context.Context, fetch func(ctx context.Context, page int) (*tfe.Pagination, error)) error {
+	pagination, err := fetch(ctx, 1)
+	if err != nil || pagination == nil {
+		return err
+	}
+
+	return fetchRemainingPages(ctx, pagination.TotalPages, func(ctx context.Context, page int) error {
+		_, err := fetch(ctx, page)
+		return err
+	})
+}
@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestFetchRemainingPages(t *testing.T) {
+	convey.Convey("Fetches the remaining pages concurrently", t, func() {
+		var (
+			mu    sync.Mutex
+			pages []int
+		)
+		err := fetchRemainingPages(context.Background(), 10, func(ctx context.Context, page int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			pages = append(pages, page)
+			return nil
+		})
+
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(pages, convey.ShouldHaveLength, 9)
+		convey.So(pages, convey.ShouldNotContain, 1)
+	})
+
+	convey.Convey("Nothing to fetch with a single page", t, func() {
+		err := fetchRemainingPages(context.Background(), 1, func(ctx context.Context, page int) error {
+			return errors.New("unexpected fetch")
+		})
+		convey.So(err, convey.ShouldBeNil)
+	})
+
+	convey.Convey("Returns the first error", t, func() {
+		err := fetchRemainingPages(context.Background(), 5, func(ctx context.Context, page int) error {
+			if page == 3 {
+				return errors.New("test error")
+			}
+			return nil
+		})
+		convey.So(err, convey.ShouldBeError, "test error")
+	})
+	convey.Convey("Stops at the page limit", t, func() {
+		ctx, limit := withPageLimit(context.Background(), 3)
+		var fetched int32
+		err := fetchRemainingPages(ctx, 10, func(ctx context.Context, page int) error {
+			atomic.AddInt32(&fetched, 1)
+			return nil
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(fetched, convey.ShouldEqual, 2)
+		convey.So(limit.Truncated(), convey.ShouldBeTrue)
+		convey.So(pagesTruncated(ctx), convey.ShouldBeTrue)
+	})
+
+	convey.Convey("Lists under the page limit aren't truncated", t, func() {
+		ctx, limit := withPageLimit(context.Background(), 3)
+		err := fetchRemainingPages(ctx, 3, func(ctx context.Context, page int) error { return nil })
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(limit.Truncated(), convey.ShouldBeFalse)
+	})
+}
+
+func TestPageLimitReached(t *testing.T) {
+	convey.Convey("Without a limit", t, func() {
+		convey.So(pageLimitReached(context.Background(), 100), convey.ShouldBeFalse)
+		convey.So(pagesTruncated(context.Background()), convey.ShouldBeFalse)
+	})
+
+	convey.Convey("With a limit", t, func() {
+		ctx, limit := withPageLimit(context.Background(), 2)
+		convey.So(pageLimitReached(ctx, 1), convey.ShouldBeFalse)
+		convey.So(limit.Truncated(), convey.ShouldBeFalse)
+		convey.So(pageLimitReached(ctx, 2), convey.ShouldBeTrue)
+		convey.So(limit.Truncated(), convey.ShouldBeTrue)
+	})
+}
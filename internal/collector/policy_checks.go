@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// policy_checks is the Metric subsystem we use.
+	policyChecksSubsystem = "policy_checks"
+)
+
+// Metric descriptors.
+var (
+	PolicyChecksOverrides = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, policyChecksSubsystem, "overrides_count"),
+		"Number of policy checks overridden, of the runs created within the lookback window",
+		[]string{"organization"}, nil,
+	)
+	PolicyChecksOverriddenSoftFailures = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, policyChecksSubsystem, "overridden_soft_failures_count"),
+		"Number of soft-mandatory policy failures overridden, of the runs created within the lookback window",
+		[]string{"organization"}, nil,
+	)
+	PolicyChecksFailures = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, policyChecksSubsystem, "failures_count"),
+		"Number of policy failures, of the runs created within the lookback window, by enforcement level (advisory, soft-mandatory or hard-mandatory)",
+		[]string{"organization", "enforcement_level"}, nil,
+	)
+)
+
+var (
+	// policyChecksRunsFields are the only fields of the runs needed to find their policy checks.
+	policyChecksRunsFields = setup.Fields{"runs": {"created-at", "policy-checks"}}
+	// policyChecksFields are the only fields of the policy checks aggregated.
+	policyChecksFields = setup.Fields{"policy-checks": {"status", "result"}}
+)
+
+// ScrapePolicyChecks scrapes the policy checks of the recent runs, aggregated per organization.
+type ScrapePolicyChecks struct{}
+
+func init() {
+	Register(ScrapePolicyChecks{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapePolicyChecks) Name() string {
+	return policyChecksSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapePolicyChecks) Help() string {
+	return "Scrape the policy checks of the recent runs from the Policy Checks API: https://www.terraform.io/cloud-docs/api-docs/policy-checks"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapePolicyChecks) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapePolicyChecks) Describe(ch chan<- *prometheus.Desc) {
+	ch <- PolicyChecksOverrides
+	ch <- PolicyChecksOverriddenSoftFailures
+	ch <- PolicyChecksFailures
+}
+
+// policyCheckStats aggregates the policy checks of an organization.
+type policyCheckStats struct {
+	mu                     sync.Mutex
+	overrides              int
+	overriddenSoftFailures int
+	// failures are the policy failures by enforcement level.
+	failures map[tfe.EnforcementLevel]int
+}
+
+func newPolicyCheckStats() *policyCheckStats {
+	return &policyCheckStats{failures: map[tfe.EnforcementLevel]int{}}
+}
+
+func (s *policyCheckStats) add(pc *tfe.PolicyCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pc.Result != nil {
+		s.failures[tfe.EnforcementAdvisory] += pc.Result.AdvisoryFailed
+		s.failures[tfe.EnforcementSoft] += pc.Result.SoftFailed
+		s.failures[tfe.EnforcementHard] += pc.Result.HardFailed
+	}
+
+	if pc.Status == tfe.PolicyOverridden {
+		s.overrides++
+		if pc.Result != nil {
+			s.overriddenSoftFailures += pc.Result.SoftFailed
+		}
+	}
+}
+
+func (s *policyCheckStats) collect(ctx context.Context, organization string, ch chan<- prometheus.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(PolicyChecksOverrides, prometheus.GaugeValue, float64(s.overrides), organization),
+		prometheus.MustNewConstMetric(PolicyChecksOverriddenSoftFailures, prometheus.GaugeValue, float64(s.overriddenSoftFailures), organization),
+	}
+	// Every level is sent, even without failures, so they can be compared.
+	for _, level := range []tfe.EnforcementLevel{tfe.EnforcementAdvisory, tfe.EnforcementSoft, tfe.EnforcementHard} {
+		metrics = append(metrics, prometheus.MustNewConstMetric(PolicyChecksFailures, prometheus.GaugeValue, float64(s.failures[level]), organization, string(level)))
+	}
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// getRunPolicyChecks adds the policy checks of the run to the stats.
+// Runs have a single policy check, so only the first page is fetched.
+func getRunPolicyChecks(ctx context.Context, runID string, config *setup.Config, stats *policyCheckStats) error {
+	var list *tfe.PolicyCheckList
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		list, err = config.Client.PolicyChecks.List(setup.WithFields(ctx, policyChecksFields), runID, &tfe.PolicyCheckListOptions{
+			ListOptions: tfe.ListOptions{PageSize: pageSize},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w, (run=%s)", err, runID)
+	}
+
+	for _, pc := range list.Items {
+		stats.add(pc)
+	}
+
+	return nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapePolicyChecks) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	since := time.Now().Add(-config.RunsLookback)
+
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			stats := newPolicyCheckStats()
+			err = visitRecentRuns(ctx, name, since, policyChecksRunsFields, config, func(ctx context.Context, _ *tfe.Workspace, r *tfe.Run) error {
+				// Only the runs of workspaces with policy sets have policy checks.
+				if len(r.PolicyChecks) == 0 {
+					return nil
+				}
+				return getRunPolicyChecks(ctx, r.ID, config, stats)
+			})
+			if err != nil {
+				return err
+			}
+
+			return stats.collect(ctx, name, ch)
+		})
+	}
+
+	return g.Wait()
+}
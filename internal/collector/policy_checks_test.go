@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapePolicyChecks(t *testing.T) {
+	createdAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"ws-1","type":"workspaces","attributes":{"name":"dev"}}]
+			}`))
+		case "/api/v2/workspaces/ws-1/runs":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"run-1","type":"runs","attributes":{"created-at":"` + createdAt + `"},
+					 "relationships":{"policy-checks":{"data":[{"id":"polchk-1","type":"policy-checks"}]}}},
+					{"id":"run-2","type":"runs","attributes":{"created-at":"` + createdAt + `"},
+					 "relationships":{"policy-checks":{"data":[]}}}
+				]
+			}`))
+		case "/api/v2/runs/run-1/policy-checks":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"polchk-1","type":"policy-checks","attributes":{
+					"status":"overridden",
+					"result":{"passed":1,"total-failed":2,"hard-failed":0,"soft-failed":2,"advisory-failed":0}
+				}}]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}, RunsLookback: 24 * time.Hour},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapePolicyChecks{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "enforcement_level": "advisory"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "enforcement_level": "soft-mandatory"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "enforcement_level": "hard-mandatory"}, value: 0, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+	})
+}
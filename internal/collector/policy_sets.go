@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// policy_sets is the Metric subsystem we use.
+	policySetsSubsystem = "policy_sets"
+)
+
+// Metric descriptors.
+var (
+	PolicySetsWorkspaceCoverage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, policySetsSubsystem, "workspace_coverage_ratio"),
+		"Ratio of the workspaces of the organization the policy set applies to, 1 for global policy sets",
+		[]string{"organization", "policy_set", "global"}, nil,
+	)
+)
+
+var (
+	// policySetsFields are the only fields of the policy sets needed for their coverage.
+	policySetsFields = setup.Fields{"policy-sets": {"name", "global", "workspace-count"}}
+	// policySetsWorkspacesFields are the fields of the workspaces counted, only their number is needed.
+	policySetsWorkspacesFields = setup.Fields{"workspaces": {"name"}}
+)
+
+// ScrapePolicySets scrapes the coverage of the policy sets.
+type ScrapePolicySets struct{}
+
+func init() {
+	Register(ScrapePolicySets{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapePolicySets) Name() string {
+	return policySetsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapePolicySets) Help() string {
+	return "Scrape the workspaces the policy sets apply to from the Policy Sets API: https://www.terraform.io/cloud-docs/api-docs/policy-sets"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapePolicySets) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapePolicySets) Describe(ch chan<- *prometheus.Desc) {
+	ch <- PolicySetsWorkspaceCoverage
+}
+
+// getWorkspacesCount returns the number of workspaces of the organization, from the pagination of a single workspace.
+func getWorkspacesCount(ctx context.Context, organization string, config *setup.Config) (int, error) {
+	var workspacesList *tfe.WorkspaceList
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(ctx, policySetsWorkspacesFields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{PageSize: 1},
+		})
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w, (organization=%s)", err, organization)
+	}
+	if workspacesList.Pagination == nil {
+		return len(workspacesList.Items), nil
+	}
+
+	return workspacesList.Pagination.TotalCount, nil
+}
+
+func getPolicySetsPage(ctx context.Context, page int, organization string, workspaces int, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.PolicySetList, err error) {
+	ctx, span := tracer.Start(ctx, "policy sets page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var policySetsList *tfe.PolicySetList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		policySetsList, err = config.Client.PolicySets.List(setup.WithFields(ctx, policySetsFields), organization, &tfe.PolicySetListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return policySetsList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	for _, ps := range policySetsList.Items {
+		coverage := 1.0
+		if !ps.Global && workspaces > 0 {
+			coverage = float64(ps.WorkspaceCount) / float64(workspaces)
+		}
+
+		select {
+		case ch <- prometheus.MustNewConstMetric(PolicySetsWorkspaceCoverage, prometheus.GaugeValue, coverage, organization, ps.Name, strconv.FormatBool(ps.Global)):
+		case <-ctx.Done():
+			return policySetsList, ctx.Err()
+		}
+	}
+
+	return policySetsList, nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapePolicySets) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			workspaces, err := getWorkspacesCount(ctx, name, config)
+			if err != nil {
+				return err
+			}
+
+			list, err := getPolicySetsPage(ctx, 1, name, workspaces, config, ch)
+			if err != nil {
+				return err
+			}
+
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getPolicySetsPage(ctx, page, name, workspaces, config, ch)
+				return err
+			})
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,213 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// projects is the Metric subsystem we use.
+	projectsSubsystem = "projects"
+)
+
+// Metric descriptors.
+var (
+	ProjectsWorkspacesCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, projectsSubsystem, "workspaces_count"),
+		"Number of workspaces of the project",
+		[]string{"organization", "project"}, nil,
+	)
+)
+
+var (
+	// projectsFields are the only fields of the projects needed.
+	projectsFields = setup.Fields{"projects": {"name"}}
+	// projectsWorkspacesFields are the only fields of the workspaces needed to count them per project.
+	projectsWorkspacesFields = setup.Fields{"workspaces": {"project"}}
+)
+
+// project is a project of an organization, which the go-tfe client doesn't support.
+type project struct {
+	ID   string `jsonapi:"primary,projects"`
+	Name string `jsonapi:"attr,name"`
+}
+
+// projectWorkspace is a workspace with its project, which the go-tfe client doesn't support.
+type projectWorkspace struct {
+	ID      string   `jsonapi:"primary,workspaces"`
+	Project *project `jsonapi:"relation,project"`
+}
+
+// ScrapeProjects scrapes metrics about the projects of the organizations.
+type ScrapeProjects struct{}
+
+func init() {
+	Register(ScrapeProjects{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeProjects) Name() string {
+	return projectsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeProjects) Help() string {
+	return "Scrape the projects and their workspaces from the Projects API: https://www.terraform.io/cloud-docs/api-docs/projects"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeProjects) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeProjects) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ProjectsWorkspacesCount
+}
+
+// projectStats counts the workspaces of the projects of an organization.
+type projectStats struct {
+	mu sync.Mutex
+	// names are the names of the projects by their ID.
+	names      map[string]string
+	workspaces map[string]int
+}
+
+func newProjectStats() *projectStats {
+	return &projectStats{names: map[string]string{}, workspaces: map[string]int{}}
+}
+
+func (s *projectStats) collect(ctx context.Context, organization string, ch chan<- prometheus.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.names))
+	for id := range s.names {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		select {
+		case ch <- prometheus.MustNewConstMetric(ProjectsWorkspacesCount, prometheus.GaugeValue, float64(s.workspaces[id]), organization, s.names[id]):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getProjectsPage(ctx context.Context, page int, organization string, config *setup.Config, stats *projectStats) (_ *tfe.Pagination, err error) {
+	ctx, span := tracer.Start(ctx, "projects page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var (
+		projects   []*project
+		pagination *tfe.Pagination
+	)
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		pagination, err = config.API.List(setup.WithFields(ctx, projectsFields), "organizations/"+organization+"/projects", tfe.ListOptions{
+			PageSize:   pageSize,
+			PageNumber: page,
+		}, nil, &projects)
+		return err
+	})
+	if err != nil {
+		return pagination, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	for _, p := range projects {
+		stats.names[p.ID] = p.Name
+	}
+
+	return pagination, nil
+}
+
+func getProjectWorkspacesPage(ctx context.Context, page int, organization string, config *setup.Config, stats *projectStats) (_ *tfe.Pagination, err error) {
+	ctx, span := tracer.Start(ctx, "projects workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var (
+		workspaces []*projectWorkspace
+		pagination *tfe.Pagination
+	)
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		pagination, err = config.API.List(setup.WithFields(ctx, projectsWorkspacesFields), "organizations/"+organization+"/workspaces", tfe.ListOptions{
+			PageSize:   pageSize,
+			PageNumber: page,
+		}, config.WorkspacesSearch(), &workspaces)
+		return err
+	})
+	if err != nil {
+		return pagination, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	for _, w := range workspaces {
+		if w.Project != nil {
+			stats.workspaces[w.Project.ID]++
+		}
+	}
+
+	return pagination, nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeProjects) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			stats := newProjectStats()
+			// The context of the group is cancelled once it's done, so it isn't used to send the metrics.
+			lg, lctx := errgroup.WithContext(ctx)
+			lg.Go(func() error {
+				return listAllPages(lctx, func(ctx context.Context, page int) (*tfe.Pagination, error) {
+					return getProjectsPage(ctx, page, name, config, stats)
+				})
+			})
+			lg.Go(func() error {
+				return listAllPages(lctx, func(ctx context.Context, page int) (*tfe.Pagination, error) {
+					return getProjectWorkspacesPage(ctx, page, name, config, stats)
+				})
+			})
+			if err := lg.Wait(); err != nil {
+				return err
+			}
+
+			return stats.collect(ctx, name, ch)
+		})
+	}
+
+	return g.Wait()
+}
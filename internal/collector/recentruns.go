@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recentRunsWorkspacesFields are the only fields of the workspaces needed to list their runs.
+var recentRunsWorkspacesFields = setup.Fields{"workspaces": {"name"}}
+
+// runVisitor is called for every recent run with its workspace, concurrently.
+type runVisitor func(ctx context.Context, w *tfe.Workspace, r *tfe.Run) error
+
+// workspaceVisitor is called for every workspace, concurrently.
+type workspaceVisitor func(ctx context.Context, w *tfe.Workspace) error
+
+// visitWorkspaceRuns calls visit for the runs of the workspace created since the given time.
+// Runs are listed from the newest, so the pages stop being fetched at the first older run.
+func visitWorkspaceRuns(ctx context.Context, w *tfe.Workspace, since time.Time, fields setup.Fields, config *setup.Config, visit runVisitor) error {
+	for page := 1; ; page++ {
+		var runsList *tfe.RunList
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			runsList, err = config.Client.Runs.List(setup.WithFields(ctx, fields), w.ID, &tfe.RunListOptions{
+				ListOptions: tfe.ListOptions{
+					PageSize:   pageSize,
+					PageNumber: page,
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%w, (workspace=%s, page=%d)", err, w.ID, page)
+		}
+
+		for _, r := range runsList.Items {
+			if r.CreatedAt.Before(since) {
+				return nil
+			}
+			if err := visit(ctx, w, r); err != nil {
+				return err
+			}
+		}
+
+		if runsList.Pagination == nil || page >= runsList.Pagination.TotalPages || pageLimitReached(ctx, page) {
+			return nil
+		}
+	}
+}
+
+func visitWorkspacesPage(ctx context.Context, page int, organization string, config *setup.Config, visit workspaceVisitor) (_ *tfe.WorkspaceList, err error) {
+	ctx, span := tracer.Start(ctx, "runs workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var workspacesList *tfe.WorkspaceList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(setup.WithQuery(ctx, config.WorkspacesSearch()), recentRunsWorkspacesFields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return workspacesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range workspacesList.Items {
+		w := w
+		g.Go(func() error {
+			return visit(ctx, w)
+		})
+	}
+
+	return workspacesList, g.Wait()
+}
+
+// visitWorkspaces calls visit for every workspace of the organization, listing only their names.
+func visitWorkspaces(ctx context.Context, organization string, config *setup.Config, visit workspaceVisitor) error {
+	list, err := visitWorkspacesPage(ctx, 1, organization, config, visit)
+	if err != nil {
+		return err
+	}
+
+	return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+		_, err := visitWorkspacesPage(ctx, page, organization, config, visit)
+		return err
+	})
+}
+
+// visitRecentRuns calls visit for every run of the organization created since the given time,
+// listing the runs of every workspace with the given sparse fieldsets.
+func visitRecentRuns(ctx context.Context, organization string, since time.Time, fields setup.Fields, config *setup.Config, visit runVisitor) error {
+	return visitWorkspaces(ctx, organization, config, func(ctx context.Context, w *tfe.Workspace) error {
+		return visitWorkspaceRuns(ctx, w, since, fields, config, visit)
+	})
+}
@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// registry_modules is the Metric subsystem we use.
+	registryModulesSubsystem = "registry_modules"
+)
+
+// Metric descriptors.
+var (
+	RegistryModulesPublishedVersions = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, registryModulesSubsystem, "published_versions"),
+		"Number of versions of the module published successfully",
+		[]string{"organization", "name", "provider"}, nil,
+	)
+	RegistryModulesStatus = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, registryModulesSubsystem, "status"),
+		"Status of the module setup (pending, no_version_tags, setup_failed or setup_complete), always 1",
+		[]string{"organization", "name", "provider", "status"}, nil,
+	)
+	RegistryModulesFailedVersions = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, registryModulesSubsystem, "failed_versions"),
+		"Number of versions of the module that failed to be published (clone_failed, reg_ingress_req_failed or reg_ingress_failed)",
+		[]string{"organization", "name", "provider"}, nil,
+	)
+	RegistryModulesLatestVersionPublished = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, registryModulesSubsystem, "latest_version_published_timestamp_seconds"),
+		"Unix timestamp of the publication of the latest version of the module",
+		[]string{"organization", "name", "provider", "version"}, nil,
+	)
+)
+
+// registryModuleVersion is the latest version of a module, as returned by the module registry protocol.
+type registryModuleVersion struct {
+	Version     string    `json:"version"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ScrapeRegistryModules scrapes metrics about the modules of the private registry.
+type ScrapeRegistryModules struct{}
+
+func init() {
+	Register(ScrapeRegistryModules{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeRegistryModules) Name() string {
+	return registryModulesSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeRegistryModules) Help() string {
+	return "Scrape the modules of the private registry from the Registry Modules API: https://www.terraform.io/cloud-docs/api-docs/private-registry/modules"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeRegistryModules) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeRegistryModules) Describe(ch chan<- *prometheus.Desc) {
+	ch <- RegistryModulesPublishedVersions
+	ch <- RegistryModulesStatus
+	ch <- RegistryModulesFailedVersions
+	ch <- RegistryModulesLatestVersionPublished
+}
+
+// failedVersionStatuses are the statuses of the versions that won't be published.
+var failedVersionStatuses = map[tfe.RegistryModuleVersionStatus]bool{
+	tfe.RegistryModuleVersionStatusCloneFailed:         true,
+	tfe.RegistryModuleVersionStatusRegIngressReqFailed: true,
+	tfe.RegistryModuleVersionStatusRegIngressFailed:    true,
+}
+
+// getLatestModuleVersion returns the latest version of the module, or nil if it has none.
+func getLatestModuleVersion(ctx context.Context, organization string, m *tfe.RegistryModule, config *setup.Config) (*registryModuleVersion, error) {
+	var latest registryModuleVersion
+	path := "/api/registry/v1/modules/" + url.PathEscape(organization) + "/" + url.PathEscape(m.Name) + "/" + url.PathEscape(m.Provider)
+	err := config.Pool.Do(ctx, func(ctx context.Context) error {
+		return config.API.GetJSON(ctx, path, &latest)
+	})
+	// Modules without published versions (or public ones) aren't found in the private registry.
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, (module=%s/%s)", err, m.Name, m.Provider)
+	}
+
+	return &latest, nil
+}
+
+func getRegistryModule(ctx context.Context, organization string, m *tfe.RegistryModule, config *setup.Config, ch chan<- prometheus.Metric) error {
+	published, failed := 0, 0
+	for _, v := range m.VersionStatuses {
+		switch {
+		case v.Status == tfe.RegistryModuleVersionStatusOk:
+			published++
+		case failedVersionStatuses[v.Status]:
+			failed++
+		}
+	}
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(RegistryModulesPublishedVersions, prometheus.GaugeValue, float64(published), organization, m.Name, m.Provider),
+		prometheus.MustNewConstMetric(RegistryModulesStatus, prometheus.GaugeValue, 1, organization, m.Name, m.Provider, string(m.Status)),
+		prometheus.MustNewConstMetric(RegistryModulesFailedVersions, prometheus.GaugeValue, float64(failed), organization, m.Name, m.Provider),
+	}
+
+	if published > 0 {
+		latest, err := getLatestModuleVersion(ctx, organization, m, config)
+		if err != nil {
+			return err
+		}
+		if latest != nil && !latest.PublishedAt.IsZero() {
+			metrics = append(metrics, prometheus.MustNewConstMetric(RegistryModulesLatestVersionPublished, prometheus.GaugeValue, float64(latest.PublishedAt.Unix()), organization, m.Name, m.Provider, latest.Version))
+		}
+	}
+
+	for _, metric := range metrics {
+		select {
+		case ch <- metric:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getRegistryModulesPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.RegistryModuleList, err error) {
+	ctx, span := tracer.Start(ctx, "registry modules page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var modulesList *tfe.RegistryModuleList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		modulesList, err = config.Client.RegistryModules.List(ctx, organization, &tfe.RegistryModuleListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return modulesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, m := range modulesList.Items {
+		m := m
+		g.Go(func() error {
+			return getRegistryModule(ctx, organization, m, config, ch)
+		})
+	}
+
+	return modulesList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeRegistryModules) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			list, err := getRegistryModulesPage(ctx, 1, name, config, ch)
+			if err != nil {
+				return err
+			}
+
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getRegistryModulesPage(ctx, page, name, config, ch)
+				return err
+			})
+		})
+	}
+
+	return g.Wait()
+}
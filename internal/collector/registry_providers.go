@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// registry_providers is the Metric subsystem we use.
+	registryProvidersSubsystem = "registry_providers"
+)
+
+// Metric descriptors.
+var (
+	RegistryProvidersLatestVersionPlatforms = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, registryProvidersSubsystem, "latest_version_platforms"),
+		"Number of platforms (os/arch) published for the latest version of the provider",
+		[]string{"organization", "namespace", "name", "version"}, nil,
+	)
+	RegistryProvidersLatestVersionPlatform = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, registryProvidersSubsystem, "latest_version_platform"),
+		"Platform (os/arch) published for the latest version of the provider, always 1",
+		[]string{"organization", "namespace", "name", "version", "os", "arch"}, nil,
+	)
+)
+
+// ScrapeRegistryProviders scrapes metrics about the providers of the private registry.
+type ScrapeRegistryProviders struct{}
+
+func init() {
+	Register(ScrapeRegistryProviders{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeRegistryProviders) Name() string {
+	return registryProvidersSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeRegistryProviders) Help() string {
+	return "Scrape the providers of the private registry from the Registry Providers API: https://www.terraform.io/cloud-docs/api-docs/private-registry/provider-versions-platforms"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeRegistryProviders) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeRegistryProviders) Describe(ch chan<- *prometheus.Desc) {
+	ch <- RegistryProvidersLatestVersionPlatforms
+	ch <- RegistryProvidersLatestVersionPlatform
+}
+
+// getLatestProviderVersion returns the version of the provider created last, or nil if it has none.
+// Versions aren't listed in any particular order, so all of them are fetched.
+func getLatestProviderVersion(ctx context.Context, providerID tfe.RegistryProviderID, config *setup.Config) (*tfe.RegistryProviderVersion, error) {
+	var (
+		latest          *tfe.RegistryProviderVersion
+		latestCreatedAt time.Time
+	)
+	for page := 1; ; page++ {
+		var versionsList *tfe.RegistryProviderVersionList
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			versionsList, err = config.Client.RegistryProviderVersions.List(ctx, providerID, &tfe.RegistryProviderVersionListOptions{
+				ListOptions: tfe.ListOptions{
+					PageSize:   pageSize,
+					PageNumber: page,
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w, (provider=%s/%s, page=%d)", err, providerID.Namespace, providerID.Name, page)
+		}
+
+		for _, v := range versionsList.Items {
+			createdAt, _ := time.Parse(time.RFC3339, v.CreatedAt)
+			if latest == nil || createdAt.After(latestCreatedAt) {
+				latest, latestCreatedAt = v, createdAt
+			}
+		}
+
+		if versionsList.Pagination == nil || page >= versionsList.Pagination.TotalPages || pageLimitReached(ctx, page) {
+			return latest, nil
+		}
+	}
+}
+
+func getRegistryProvider(ctx context.Context, organization string, p *tfe.RegistryProvider, config *setup.Config, ch chan<- prometheus.Metric) error {
+	providerID := tfe.RegistryProviderID{
+		OrganizationName: organization,
+		RegistryName:     p.RegistryName,
+		Namespace:        p.Namespace,
+		Name:             p.Name,
+	}
+	latest, err := getLatestProviderVersion(ctx, providerID, config)
+	if err != nil || latest == nil {
+		return err
+	}
+
+	// Providers are built for a handful of platforms, so a single page is enough.
+	var platformsList *tfe.RegistryProviderPlatformList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		platformsList, err = config.Client.RegistryProviderPlatforms.List(ctx, tfe.RegistryProviderVersionID{
+			RegistryProviderID: providerID,
+			Version:            latest.Version,
+		}, &tfe.RegistryProviderPlatformListOptions{
+			ListOptions: tfe.ListOptions{PageSize: pageSize},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w, (provider=%s/%s, version=%s)", err, p.Namespace, p.Name, latest.Version)
+	}
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(RegistryProvidersLatestVersionPlatforms, prometheus.GaugeValue, float64(len(platformsList.Items)), organization, p.Namespace, p.Name, latest.Version),
+	}
+	for _, platform := range platformsList.Items {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RegistryProvidersLatestVersionPlatform, prometheus.GaugeValue, 1, organization, p.Namespace, p.Name, latest.Version, platform.OS, platform.Arch))
+	}
+
+	for _, metric := range metrics {
+		select {
+		case ch <- metric:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getRegistryProvidersPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.RegistryProviderList, err error) {
+	ctx, span := tracer.Start(ctx, "registry providers page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var providersList *tfe.RegistryProviderList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		// Public providers are only mirrored from the public registry, nothing is published for them.
+		providersList, err = config.Client.RegistryProviders.List(ctx, organization, &tfe.RegistryProviderListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+			RegistryName: tfe.PrivateRegistry,
+		})
+		return err
+	})
+	if err != nil {
+		return providersList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, p := range providersList.Items {
+		p := p
+		g.Go(func() error {
+			return getRegistryProvider(ctx, organization, p, config, ch)
+		})
+	}
+
+	return providersList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeRegistryProviders) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			list, err := getRegistryProvidersPage(ctx, 1, name, config, ch)
+			if err != nil {
+				return err
+			}
+
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getRegistryProvidersPage(ctx, page, name, config, ch)
+				return err
+			})
+		})
+	}
+
+	return g.Wait()
+}
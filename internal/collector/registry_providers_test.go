@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeRegistryProviders(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/registry-providers":
+			if got := r.URL.Query().Get("filter[registry_name]"); got != "private" {
+				t.Errorf("unexpected registry filter: %q", got)
+			}
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"prov-1","type":"registry-providers","attributes":{"name":"internal","namespace":"test-org","registry-name":"private"}}]
+			}`))
+		case "/api/v2/organizations/test-org/registry-providers/private/test-org/internal/versions":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"provver-1","type":"registry-provider-versions","attributes":{"version":"1.0.0","created-at":"2022-01-01T00:00:00Z"}},
+					{"id":"provver-2","type":"registry-provider-versions","attributes":{"version":"1.1.0","created-at":"2022-02-01T00:00:00Z"}}
+				]
+			}`))
+		case "/api/v2/organizations/test-org/registry-providers/private/test-org/internal/versions/1.1.0/platforms":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"provpltfrm-1","type":"registry-provider-platforms","attributes":{"os":"linux","arch":"amd64"}},
+					{"id":"provpltfrm-2","type":"registry-provider-platforms","attributes":{"os":"darwin","arch":"amd64"}}
+				]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeRegistryProviders{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "namespace": "test-org", "name": "internal", "version": "1.1.0"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "namespace": "test-org", "name": "internal", "version": "1.1.0", "os": "linux", "arch": "amd64"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "namespace": "test-org", "name": "internal", "version": "1.1.0", "os": "darwin", "arch": "amd64"}, value: 1, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+	})
+}
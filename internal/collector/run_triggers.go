@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// run_triggers is the Metric subsystem we use.
+	runTriggersSubsystem = "run_triggers"
+)
+
+// Metric descriptors.
+var (
+	RunTriggersDownstreamCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runTriggersSubsystem, "downstream_count"),
+		"Number of workspaces the runs of the workspace trigger runs in",
+		[]string{"organization", "workspace"}, nil,
+	)
+	RunTriggersMaxChainDepth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runTriggersSubsystem, "max_chain_depth"),
+		"Number of run triggers of the longest chain of workspaces triggering runs in the next one",
+		[]string{"organization"}, nil,
+	)
+)
+
+var (
+	// runTriggersWorkspacesFields are the only fields of the workspaces needed to list their run triggers.
+	runTriggersWorkspacesFields = setup.Fields{"workspaces": {"name"}}
+	// runTriggersFields are the only fields of the run triggers needed to build their graph.
+	runTriggersFields = setup.Fields{"run-triggers": {"workspace-name"}}
+)
+
+// ScrapeRunTriggers scrapes the run triggers between the workspaces.
+type ScrapeRunTriggers struct{}
+
+func init() {
+	Register(ScrapeRunTriggers{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeRunTriggers) Name() string {
+	return runTriggersSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeRunTriggers) Help() string {
+	return "Scrape the run triggers between the workspaces from the Run Triggers API: https://www.terraform.io/cloud-docs/api-docs/run-triggers"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeRunTriggers) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeRunTriggers) Describe(ch chan<- *prometheus.Desc) {
+	ch <- RunTriggersDownstreamCount
+	ch <- RunTriggersMaxChainDepth
+}
+
+// runTriggerGraph holds the workspaces each workspace triggers runs in, by their names.
+type runTriggerGraph struct {
+	mu         sync.Mutex
+	downstream map[string][]string
+}
+
+func (g *runTriggerGraph) add(workspace string, downstream []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.downstream[workspace] = downstream
+}
+
+// maxChainDepth returns the number of run triggers of the longest chain of the graph.
+// Cycles aren't followed, a chain ends when it gets back to one of its workspaces.
+func (g *runTriggerGraph) maxChainDepth() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var (
+		depths  = map[string]int{}
+		inChain = map[string]bool{}
+		depth   func(workspace string) int
+	)
+	depth = func(workspace string) int {
+		if d, ok := depths[workspace]; ok {
+			return d
+		}
+		inChain[workspace] = true
+		max := 0
+		for _, next := range g.downstream[workspace] {
+			if inChain[next] {
+				continue
+			}
+			if d := 1 + depth(next); d > max {
+				max = d
+			}
+		}
+		inChain[workspace] = false
+		depths[workspace] = max
+		return max
+	}
+
+	max := 0
+	for workspace := range g.downstream {
+		if d := depth(workspace); d > max {
+			max = d
+		}
+	}
+
+	return max
+}
+
+func getWorkspaceRunTriggers(ctx context.Context, organization string, w *tfe.Workspace, config *setup.Config, graph *runTriggerGraph, ch chan<- prometheus.Metric) error {
+	downstream := []string{}
+	for page := 1; ; page++ {
+		var triggersList *tfe.RunTriggerList
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			triggersList, err = config.Client.RunTriggers.List(setup.WithFields(ctx, runTriggersFields), w.ID, &tfe.RunTriggerListOptions{
+				ListOptions: tfe.ListOptions{
+					PageSize:   pageSize,
+					PageNumber: page,
+				},
+				RunTriggerType: tfe.RunTriggerOutbound,
+			})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%w, (workspace=%s, page=%d)", err, w.Name, page)
+		}
+
+		for _, t := range triggersList.Items {
+			downstream = append(downstream, t.WorkspaceName)
+		}
+
+		if triggersList.Pagination == nil || page >= triggersList.Pagination.TotalPages || pageLimitReached(ctx, page) {
+			break
+		}
+	}
+	graph.add(w.Name, downstream)
+
+	select {
+	case ch <- prometheus.MustNewConstMetric(RunTriggersDownstreamCount, prometheus.GaugeValue, float64(len(downstream)), organization, w.Name):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func getRunTriggersPage(ctx context.Context, page int, organization string, config *setup.Config, graph *runTriggerGraph, ch chan<- prometheus.Metric) (_ *tfe.WorkspaceList, err error) {
+	ctx, span := tracer.Start(ctx, "run triggers workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var workspacesList *tfe.WorkspaceList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(setup.WithQuery(ctx, config.WorkspacesSearch()), runTriggersWorkspacesFields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return workspacesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range workspacesList.Items {
+		w := w
+		g.Go(func() error {
+			return getWorkspaceRunTriggers(ctx, organization, w, config, graph, ch)
+		})
+	}
+
+	return workspacesList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeRunTriggers) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			graph := &runTriggerGraph{downstream: map[string][]string{}}
+			list, err := getRunTriggersPage(ctx, 1, name, config, graph, ch)
+			if err != nil {
+				return err
+			}
+
+			err = fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getRunTriggersPage(ctx, page, name, config, graph, ch)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			// The depth is only sent once the whole graph is known, a partial one would be misleading.
+			select {
+			case ch <- prometheus.MustNewConstMetric(RunTriggersMaxChainDepth, prometheus.GaugeValue, float64(graph.maxChainDepth()), name):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeRunTriggers(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/run-triggers") && r.URL.Query().Get("filter[run-trigger][type]") != "outbound" {
+			t.Errorf("unexpected run triggers filter: %s", r.URL.RawQuery)
+		}
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":3}},
+				"data":[
+					{"id":"ws-a","type":"workspaces","attributes":{"name":"network"}},
+					{"id":"ws-b","type":"workspaces","attributes":{"name":"cluster"}},
+					{"id":"ws-c","type":"workspaces","attributes":{"name":"app"}}
+				]
+			}`))
+		case "/api/v2/workspaces/ws-a/run-triggers":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"rt-1","type":"run-triggers","attributes":{"workspace-name":"cluster"}},
+					{"id":"rt-2","type":"run-triggers","attributes":{"workspace-name":"app"}}
+				]
+			}`))
+		case "/api/v2/workspaces/ws-b/run-triggers":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"rt-3","type":"run-triggers","attributes":{"workspace-name":"app"}}]
+			}`))
+		case "/api/v2/workspaces/ws-c/run-triggers":
+			w.Write([]byte(`{"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":0}},"data":[]}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeRunTriggers{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// The workspaces are read concurrently, so their order isn't deterministic.
+	got := []MetricResult{}
+	for m := range ch {
+		got = append(got, readMetric(m))
+	}
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "workspace": "network"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "cluster"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "app"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 2, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldHaveLength, len(counterExpected))
+		for _, expect := range counterExpected {
+			convey.So(got, convey.ShouldContain, expect)
+		}
+	})
+}
+
+func TestRunTriggerGraphMaxChainDepth(t *testing.T) {
+	convey.Convey("Cycles aren't followed", t, func() {
+		graph := &runTriggerGraph{downstream: map[string][]string{
+			"a": {"b"},
+			"b": {"c"},
+			"c": {"a"},
+		}}
+		convey.So(graph.maxChainDepth(), convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Without run triggers", t, func() {
+		graph := &runTriggerGraph{downstream: map[string][]string{"a": {}}}
+		convey.So(graph.maxChainDepth(), convey.ShouldEqual, 0)
+	})
+}
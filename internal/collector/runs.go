@@ -0,0 +1,519 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// runs is the Metric subsystem we use.
+	runsSubsystem = "runs"
+)
+
+// Metric descriptors.
+var (
+	RunsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "count"),
+		"Number of runs created within the lookback window, by their current status and whether they destroy the resources",
+		[]string{"organization", "status", "is_destroy"}, nil,
+	)
+	RunsSourceCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "source_count"),
+		"Number of runs created within the lookback window, by their source (tfe-ui, tfe-api or tfe-configuration-version)",
+		[]string{"organization", "source"}, nil,
+	)
+	RunsTriggerReasonCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "trigger_reason_count"),
+		"Number of runs created within the lookback window, by their source and the reason they were triggered for (e.g. manual or vcs)",
+		[]string{"organization", "source", "trigger_reason"}, nil,
+	)
+	RunsDestroyCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "destroy_count"),
+		"Number of destroy runs created within the lookback window, only for the workspaces with any",
+		[]string{"organization", "workspace"}, nil,
+	)
+	RunsDestroyTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "destroy_total"),
+		"Number of destroy runs created since the exporter started, as listed within the lookback window, for every workspace",
+		[]string{"organization", "workspace"}, nil,
+	)
+	RunsPlanDurationAverage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "plan_duration_average_seconds"),
+		"Average time to plan the runs created within the lookback window",
+		[]string{"organization"}, nil,
+	)
+	RunsErrorRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "error_ratio"),
+		"Ratio of the runs created within the lookback window that errored",
+		[]string{"organization"}, nil,
+	)
+	RunsConfirmationDurationAverage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "confirmation_duration_average_seconds"),
+		"Average time the runs created within the lookback window waited to be confirmed manually, once planned",
+		[]string{"organization"}, nil,
+	)
+	RunsOldestPendingAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, runsSubsystem, "oldest_pending_age_seconds"),
+		"Time since the creation of the oldest run waiting to be planned or applied (pending, plan_queued or apply_queued), however old, 0 if there are none",
+		[]string{"organization"}, nil,
+	)
+)
+
+// pendingStatuses are the statuses of the runs waiting for their workspace or for a free worker/agent.
+var pendingStatuses = map[tfe.RunStatus]bool{
+	tfe.RunPending:     true,
+	tfe.RunPlanQueued:  true,
+	tfe.RunApplyQueued: true,
+}
+
+// runsFields are the only fields of the runs aggregated.
+var runsFields = setup.Fields{"runs": {"status", "source", "trigger-reason", "is-destroy", "created-at", "status-timestamps", "auto-apply"}}
+
+// listedRun is a run aggregated, listed with its trigger reason which the go-tfe client doesn't support.
+type listedRun struct {
+	ID               string                   `jsonapi:"primary,runs"`
+	Status           tfe.RunStatus            `jsonapi:"attr,status"`
+	Source           tfe.RunSource            `jsonapi:"attr,source"`
+	TriggerReason    string                   `jsonapi:"attr,trigger-reason"`
+	IsDestroy        bool                     `jsonapi:"attr,is-destroy"`
+	CreatedAt        time.Time                `jsonapi:"attr,created-at,iso8601"`
+	StatusTimestamps *tfe.RunStatusTimestamps `jsonapi:"attr,status-timestamps"`
+	AutoApply        bool                     `jsonapi:"attr,auto-apply"`
+}
+
+// runStatus is the current status of a run along with whether it destroys the resources.
+type runStatus struct {
+	status    tfe.RunStatus
+	isDestroy bool
+}
+
+// runTrigger is the source of a run along with the reason it was triggered for.
+type runTrigger struct {
+	source tfe.RunSource
+	reason string
+}
+
+// pendingRunsFields are the only fields of the pending runs needed to tell the oldest one.
+var pendingRunsFields = setup.Fields{"runs": {"created-at"}}
+
+// pendingRunsQuery only lists the pending runs of the organization, however old, as they may be stuck since before
+// the lookback window.
+var pendingRunsQuery = url.Values{"filter[status]": {string(tfe.RunPending) + "," + string(tfe.RunPlanQueued) + "," + string(tfe.RunApplyQueued)}}
+
+// pendingRun is a pending run, listed with a filter the go-tfe client doesn't support.
+type pendingRun struct {
+	ID        string    `jsonapi:"primary,runs"`
+	CreatedAt time.Time `jsonapi:"attr,created-at,iso8601"`
+}
+
+// ScrapeRuns scrapes the runs of every workspace, aggregated per organization,
+// so dashboards don't need to aggregate the series of every workspace.
+type ScrapeRuns struct{}
+
+func init() {
+	Register(ScrapeRuns{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeRuns) Name() string {
+	return runsSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeRuns) Help() string {
+	return "Scrape the recent runs of every workspace from the Runs API: https://www.terraform.io/docs/cloud/api/run.html"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeRuns) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeRuns) Describe(ch chan<- *prometheus.Desc) {
+	ch <- RunsCount
+	ch <- RunsSourceCount
+	ch <- RunsTriggerReasonCount
+	ch <- RunsDestroyCount
+	ch <- RunsDestroyTotal
+	ch <- RunsPlanDurationAverage
+	ch <- RunsErrorRatio
+	ch <- RunsConfirmationDurationAverage
+	ch <- RunsOldestPendingAge
+}
+
+// runStats aggregates the runs of an organization.
+type runStats struct {
+	mu        sync.Mutex
+	byStatus  map[runStatus]int
+	bySource  map[tfe.RunSource]int
+	byTrigger map[runTrigger]int
+	// destroys counts the destroy runs by the name of their workspace.
+	destroys map[string]int
+	// destroyRuns are the destroy runs by their ID, and workspaces the names of the workspaces
+	// visited, for tf_runs_destroy_total.
+	destroyRuns  map[string]destroyRun
+	workspaces   map[string]bool
+	total        int
+	errored      int
+	plans        int
+	planDuration time.Duration
+	// confirmations only counts the runs confirmed manually.
+	confirmations        int
+	confirmationDuration time.Duration
+	// oldestPending is the creation time of the oldest pending run, if any.
+	oldestPending time.Time
+}
+
+func newRunStats() *runStats {
+	return &runStats{
+		byStatus:    map[runStatus]int{},
+		bySource:    map[tfe.RunSource]int{},
+		byTrigger:   map[runTrigger]int{},
+		destroys:    map[string]int{},
+		destroyRuns: map[string]destroyRun{},
+		workspaces:  map[string]bool{},
+	}
+}
+
+// visit records a workspace whose runs are listed, even without any.
+func (s *runStats) visit(w *tfe.Workspace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.workspaces[w.Name] = true
+}
+
+func (s *runStats) add(w *tfe.Workspace, r *listedRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.total++
+	s.byStatus[runStatus{r.Status, r.IsDestroy}]++
+	s.bySource[r.Source]++
+	s.byTrigger[runTrigger{r.Source, r.TriggerReason}]++
+	if r.IsDestroy {
+		s.destroys[w.Name]++
+		s.destroyRuns[r.ID] = destroyRun{workspace: w.Name, createdAt: r.CreatedAt}
+	}
+	if r.Status == tfe.RunErrored {
+		s.errored++
+	}
+	if pendingStatuses[r.Status] && (s.oldestPending.IsZero() || r.CreatedAt.Before(s.oldestPending)) {
+		s.oldestPending = r.CreatedAt
+	}
+
+	if ts := r.StatusTimestamps; ts != nil && !ts.PlanningAt.IsZero() {
+		plannedAt := ts.PlannedAt
+		if plannedAt.IsZero() {
+			plannedAt = ts.PlannedAndFinishedAt
+		}
+		if !plannedAt.IsZero() {
+			s.plans++
+			s.planDuration += plannedAt.Sub(ts.PlanningAt)
+		}
+	}
+
+	if ts := r.StatusTimestamps; ts != nil && !r.AutoApply && !ts.ConfirmedAt.IsZero() {
+		if confirmableAt := confirmableAt(ts); !confirmableAt.IsZero() {
+			s.confirmations++
+			s.confirmationDuration += ts.ConfirmedAt.Sub(confirmableAt)
+		}
+	}
+}
+
+// addPending counts a pending run created at the given time, listed apart from the recent ones, however old.
+func (s *runStats) addPending(createdAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.oldestPending.IsZero() || createdAt.Before(s.oldestPending) {
+		s.oldestPending = createdAt
+	}
+}
+
+// confirmableAt returns when the run was ready to be confirmed: Once planned, and after the cost estimation and
+// policy checks if any, or the zero time if it never was.
+func confirmableAt(ts *tfe.RunStatusTimestamps) time.Time {
+	at := ts.PlannedAt
+	for _, t := range []time.Time{ts.CostEstimatedAt, ts.PolicyCheckedAt, ts.PolicySoftFailedAt} {
+		if t.After(at) && !t.After(ts.ConfirmedAt) {
+			at = t
+		}
+	}
+
+	return at
+}
+
+func (s *runStats) collect(ctx context.Context, organization string, destroyTotals map[string]float64, ch chan<- prometheus.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]runStatus, 0, len(s.byStatus))
+	for status := range s.byStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].status != statuses[j].status {
+			return statuses[i].status < statuses[j].status
+		}
+		return !statuses[i].isDestroy && statuses[j].isDestroy
+	})
+	sources := make([]string, 0, len(s.bySource))
+	for source := range s.bySource {
+		sources = append(sources, string(source))
+	}
+	sort.Strings(sources)
+	triggers := make([]runTrigger, 0, len(s.byTrigger))
+	for trigger := range s.byTrigger {
+		triggers = append(triggers, trigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool {
+		if triggers[i].source != triggers[j].source {
+			return triggers[i].source < triggers[j].source
+		}
+		return triggers[i].reason < triggers[j].reason
+	})
+	workspaces := make([]string, 0, len(s.destroys))
+	for workspace := range s.destroys {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+	totalWorkspaces := make([]string, 0, len(destroyTotals))
+	for workspace := range destroyTotals {
+		totalWorkspaces = append(totalWorkspaces, workspace)
+	}
+	sort.Strings(totalWorkspaces)
+
+	metrics := make([]prometheus.Metric, 0, len(statuses)+len(sources)+len(triggers)+len(workspaces)+len(totalWorkspaces)+4)
+	for _, status := range statuses {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsCount, prometheus.GaugeValue, float64(s.byStatus[status]), organization, string(status.status), strconv.FormatBool(status.isDestroy)))
+	}
+	for _, source := range sources {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsSourceCount, prometheus.GaugeValue, float64(s.bySource[tfe.RunSource(source)]), organization, source))
+	}
+	for _, trigger := range triggers {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsTriggerReasonCount, prometheus.GaugeValue, float64(s.byTrigger[trigger]), organization, string(trigger.source), trigger.reason))
+	}
+	for _, workspace := range workspaces {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsDestroyCount, prometheus.GaugeValue, float64(s.destroys[workspace]), organization, workspace))
+	}
+	for _, workspace := range totalWorkspaces {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsDestroyTotal, prometheus.CounterValue, destroyTotals[workspace], organization, workspace))
+	}
+	if s.plans > 0 {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsPlanDurationAverage, prometheus.GaugeValue, s.planDuration.Seconds()/float64(s.plans), organization))
+	}
+	if s.total > 0 {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsErrorRatio, prometheus.GaugeValue, float64(s.errored)/float64(s.total), organization))
+	}
+	if s.confirmations > 0 {
+		metrics = append(metrics, prometheus.MustNewConstMetric(RunsConfirmationDurationAverage, prometheus.GaugeValue, s.confirmationDuration.Seconds()/float64(s.confirmations), organization))
+	}
+	oldestPendingAge := 0.0
+	if !s.oldestPending.IsZero() {
+		oldestPendingAge = time.Since(s.oldestPending).Seconds()
+	}
+	metrics = append(metrics, prometheus.MustNewConstMetric(RunsOldestPendingAge, prometheus.GaugeValue, oldestPendingAge, organization))
+
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// destroyRun is a destroy run of a workspace, counted once by tf_runs_destroy_total.
+type destroyRun struct {
+	workspace string
+	createdAt time.Time
+}
+
+// destroyRunsCounter counts the destroy runs of an organization across the scrapes, for tf_runs_destroy_total.
+type destroyRunsCounter struct {
+	mu sync.Mutex
+	// counted are the creation times of the destroy runs already counted by their ID, forgotten once they are older
+	// than the lookback window, as they aren't listed anymore.
+	counted map[string]time.Time
+	totals  map[string]float64
+}
+
+// destroyRunsCounters keeps the destroy runs counters of every instance and organization, across the scrapes.
+var destroyRunsCounters = struct {
+	sync.Mutex
+	m map[string]*destroyRunsCounter
+}{m: map[string]*destroyRunsCounter{}}
+
+// destroyRunsCounterOf returns the destroy runs counter of the organization, empty if its runs weren't listed yet.
+func destroyRunsCounterOf(organization string, config *setup.Config) *destroyRunsCounter {
+	key := config.Instance + "/" + organization
+
+	destroyRunsCounters.Lock()
+	defer destroyRunsCounters.Unlock()
+
+	c, ok := destroyRunsCounters.m[key]
+	if !ok {
+		c = &destroyRunsCounter{counted: map[string]time.Time{}, totals: map[string]float64{}}
+		destroyRunsCounters.m[key] = c
+	}
+
+	return c
+}
+
+// add counts the destroy runs not counted yet, and returns the totals of every workspace visited, 0 for the ones
+// without any so alerts on their increase fire on the first one. The totals of the workspaces no longer visited
+// are dropped, unless the list of the workspaces was truncated.
+func (c *destroyRunsCounter) add(stats *runStats, since time.Time, truncated bool) map[string]float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	for id, createdAt := range c.counted {
+		if createdAt.Before(since) {
+			delete(c.counted, id)
+		}
+	}
+	for id, r := range stats.destroyRuns {
+		if _, ok := c.counted[id]; ok {
+			continue
+		}
+		c.counted[id] = r.createdAt
+		c.totals[r.workspace]++
+	}
+	for workspace := range stats.workspaces {
+		if _, ok := c.totals[workspace]; !ok {
+			c.totals[workspace] = 0
+		}
+	}
+	if !truncated {
+		for workspace := range c.totals {
+			if !stats.workspaces[workspace] {
+				delete(c.totals, workspace)
+			}
+		}
+	}
+
+	totals := make(map[string]float64, len(c.totals))
+	for workspace, total := range c.totals {
+		totals[workspace] = total
+	}
+
+	return totals
+}
+
+// visitListedRuns counts the runs of the workspace created since the given time, listed with their trigger reason.
+// Runs are listed from the newest, so the pages stop being fetched at the first older run.
+func visitListedRuns(ctx context.Context, w *tfe.Workspace, since time.Time, config *setup.Config, stats *runStats) error {
+	for page := 1; ; page++ {
+		var runs []*listedRun
+		var pagination *tfe.Pagination
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			pagination, err = config.API.List(setup.WithFields(ctx, runsFields), "workspaces/"+url.PathEscape(w.ID)+"/runs", tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			}, nil, &runs)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%w, (workspace=%s, page=%d)", err, w.ID, page)
+		}
+
+		for _, r := range runs {
+			if r.CreatedAt.Before(since) {
+				return nil
+			}
+			stats.add(w, r)
+		}
+
+		if pagination == nil || page >= pagination.TotalPages || pageLimitReached(ctx, page) {
+			return nil
+		}
+	}
+}
+
+// visitPendingRuns counts the pending runs of the organization, however old, with a single list filtered by their
+// status. Instances without this list (older Terraform Enterprise releases) only count the pending runs listed
+// within the lookback window.
+func visitPendingRuns(ctx context.Context, organization string, config *setup.Config, stats *runStats) error {
+	return listAllPages(ctx, func(ctx context.Context, page int) (*tfe.Pagination, error) {
+		var runs []*pendingRun
+		var pagination *tfe.Pagination
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			pagination, err = config.API.List(setup.WithFields(ctx, pendingRunsFields), "organizations/"+url.PathEscape(organization)+"/runs", tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			}, pendingRunsQuery, &runs)
+			return err
+		})
+		if errors.Is(err, tfe.ErrResourceNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+		}
+
+		for _, r := range runs {
+			stats.addPending(r.CreatedAt)
+		}
+
+		return pagination, nil
+	})
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeRuns) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	since := time.Now().Add(-config.RunsLookback)
+
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			stats := newRunStats()
+			err = visitWorkspaces(ctx, name, config, func(ctx context.Context, w *tfe.Workspace) error {
+				stats.visit(w)
+				return visitListedRuns(ctx, w, since, config, stats)
+			})
+			if err != nil {
+				return err
+			}
+			if err := visitPendingRuns(ctx, name, config, stats); err != nil {
+				return err
+			}
+
+			// The aggregates are only sent once every run is counted, partial ones would be misleading.
+			destroyTotals := destroyRunsCounterOf(name, config).add(stats, since, pagesTruncated(ctx))
+			return stats.collect(ctx, name, destroyTotals, ch)
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// runJSON returns a run created the given time ago, planned in planSeconds
+// and confirmed confirmSeconds after being planned, if any.
+func runJSON(id, status string, age time.Duration, planSeconds, confirmSeconds int) string {
+	return runSourceJSON(id, status, "tfe-configuration-version", age, planSeconds, confirmSeconds)
+}
+
+// runSourceJSON returns a run like runJSON, created from the given source, triggered manually from the UI
+// or by a VCS otherwise.
+func runSourceJSON(id, status, source string, age time.Duration, planSeconds, confirmSeconds int) string {
+	triggerReason := "vcs"
+	if source == "tfe-ui" {
+		triggerReason = "manual"
+	}
+	createdAt := time.Now().Add(-age).UTC()
+	plannedAt := createdAt.Add(time.Duration(planSeconds) * time.Second)
+	confirmedAt := ""
+	if confirmSeconds > 0 {
+		confirmedAt = fmt.Sprintf(`,"confirmed-at":%q`, plannedAt.Add(time.Duration(confirmSeconds)*time.Second).Format(time.RFC3339))
+	}
+	return fmt.Sprintf(`{
+		"id":%q,
+		"type":"runs",
+		"attributes":{
+			"status":%q,
+			"source":%q,
+			"trigger-reason":%q,
+			"created-at":%q,
+			"status-timestamps":{"planning-at":%q,"planned-at":%q%s}
+		}
+	}`, id, status, source, triggerReason, createdAt.Format(time.RFC3339), createdAt.Format(time.RFC3339), plannedAt.Format(time.RFC3339), confirmedAt)
+}
+
+func TestScrapeRuns(t *testing.T) {
+	// The second scrape lists another destroy run of dev, along with the ones already counted.
+	scrapes := 0
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		// The pending runs of the organization are listed apart, however old.
+		case "/api/v2/organizations/test-org/runs":
+			if got := r.URL.Query().Get("filter[status]"); got != "pending,plan_queued,apply_queued" {
+				t.Errorf("unexpected runs filter: %q", got)
+			}
+			w.Write([]byte(`{"meta":{"pagination":{"current-page":1,"total-pages":1}},"data":[` + runJSON("run-5", "pending", 30*time.Minute, 0, 0) + `,` + runJSON("run-6", "plan_queued", 72*time.Hour, 0, 0) + `]}`))
+		case "/api/v2/organizations/test-org/workspaces":
+			scrapes++
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"ws-1","type":"workspaces","attributes":{"name":"dev"}},
+					{"id":"ws-2","type":"workspaces","attributes":{"name":"stg"}}
+				]
+			}`))
+		case "/api/v2/workspaces/ws-1/runs":
+			if scrapes > 1 {
+				w.Write([]byte(`{"meta":{"pagination":{"current-page":1,"total-pages":1}},"data":[{"id":"run-7","type":"runs","attributes":{"status":"planning","is-destroy":true,"created-at":"` + time.Now().UTC().Format(time.RFC3339) + `"}}]}`))
+				return
+			}
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":3}},
+				"data":[` + runJSON("run-1", "applied", time.Hour, 10, 120) + `,` + runJSON("run-2", "errored", 2*time.Hour, 30, 0) + `,` + runJSON("run-3", "applied", 48*time.Hour, 100, 0) + `]
+			}`))
+		case "/api/v2/workspaces/ws-2/runs":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[
+					{"id":"run-5","type":"runs","attributes":{"status":"pending","source":"tfe-api","is-destroy":true,"created-at":"` + time.Now().Add(-30*time.Minute).UTC().Format(time.RFC3339) + `"}},
+					` + runSourceJSON("run-4", "applied", "tfe-ui", 3*time.Hour, 20, 60) + `
+				]
+			}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		API:    api,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}, RunsLookback: 24 * time.Hour},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeRuns{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// The run older than the lookback window isn't aggregated, but the oldest pending run is, however old.
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "status": "applied", "is_destroy": "false"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "status": "errored", "is_destroy": "false"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "status": "pending", "is_destroy": "true"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "source": "tfe-api"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "source": "tfe-configuration-version"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "source": "tfe-ui"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "source": "tfe-api", "trigger_reason": ""}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "source": "tfe-configuration-version", "trigger_reason": "vcs"}, value: 2, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "source": "tfe-ui", "trigger_reason": "manual"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "stg"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "workspace": "dev"}, value: 0, metricType: dto.MetricType_COUNTER},
+		{labels: labelMap{"organization": "test-org", "workspace": "stg"}, value: 1, metricType: dto.MetricType_COUNTER},
+		{labels: labelMap{"organization": "test-org"}, value: 20, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 0.25, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org"}, value: 90, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range counterExpected {
+			got := readMetric(<-ch)
+			convey.So(got, convey.ShouldResemble, expect)
+		}
+
+		oldestPending := readMetric(<-ch)
+		convey.So(oldestPending.value, convey.ShouldAlmostEqual, (72 * time.Hour).Seconds(), 60)
+	})
+
+	convey.Convey("Counts each destroy run once across the scrapes", t, func() {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			defer close(ch)
+			if err := (ScrapeRuns{}).Scrape(context.Background(), config, ch); err != nil {
+				t.Errorf("error calling function on test: %s", err)
+			}
+		}()
+
+		totals := []MetricResult{}
+		for m := range ch {
+			if m.Desc() == RunsDestroyTotal {
+				totals = append(totals, readMetric(m))
+			}
+		}
+		convey.So(totals, convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"organization": "test-org", "workspace": "dev"}, value: 1, metricType: dto.MetricType_COUNTER},
+			{labels: labelMap{"organization": "test-org", "workspace": "stg"}, value: 1, metricType: dto.MetricType_COUNTER},
+		})
+	})
+}
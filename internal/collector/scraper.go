@@ -9,6 +9,8 @@ import (
 )
 
 // Scraper is minimal interface that let's you add new prometheus metrics to tf_exporter.
+// It's stable, so Scrapers can be shipped as plugins, built in with a build tag and added with Register.
+// New capabilities are optional interfaces, instead of new methods.
 type Scraper interface {
 	// Name of the Scraper. Should be unique.
 	Name() string
@@ -20,6 +22,18 @@ type Scraper interface {
 	// Version of Terraform Cloud/Enterprise API from which scraper is available.
 	Version() string
 
+	// Describe sends the descriptors of every metric the Scraper can emit, used to document them.
+	Describe(ch chan<- *prometheus.Desc)
+
 	// Scrape collects data from a particular terraform cloud/enterprise API and sends it over channel as prometheus metric.
+	// Metrics should be sent as soon as they are built (e.g. per page of a list) instead of accumulating
+	// whole lists, so the memory used by the exporter doesn't grow with the size of the organizations.
 	Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error
 }
+
+// siteScraper is implemented by the Scrapers of site-wide APIs (e.g. the Terraform Enterprise admin API),
+// which aren't scoped to organizations, so they're run once per scrape instead of once per organization.
+type siteScraper interface {
+	Scraper
+	siteWide()
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ScraperStatus is the outcome of the last run of a scraper.
+type ScraperStatus struct {
+	Scraper  string    `json:"scraper"`
+	LastRun  time.Time `json:"last_run"`
+	Age      float64   `json:"age_seconds"`
+	Duration float64   `json:"duration_seconds"`
+	Success  bool      `json:"success"`
+	// LastSuccess is unset until the scraper succeeds.
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Requests    int        `json:"api_requests"`
+	Pages       int        `json:"pages_fetched"`
+	Items       int        `json:"items_listed"`
+	// Truncated reports whether any list stopped before its last page, by --max-pages.
+	Truncated bool   `json:"pages_truncated,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Status keeps the outcome of the last run of every scraper, to tell whether the exporter is healthy and its metrics fresh.
+// It is safe for concurrent use. A nil Status records nothing.
+type Status struct {
+	mu       sync.RWMutex
+	scrapers map[string]ScraperStatus
+}
+
+// NewStatus returns a new empty Status.
+func NewStatus() *Status {
+	return &Status{scrapers: map[string]ScraperStatus{}}
+}
+
+// record saves the outcome of a run of a scraper, keeping when it last succeeded.
+func (s *Status) record(status ScraperStatus, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		status.Success = true
+		status.LastSuccess = &status.LastRun
+	} else {
+		status.LastError = err.Error()
+		status.LastSuccess = s.scrapers[status.Scraper].LastSuccess
+	}
+	s.scrapers[status.Scraper] = status
+}
+
+// Scrapers returns the outcome of the last run of every scraper that ran, sorted by name.
+func (s *Status) Scrapers() []ScraperStatus {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scrapers := make([]ScraperStatus, 0, len(s.scrapers))
+	for _, status := range s.scrapers {
+		status.Age = time.Since(status.LastRun).Seconds()
+		scrapers = append(scrapers, status)
+	}
+	sort.Slice(scrapers, func(i, j int) bool { return scrapers[i].Scraper < scrapers[j].Scraper })
+
+	return scrapers
+}
+
+// Healthy reports whether the last run of every scraper succeeded.
+func (s *Status) Healthy() bool {
+	for _, status := range s.Scrapers() {
+		if !status.Success {
+			return false
+		}
+	}
+
+	return true
+}
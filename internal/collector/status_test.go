@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestStatus(t *testing.T) {
+	config := setup.Config{CLI: setup.CLI{Organizations: []string{"org1", "org2"}}, Logger: log.NewNopLogger()}
+	scrapers := []Scraper{
+		fakeScraper{name: "ok"},
+		fakeScraper{name: "failing", err: errors.New("boom"), failOrganization: "org2"},
+	}
+	metrics := NewMetrics()
+	collectByName(New(context.Background(), config, scrapers, metrics, nil))
+
+	convey.Convey("The last run of every scraper is recorded", t, func() {
+		status := metrics.Status.Scrapers()
+		convey.So(status, convey.ShouldHaveLength, 2)
+		convey.So(metrics.Status.Healthy(), convey.ShouldBeFalse)
+
+		convey.So(status[0].Scraper, convey.ShouldEqual, "failing")
+		convey.So(status[0].Success, convey.ShouldBeFalse)
+		convey.So(status[0].LastSuccess, convey.ShouldBeNil)
+		convey.So(status[0].LastError, convey.ShouldEqual, "failed organizations: org2, last error: boom")
+
+		convey.So(status[1].Scraper, convey.ShouldEqual, "ok")
+		convey.So(status[1].Success, convey.ShouldBeTrue)
+		convey.So(*status[1].LastSuccess, convey.ShouldEqual, status[1].LastRun)
+		convey.So(status[1].LastError, convey.ShouldBeEmpty)
+	})
+
+	convey.Convey("A failing run keeps the last success", t, func() {
+		previous := metrics.Status.Scrapers()[1].LastRun
+		metrics.Status.record(ScraperStatus{Scraper: "ok", LastRun: previous.Add(1)}, errors.New("boom"))
+
+		status := metrics.Status.Scrapers()[1]
+		convey.So(status.Success, convey.ShouldBeFalse)
+		convey.So(*status.LastSuccess, convey.ShouldEqual, previous)
+	})
+
+	convey.Convey("A nil Status records nothing", t, func() {
+		var status *Status
+		status.record(ScraperStatus{Scraper: "ok"}, nil)
+		convey.So(status.Scrapers(), convey.ShouldBeEmpty)
+		convey.So(status.Healthy(), convey.ShouldBeTrue)
+	})
+}
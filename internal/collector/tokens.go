@@ -0,0 +1,251 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tokens is the Metric subsystem we use.
+	tokensSubsystem = "tokens"
+)
+
+// Metric descriptors.
+var (
+	TokensCreated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tokensSubsystem, "created_timestamp_seconds"),
+		"Unix timestamp of the creation of the API token, by its type (organization, team or agent) and owner",
+		[]string{"organization", "type", "owner", "token_id"}, nil,
+	)
+	TokensLastUsed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tokensSubsystem, "last_used_timestamp_seconds"),
+		"Unix timestamp of the last use of the API token, by its type (organization, team or agent) and owner, 0 if it was never used",
+		[]string{"organization", "type", "owner", "token_id"}, nil,
+	)
+)
+
+// tokensTeamsFields are the only fields of the teams needed to read their tokens.
+var tokensTeamsFields = setup.Fields{"teams": {"name"}}
+
+// ScrapeTokens scrapes when the API tokens of the organizations were last used.
+type ScrapeTokens struct{}
+
+func init() {
+	Register(ScrapeTokens{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeTokens) Name() string {
+	return tokensSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeTokens) Help() string {
+	return "Scrape the organization, team and agent tokens from the Tokens APIs: https://www.terraform.io/cloud-docs/users-teams-organizations/api-tokens"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeTokens) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeTokens) Describe(ch chan<- *prometheus.Desc) {
+	ch <- TokensCreated
+	ch <- TokensLastUsed
+}
+
+func sendToken(ctx context.Context, organization, tokenType, owner, id string, createdAt, lastUsedAt time.Time, ch chan<- prometheus.Metric) error {
+	lastUsed := 0.0
+	if !lastUsedAt.IsZero() {
+		lastUsed = float64(lastUsedAt.Unix())
+	}
+	for _, m := range []prometheus.Metric{
+		prometheus.MustNewConstMetric(TokensCreated, prometheus.GaugeValue, float64(createdAt.Unix()), organization, tokenType, owner, id),
+		prometheus.MustNewConstMetric(TokensLastUsed, prometheus.GaugeValue, lastUsed, organization, tokenType, owner, id),
+	} {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getOrganizationToken(ctx context.Context, organization string, config *setup.Config, ch chan<- prometheus.Metric) error {
+	var t *tfe.OrganizationToken
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		t, err = config.Client.OrganizationTokens.Read(ctx, organization)
+		return err
+	})
+	// Organizations don't have a token until one is generated.
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w, (organization=%s)", err, organization)
+	}
+
+	return sendToken(ctx, organization, "organization", organization, t.ID, t.CreatedAt, t.LastUsedAt, ch)
+}
+
+func getTeamToken(ctx context.Context, organization string, team *tfe.Team, config *setup.Config, ch chan<- prometheus.Metric) error {
+	var t *tfe.TeamToken
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		t, err = config.Client.TeamTokens.Read(ctx, team.ID)
+		return err
+	})
+	// Teams don't have a token until one is generated.
+	if errors.Is(err, tfe.ErrResourceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w, (team=%s)", err, team.Name)
+	}
+
+	return sendToken(ctx, organization, "team", team.Name, t.ID, t.CreatedAt, t.LastUsedAt, ch)
+}
+
+func getTeamTokensPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.TeamList, err error) {
+	ctx, span := tracer.Start(ctx, "team tokens page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var teamsList *tfe.TeamList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		teamsList, err = config.Client.Teams.List(setup.WithFields(ctx, tokensTeamsFields), organization, &tfe.TeamListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return teamsList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, team := range teamsList.Items {
+		team := team
+		g.Go(func() error {
+			return getTeamToken(ctx, organization, team, config, ch)
+		})
+	}
+
+	return teamsList, g.Wait()
+}
+
+func getAgentTokens(ctx context.Context, organization string, pool *tfe.AgentPool, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// Agent pools only have a few tokens, they aren't paginated.
+	var tokensList *tfe.AgentTokenList
+	err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		tokensList, err = config.Client.AgentTokens.List(ctx, pool.ID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w, (agent_pool=%s)", err, pool.Name)
+	}
+
+	for _, t := range tokensList.Items {
+		if err := sendToken(ctx, organization, "agent", pool.Name, t.ID, t.CreatedAt, t.LastUsedAt, ch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getAgentTokensPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.AgentPoolList, err error) {
+	ctx, span := tracer.Start(ctx, "agent tokens page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var poolsList *tfe.AgentPoolList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		poolsList, err = config.Client.AgentPools.List(ctx, organization, &tfe.AgentPoolListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return poolsList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, pool := range poolsList.Items {
+		pool := pool
+		g.Go(func() error {
+			return getAgentTokens(ctx, organization, pool, config, ch)
+		})
+	}
+
+	return poolsList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeTokens) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			tg, ctx := errgroup.WithContext(ctx)
+			tg.Go(func() error {
+				return getOrganizationToken(ctx, name, config, ch)
+			})
+			tg.Go(func() error {
+				list, err := getTeamTokensPage(ctx, 1, name, config, ch)
+				if err != nil {
+					return err
+				}
+				return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+					_, err := getTeamTokensPage(ctx, page, name, config, ch)
+					return err
+				})
+			})
+			tg.Go(func() error {
+				list, err := getAgentTokensPage(ctx, 1, name, config, ch)
+				if err != nil {
+					return err
+				}
+				return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+					_, err := getAgentTokensPage(ctx, page, name, config, ch)
+					return err
+				})
+			})
+
+			return tg.Wait()
+		})
+	}
+
+	return g.Wait()
+}
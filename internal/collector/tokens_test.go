@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeTokens(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/authentication-token":
+			w.Write([]byte(`{"data":{"id":"at-org","type":"authentication-tokens","attributes":{"created-at":"2022-01-01T00:00:00Z","last-used-at":"2022-02-01T00:00:00Z"}}}`))
+		case "/api/v2/organizations/test-org/teams":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":2}},
+				"data":[{"id":"team-1","type":"teams","attributes":{"name":"owners"}},{"id":"team-2","type":"teams","attributes":{"name":"devs"}}]
+			}`))
+		case "/api/v2/teams/team-1/authentication-token":
+			w.Write([]byte(`{"data":{"id":"at-team","type":"authentication-tokens","attributes":{"created-at":"2022-01-01T00:00:00Z","last-used-at":null}}}`))
+		case "/api/v2/teams/team-2/authentication-token":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/v2/organizations/test-org/agent-pools":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{"id":"apool-1","type":"agent-pools","attributes":{"name":"default"}}]
+			}`))
+		case "/api/v2/agent-pools/apool-1/authentication-tokens":
+			w.Write([]byte(`{"data":[{"id":"at-agent","type":"authentication-tokens","attributes":{"created-at":"2022-01-01T00:00:00Z","last-used-at":"2022-03-01T00:00:00Z"}}]}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeTokens{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	// The tokens are read concurrently, so their order isn't deterministic.
+	got := []MetricResult{}
+	for m := range ch {
+		got = append(got, readMetric(m))
+	}
+
+	counterExpected := []MetricResult{
+		{labels: labelMap{"organization": "test-org", "type": "organization", "owner": "test-org", "token_id": "at-org"}, value: 1640995200, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "type": "organization", "owner": "test-org", "token_id": "at-org"}, value: 1643673600, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "type": "team", "owner": "owners", "token_id": "at-team"}, value: 1640995200, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "type": "team", "owner": "owners", "token_id": "at-team"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "type": "agent", "owner": "default", "token_id": "at-agent"}, value: 1640995200, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"organization": "test-org", "type": "agent", "owner": "default", "token_id": "at-agent"}, value: 1646092800, metricType: dto.MetricType_GAUGE},
+	}
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldHaveLength, len(counterExpected))
+		for _, expect := range counterExpected {
+			convey.So(got, convey.ShouldContain, expect)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// variables is the Metric subsystem we use.
+	variablesSubsystem = "variables"
+)
+
+// Metric descriptors.
+var (
+	VariablesCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, variablesSubsystem, "count"),
+		"Number of variables of the workspace, by whether they're sensitive",
+		[]string{"organization", "workspace", "sensitive"}, nil,
+	)
+	VariablesUnprotectedCredential = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, variablesSubsystem, "unprotected_credential"),
+		"Variable of the workspace named like a credential (e.g. AWS_SECRET_ACCESS_KEY or *_TOKEN) that isn't sensitive, always 1",
+		[]string{"organization", "workspace", "key"}, nil,
+	)
+)
+
+var (
+	// variablesWorkspacesFields are the only fields of the workspaces needed to list their variables.
+	variablesWorkspacesFields = setup.Fields{"workspaces": {"name"}}
+	// variablesFields are the only fields of the variables needed, their values are never requested.
+	variablesFields = setup.Fields{"vars": {"key", "sensitive"}}
+	// credentialKey matches the keys of the variables that usually hold credentials.
+	credentialKey = regexp.MustCompile(`(?i)(secret|token|password|passwd|private_?key|access_?key|api_?key|credential)`)
+)
+
+// ScrapeVariables scrapes whether the variables of the workspaces are sensitive.
+type ScrapeVariables struct{}
+
+func init() {
+	Register(ScrapeVariables{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeVariables) Name() string {
+	return variablesSubsystem
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeVariables) Help() string {
+	return "Scrape the variables of the workspaces, without their values, from the Variables API: https://www.terraform.io/cloud-docs/api-docs/workspace-variables"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeVariables) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeVariables) Describe(ch chan<- *prometheus.Desc) {
+	ch <- VariablesCount
+	ch <- VariablesUnprotectedCredential
+}
+
+func getWorkspaceVariables(ctx context.Context, organization string, w *tfe.Workspace, config *setup.Config, ch chan<- prometheus.Metric) error {
+	counts := map[bool]int{}
+	metrics := []prometheus.Metric{}
+	for page := 1; ; page++ {
+		var variablesList *tfe.VariableList
+		err := config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+			variablesList, err = config.Client.Variables.List(setup.WithFields(ctx, variablesFields), w.ID, &tfe.VariableListOptions{
+				ListOptions: tfe.ListOptions{
+					PageSize:   pageSize,
+					PageNumber: page,
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%w, (workspace=%s, page=%d)", err, w.Name, page)
+		}
+
+		for _, v := range variablesList.Items {
+			counts[v.Sensitive]++
+			if !v.Sensitive && credentialKey.MatchString(v.Key) {
+				metrics = append(metrics, prometheus.MustNewConstMetric(VariablesUnprotectedCredential, prometheus.GaugeValue, 1, organization, w.Name, v.Key))
+			}
+		}
+
+		if variablesList.Pagination == nil || page >= variablesList.Pagination.TotalPages || pageLimitReached(ctx, page) {
+			break
+		}
+	}
+
+	for _, sensitive := range []bool{true, false} {
+		metrics = append(metrics, prometheus.MustNewConstMetric(VariablesCount, prometheus.GaugeValue, float64(counts[sensitive]), organization, w.Name, strconv.FormatBool(sensitive)))
+	}
+	for _, m := range metrics {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func getVariablesPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (_ *tfe.WorkspaceList, err error) {
+	ctx, span := tracer.Start(ctx, "variables workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	var workspacesList *tfe.WorkspaceList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(setup.WithQuery(ctx, config.WorkspacesSearch()), variablesWorkspacesFields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return workspacesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, w := range workspacesList.Items {
+		w := w
+		g.Go(func() error {
+			return getWorkspaceVariables(ctx, organization, w, config, ch)
+		})
+	}
+
+	return workspacesList, g.Wait()
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeVariables) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			list, err := getVariablesPage(ctx, 1, name, config, ch)
+			if err != nil {
+				return err
+			}
+
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getVariablesPage(ctx, page, name, config, ch)
+				return err
+			})
+		})
+	}
+
+	return g.Wait()
+}
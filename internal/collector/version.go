@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseVersion parses the numeric segments of a version, e.g. [1 4 0] from 1.4.0, v1.4.0-rc1 or the ~> 1.4.0 constraint.
+// Constraints are parsed as their lower bound, and anything else (e.g. latest) isn't a version.
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimLeft(v, "~>=< v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+
+	segments := strings.Split(v, ".")
+	parsed := make([]int, len(segments))
+	for i, s := range segments {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, false
+		}
+		parsed[i] = n
+	}
+
+	return parsed, true
+}
+
+// compareVersions returns -1, 0 or 1 if the version a is older, the same or newer than b,
+// or false if any of them isn't a version. Missing segments are 0, so 1.4 is the same as 1.4.0.
+func compareVersions(a, b string) (int, bool) {
+	va, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	vb, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var sa, sb int
+		if i < len(va) {
+			sa = va[i]
+		}
+		if i < len(vb) {
+			sb = vb[i]
+		}
+		switch {
+		case sa < sb:
+			return -1, true
+		case sa > sb:
+			return 1, true
+		}
+	}
+
+	return 0, true
+}
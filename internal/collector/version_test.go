@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompareVersions(t *testing.T) {
+	convey.Convey("Versions are compared by their numeric segments", t, func() {
+		for _, c := range []struct {
+			a, b string
+			want int
+		}{
+			{"1.4.0", "1.4.0", 0},
+			{"1.4", "1.4.0", 0},
+			{"0.14.3", "1.4.0", -1},
+			{"1.10.0", "1.9.5", 1},
+			{"v1.4.0-rc1", "1.4.0", 0},
+			{"~> 1.3.0", "1.4.0", -1},
+		} {
+			got, ok := compareVersions(c.a, c.b)
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(got, convey.ShouldEqual, c.want)
+		}
+	})
+
+	convey.Convey("Anything else isn't a version", t, func() {
+		for _, v := range []string{"", "latest", "1.x"} {
+			_, ok := compareVersions(v, "1.4.0")
+			convey.So(ok, convey.ShouldBeFalse)
+		}
+	})
+}
@@ -3,6 +3,9 @@ package collector
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
 
 	"golang.org/x/sync/errgroup"
 
@@ -11,6 +14,9 @@ import (
 	tfe "github.com/hashicorp/go-tfe"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -30,13 +36,51 @@ var (
 		"Information about existing workspaces",
 		[]string{"id", "name", "organization", "terraform_version", "created_at", "environment", "current_run", "current_run_status", "current_run_created_at"}, nil,
 	)
+	WorkspacesSettingEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, workspacesSubsystem, "setting_enabled"),
+		"Whether the setting of the workspace (allow_destroy_plan, queue_all_runs or structured_run_output_enabled) is enabled (1 for enabled, 0 for disabled)",
+		[]string{"name", "organization", "setting"}, nil,
+	)
+	WorkspacesTerraformVersionOutdated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, workspacesSubsystem, "terraform_version_outdated"),
+		"Whether the Terraform version of the workspace is older than the expected one (1 for outdated, 0 otherwise)",
+		[]string{"name", "organization", "terraform_version", "expected_version"}, nil,
+	)
+	WorkspacesCurrentRunConfirmedBy = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, workspacesSubsystem, "current_run_confirmed_by"),
+		"Who confirmed the current run of the workspace (user, or the hash of the user when enabled, or auto_apply), for the confirmed runs, when enabled",
+		[]string{"name", "organization", "run", "actor"}, nil,
+	)
+	WorkspacesCurrentRunCommitInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, workspacesSubsystem, "current_run_commit_info"),
+		"Commit the configuration of the current run of the workspace was ingressed from (SHA, branch and pull request number, if any), for the runs of VCS-backed workspaces, when enabled",
+		[]string{"name", "organization", "run", "commit_sha", "branch", "pull_request"}, nil,
+	)
 )
 
+// workspacesFields are the only fields of the workspaces (and their current run) turned into metrics.
+var workspacesFields = setup.Fields{
+	"workspaces": {"name", "created-at", "environment", "terraform-version", "organization", "current-run", "allow-destroy-plan", "queue-all-runs", "structured-run-output-enabled"},
+	"runs":       {"status", "created-at"},
+}
+
+// workspacesConfirmationFields are the fields of the workspaces (and their current run) also needed to tell who confirmed the current run.
+var workspacesConfirmationFields = setup.Fields{
+	"runs": {"auto-apply", "status-timestamps"},
+}
+
+// workspacesCommitFields are the fields of the current run of the workspaces also needed to expose the commit of its configuration.
+var workspacesCommitFields = setup.Fields{
+	"runs":                   {"configuration-version"},
+	"configuration-versions": {"ingress-attributes"},
+	"ingress-attributes":     {"commit-sha", "branch", "pull-request-number"},
+}
+
 // ScrapeWorkspaces scrapes metrics about the workspaces.
 type ScrapeWorkspaces struct{}
 
 func init() {
-	Scrapers = append(Scrapers, ScrapeWorkspaces{})
+	Register(ScrapeWorkspaces{})
 }
 
 // Name of the Scraper. Should be unique.
@@ -54,68 +98,274 @@ func (ScrapeWorkspaces) Version() string {
 	return "v2"
 }
 
-func getWorkspacesListPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (*tfe.WorkspaceList, error) {
-	include := []tfe.WSIncludeOpt{"current_run"}
-	workspacesList, err := config.Client.Workspaces.List(ctx, organization, &tfe.WorkspaceListOptions{
-		ListOptions: tfe.ListOptions{
-			PageSize:   pageSize,
-			PageNumber: page,
-		},
-		Include: include,
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeWorkspaces) Describe(ch chan<- *prometheus.Desc) {
+	ch <- WorkspacesInfo
+	ch <- WorkspacesSettingEnabled
+	ch <- WorkspacesTerraformVersionOutdated
+	ch <- WorkspacesCurrentRunConfirmedBy
+	ch <- WorkspacesCurrentRunCommitInfo
+}
+
+// workspaceSetting is a boolean setting of a workspace.
+type workspaceSetting struct {
+	name    string
+	enabled bool
+}
+
+// workspaceSettings returns the settings of the workspace exposed.
+func workspaceSettings(w *tfe.Workspace) []workspaceSetting {
+	return []workspaceSetting{
+		{"allow_destroy_plan", w.AllowDestroyPlan},
+		{"queue_all_runs", w.QueueAllRuns},
+		{"structured_run_output_enabled", w.StructuredRunOutputEnabled},
+	}
+}
+
+// workspacesOptions returns the related resources to include and the fields to fetch of the workspaces.
+func workspacesOptions(config *setup.Config) ([]tfe.WSIncludeOpt, setup.Fields) {
+	include := []tfe.WSIncludeOpt{tfe.WSCurrentRun}
+	fields := workspacesFields
+	if config.RunConfirmationActor {
+		fields = fields.With(workspacesConfirmationFields)
+	}
+	if config.RunCommitInfo {
+		include = append(include, tfe.WSCurrentrunConfigVerIngress)
+		fields = fields.With(workspacesCommitFields)
+	}
+
+	return include, fields
+}
+
+func listWorkspacesPage(ctx context.Context, page int, organization string, config *setup.Config) (_ *tfe.WorkspaceList, err error) {
+	ctx, span := tracer.Start(ctx, "workspaces page", trace.WithAttributes(attribute.String("organization", organization), attribute.Int("page", page)))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	include, fields := workspacesOptions(config)
+	var workspacesList *tfe.WorkspaceList
+	err = config.Pool.Do(ctx, func(ctx context.Context) (err error) {
+		workspacesList, err = config.Client.Workspaces.List(setup.WithFields(setup.WithQuery(ctx, config.WorkspacesSearch()), fields), organization, &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{
+				PageSize:   pageSize,
+				PageNumber: page,
+			},
+			Include: include,
+		})
+		return err
 	})
 	if err != nil {
-		return workspacesList, fmt.Errorf("%v, (organization=%s, page=%d)", err, organization, page)
+		return workspacesList, fmt.Errorf("%w, (organization=%s, page=%d)", err, organization, page)
+	}
+
+	return workspacesList, nil
+}
+
+func getWorkspacesListPage(ctx context.Context, page int, organization string, config *setup.Config, ch chan<- prometheus.Metric) (*tfe.WorkspaceList, error) {
+	workspacesList, err := listWorkspacesPage(ctx, page, organization, config)
+	if err != nil {
+		return workspacesList, err
 	}
 
 	for _, w := range workspacesList.Items {
+		if err := sendWorkspace(ctx, w, config, ch); err != nil {
+			return workspacesList, err
+		}
+	}
+
+	return workspacesList, nil
+}
+
+// sendWorkspace sends the metrics of the workspace.
+func sendWorkspace(ctx context.Context, w *tfe.Workspace, config *setup.Config, ch chan<- prometheus.Metric) error {
+	select {
+	case ch <- prometheus.MustNewConstMetric(
+		WorkspacesInfo,
+		prometheus.GaugeValue,
+		1,
+		w.ID,
+		w.Name,
+		w.Organization.Name,
+		w.TerraformVersion,
+		w.CreatedAt.String(),
+		w.Environment,
+		getCurrentRunID(w.CurrentRun),
+		getCurrentRunStatus(w.CurrentRun),
+		getCurrentRunCreatedAt(w.CurrentRun),
+	):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for _, setting := range workspaceSettings(w) {
+		enabled := 0.0
+		if setting.enabled {
+			enabled = 1
+		}
 		select {
-		case ch <- prometheus.MustNewConstMetric(
-			WorkspacesInfo,
-			prometheus.GaugeValue,
-			1,
-			w.ID,
-			w.Name,
-			w.Organization.Name,
-			w.TerraformVersion,
-			w.CreatedAt.String(),
-			w.Environment,
-			getCurrentRunID(w.CurrentRun),
-			getCurrentRunStatus(w.CurrentRun),
-			getCurrentRunCreatedAt(w.CurrentRun),
-		):
+		case ch <- prometheus.MustNewConstMetric(WorkspacesSettingEnabled, prometheus.GaugeValue, enabled, w.Name, w.Organization.Name, setting.name):
 		case <-ctx.Done():
-			return workspacesList, ctx.Err()
+			return ctx.Err()
 		}
 	}
 
-	return workspacesList, nil
+	if actor := confirmationActor(w); config.RunConfirmationActor && actor != "" {
+		if actor == "user" && config.RunConfirmationActorHashed {
+			user, err := getConfirmedBy(ctx, w.CurrentRun.ID, config)
+			if err != nil {
+				return err
+			}
+			actor = hashLabelValue(user, config.LabelsHashKey)
+		}
+		select {
+		case ch <- prometheus.MustNewConstMetric(WorkspacesCurrentRunConfirmedBy, prometheus.GaugeValue, 1, w.Name, w.Organization.Name, w.CurrentRun.ID, actor):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if commit := currentRunCommit(w); config.RunCommitInfo && commit != nil {
+		pullRequest := ""
+		if commit.PullRequestNumber > 0 {
+			pullRequest = strconv.Itoa(commit.PullRequestNumber)
+		}
+		select {
+		case ch <- prometheus.MustNewConstMetric(WorkspacesCurrentRunCommitInfo, prometheus.GaugeValue, 1, w.Name, w.Organization.Name, w.CurrentRun.ID, commit.CommitSHA, commit.Branch, pullRequest):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if config.ExpectedTerraformVersion == "" {
+		return nil
+	}
+	// Versions which can't be compared (e.g. latest) are never outdated.
+	outdated := 0.0
+	if cmp, ok := compareVersions(w.TerraformVersion, config.ExpectedTerraformVersion); ok && cmp < 0 {
+		outdated = 1
+	}
+	select {
+	case ch <- prometheus.MustNewConstMetric(WorkspacesTerraformVersionOutdated, prometheus.GaugeValue, outdated, w.Name, w.Organization.Name, w.TerraformVersion, config.ExpectedTerraformVersion):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
 }
 
 // Scrape collects data from Terraform API and sends it over channel as prometheus metric.
 func (ScrapeWorkspaces) Scrape(ctx context.Context, config *setup.Config, ch chan<- prometheus.Metric) error {
-	g, ctx := errgroup.WithContext(ctx)
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
 	for _, name := range config.Organizations {
 		name := name
-		g.Go(func() error {
+		g.Go(func() (err error) {
+			ctx, span := tracer.Start(ctx, "organization", trace.WithAttributes(attribute.String("organization", name)))
+			defer func() {
+				recordError(span, err)
+				span.End()
+			}()
+
+			if config.WorkspacesFullRefreshInterval > 0 {
+				return scrapeWorkspacesInventory(ctx, name, config, ch)
+			}
+
 			list, err := getWorkspacesListPage(ctx, 1, name, config, ch)
 			if err != nil {
 				return err
 			}
 
-			for list.Pagination.NextPage != 0 {
-				list, err = getWorkspacesListPage(ctx, list.Pagination.NextPage, name, config, ch)
-				if err != nil {
-					return err
-				}
-			}
-
-			return nil
+			return fetchRemainingPages(ctx, list.Pagination.TotalPages, func(ctx context.Context, page int) error {
+				_, err := getWorkspacesListPage(ctx, page, name, config, ch)
+				return err
+			})
 		})
 	}
 
 	return g.Wait()
 }
 
+// confirmationActor returns who confirmed the current run of the workspace: auto_apply or user,
+// or an empty string if it wasn't confirmed (yet). Only the run itself tells whether it auto-applied,
+// the setting of the workspace may have changed since.
+func confirmationActor(w *tfe.Workspace) string {
+	r := w.CurrentRun
+	// Auto-applied runs may go straight to applying.
+	if r == nil || r.StatusTimestamps == nil || (r.StatusTimestamps.ConfirmedAt.IsZero() && r.StatusTimestamps.ApplyingAt.IsZero()) {
+		return ""
+	}
+	if r.AutoApply {
+		return "auto_apply"
+	}
+
+	return "user"
+}
+
+// confirmedByRun is a run with the user who confirmed it, a relationship the go-tfe client doesn't decode.
+type confirmedByRun struct {
+	Data struct {
+		Relationships struct {
+			ConfirmedBy struct {
+				Data *struct {
+					ID string `json:"id"`
+				} `json:"data"`
+			} `json:"confirmed-by"`
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+// confirmers keeps the users who confirmed the runs, which never change, so they're only fetched once per run.
+var confirmers = struct {
+	sync.Mutex
+	m map[string]string
+}{m: map[string]string{}}
+
+// maxConfirmers bounds the runs kept in confirmers, which is emptied once it's full.
+const maxConfirmers = 10000
+
+// getConfirmedBy returns the ID of the user who confirmed the run.
+func getConfirmedBy(ctx context.Context, run string, config *setup.Config) (string, error) {
+	confirmers.Lock()
+	user, ok := confirmers.m[run]
+	confirmers.Unlock()
+	if ok {
+		return user, nil
+	}
+
+	var r confirmedByRun
+	err := config.Pool.Do(ctx, func(ctx context.Context) error {
+		return config.API.GetJSON(ctx, "runs/"+url.PathEscape(run)+"?fields%5Bruns%5D=confirmed-by", &r)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w, (run=%s)", err, run)
+	}
+	// Users deleted since are exposed like the unknown ones.
+	if r.Data.Relationships.ConfirmedBy.Data != nil {
+		user = r.Data.Relationships.ConfirmedBy.Data.ID
+	}
+
+	confirmers.Lock()
+	defer confirmers.Unlock()
+	if len(confirmers.m) >= maxConfirmers {
+		confirmers.m = map[string]string{}
+	}
+	confirmers.m[run] = user
+	return user, nil
+}
+
+// currentRunCommit returns the commit the configuration of the current run of the workspace was ingressed from,
+// or nil if it wasn't ingressed from a VCS (e.g. uploaded through the API).
+func currentRunCommit(w *tfe.Workspace) *tfe.IngressAttributes {
+	r := w.CurrentRun
+	if r == nil || r.ConfigurationVersion == nil || r.ConfigurationVersion.IngressAttributes == nil || r.ConfigurationVersion.IngressAttributes.CommitSHA == "" {
+		return nil
+	}
+
+	return r.ConfigurationVersion.IngressAttributes
+}
+
 func getCurrentRunID(r *tfe.Run) string {
 	if r == nil {
 		return "na"
@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
 
@@ -31,6 +33,9 @@ func TestScrapeWorkspaces(t *testing.T) {
 					"created-at":"1010-10-10T10:10:10.101Z",
 					"environment":"test-environment",
 					"terraform-version":"0.14.3",
+					"allow-destroy-plan":true,
+					"queue-all-runs":false,
+					"structured-run-output-enabled":true,
 					"latest-change-at":"2020-10-10T10:10:10.101Z"
 				},
 				"relationships":{
@@ -74,7 +79,7 @@ func TestScrapeWorkspaces(t *testing.T) {
 
 	config := &setup.Config{
 		Client: *client,
-		CLI:    setup.CLI{Organizations: []string{"test-org"}},
+		CLI:    setup.CLI{Organizations: []string{"test-org"}, ExpectedTerraformVersion: "0.14.3"},
 	}
 
 	ch := make(chan prometheus.Metric)
@@ -87,7 +92,15 @@ func TestScrapeWorkspaces(t *testing.T) {
 
 	counterExpected := []MetricResult{
 		{labels: labelMap{"created_at": "1010-10-10 10:10:10.101 +0000 UTC", "current_run": "run-id-1", "current_run_status": "applied", "current_run_created_at": "1010-10-10 10:10:10.101 +0000 UTC", "environment": "test-environment", "id": "test-id-1", "name": "dev", "organization": "test-org", "terraform_version": "0.14.3"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "dev", "organization": "test-org", "setting": "allow_destroy_plan"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "dev", "organization": "test-org", "setting": "queue_all_runs"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "dev", "organization": "test-org", "setting": "structured_run_output_enabled"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "dev", "organization": "test-org", "terraform_version": "0.14.3", "expected_version": "0.14.3"}, value: 0, metricType: dto.MetricType_GAUGE},
 		{labels: labelMap{"created_at": "1010-10-10 10:10:10.101 +0000 UTC", "current_run": "na", "current_run_status": "na", "current_run_created_at": "na", "environment": "test-environment", "id": "test-id-2", "name": "stg", "organization": "test-org", "terraform_version": "0.14.2"}, value: 1, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "stg", "organization": "test-org", "setting": "allow_destroy_plan"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "stg", "organization": "test-org", "setting": "queue_all_runs"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "stg", "organization": "test-org", "setting": "structured_run_output_enabled"}, value: 0, metricType: dto.MetricType_GAUGE},
+		{labels: labelMap{"name": "stg", "organization": "test-org", "terraform_version": "0.14.2", "expected_version": "0.14.3"}, value: 1, metricType: dto.MetricType_GAUGE},
 	}
 	convey.Convey("Metrics comparison", t, func() {
 		for _, expect := range counterExpected {
@@ -96,3 +109,177 @@ func TestScrapeWorkspaces(t *testing.T) {
 		}
 	})
 }
+
+func TestConfirmationActor(t *testing.T) {
+	confirmed := &tfe.RunStatusTimestamps{ConfirmedAt: time.Now()}
+
+	convey.Convey("Who confirmed the current run", t, func() {
+		convey.So(confirmationActor(&tfe.Workspace{}), convey.ShouldEqual, "")
+		convey.So(confirmationActor(&tfe.Workspace{CurrentRun: &tfe.Run{StatusTimestamps: &tfe.RunStatusTimestamps{}}}), convey.ShouldEqual, "")
+		convey.So(confirmationActor(&tfe.Workspace{CurrentRun: &tfe.Run{StatusTimestamps: confirmed}}), convey.ShouldEqual, "user")
+		convey.So(confirmationActor(&tfe.Workspace{CurrentRun: &tfe.Run{StatusTimestamps: confirmed, AutoApply: true}}), convey.ShouldEqual, "auto_apply")
+		convey.So(confirmationActor(&tfe.Workspace{CurrentRun: &tfe.Run{StatusTimestamps: &tfe.RunStatusTimestamps{ApplyingAt: time.Now()}, AutoApply: true}}), convey.ShouldEqual, "auto_apply")
+		// The setting of the workspace may have changed since the run.
+		convey.So(confirmationActor(&tfe.Workspace{AutoApply: true, CurrentRun: &tfe.Run{StatusTimestamps: confirmed}}), convey.ShouldEqual, "user")
+	})
+}
+
+func TestScrapeWorkspacesCommitInfo(t *testing.T) {
+	var query url.Values
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"meta":{
+				"pagination":{"current-page":1,"prev-page":null,"next-page":null,"total-pages":1,"total-count":2}
+			},
+			"data":[{
+				"id":"test-id-1",
+				"type":"workspaces",
+				"attributes":{"name":"dev"},
+				"relationships":{
+					"organization":{"data":{"id":"test-org","type":"organizations"}},
+					"current-run":{"data":{"id":"run-id-1","type":"runs"}}
+				}
+			}, {
+				"id":"test-id-2",
+				"type":"workspaces",
+				"attributes":{"name":"stg"},
+				"relationships":{
+					"organization":{"data":{"id":"test-org","type":"organizations"}},
+					"current-run":{"data":{"id":"run-id-2","type":"runs"}}
+				}
+			}],
+			"included":[{
+				"id":"run-id-1",
+				"type":"runs",
+				"attributes":{"status":"applied"},
+				"relationships":{"configuration-version":{"data":{"id":"cv-1","type":"configuration-versions"}}}
+			}, {
+				"id":"cv-1",
+				"type":"configuration-versions",
+				"relationships":{"ingress-attributes":{"data":{"id":"ia-1","type":"ingress-attributes"}}}
+			}, {
+				"id":"ia-1",
+				"type":"ingress-attributes",
+				"attributes":{"commit-sha":"abc123","branch":"feature","pull-request-number":42}
+			}, {
+				"id":"run-id-2",
+				"type":"runs",
+				"attributes":{"status":"applied"},
+				"relationships":{"configuration-version":{"data":{"id":"cv-2","type":"configuration-versions"}}}
+			}, {
+				"id":"cv-2",
+				"type":"configuration-versions",
+				"relationships":{"ingress-attributes":{"data":null}}
+			}]
+		}`))
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client: *client,
+		CLI:    setup.CLI{Organizations: []string{"test-org"}, RunCommitInfo: true},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeWorkspaces{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	convey.Convey("Commit of the current run, for the configurations ingressed from a VCS", t, func() {
+		commits := []MetricResult{}
+		for m := range ch {
+			if m.Desc() == WorkspacesCurrentRunCommitInfo {
+				commits = append(commits, readMetric(m))
+			}
+		}
+		convey.So(commits, convey.ShouldResemble, []MetricResult{
+			{labels: labelMap{"name": "dev", "organization": "test-org", "run": "run-id-1", "commit_sha": "abc123", "branch": "feature", "pull_request": "42"}, value: 1, metricType: dto.MetricType_GAUGE},
+		})
+		convey.So(query.Get("include"), convey.ShouldEqual, "current_run,current_run.configuration_version.ingress_attributes")
+	})
+}
+
+func TestScrapeWorkspacesConfirmationActorHashed(t *testing.T) {
+	var fields string
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/api/v2/organizations/test-org/workspaces":
+			w.Write([]byte(`{
+				"meta":{"pagination":{"current-page":1,"total-pages":1,"total-count":1}},
+				"data":[{
+					"id":"test-id-1",
+					"type":"workspaces",
+					"attributes":{"name":"dev"},
+					"relationships":{
+						"organization":{"data":{"id":"test-org","type":"organizations"}},
+						"current-run":{"data":{"id":"run-confirmed","type":"runs"}}
+					}
+				}],
+				"included":[{
+					"id":"run-confirmed",
+					"type":"runs",
+					"attributes":{"status":"applied","auto-apply":false,"status-timestamps":{"confirmed-at":"2020-10-10T10:10:10Z"}}
+				}]
+			}`))
+		case "/api/v2/runs/run-confirmed":
+			fields = r.URL.Query().Get("fields[runs]")
+			w.Write([]byte(`{"data":{"id":"run-confirmed","type":"runs","relationships":{"confirmed-by":{"data":{"id":"user-1","type":"users"}}}}}`))
+		case "/api/v2/ping":
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer mockAPI.Close()
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address: mockAPI.URL,
+		Token:   "test",
+	})
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+	api, err := setup.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &setup.Config{
+		Client:        *client,
+		API:           api,
+		CLI:           setup.CLI{Organizations: []string{"test-org"}, RunConfirmationActor: true, RunConfirmationActorHashed: true},
+		LabelsHashKey: []byte("key"),
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeWorkspaces{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	convey.Convey("The user who confirmed the current run is exposed hashed", t, func() {
+		actors := []string{}
+		for m := range ch {
+			if m.Desc() == WorkspacesCurrentRunConfirmedBy {
+				actors = append(actors, readMetric(m).labels["actor"])
+			}
+		}
+		convey.So(actors, convey.ShouldResemble, []string{hashLabelValue("user-1", []byte("key"))})
+		convey.So(fields, convey.ShouldEqual, "confirmed-by")
+	})
+}
@@ -0,0 +1,131 @@
+// Package health implements the liveness and readiness checks of the exporter.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// checkTTL is how long the result of a readiness check is reused,
+	// so frequent probes don't add load to the Terraform API.
+	checkTTL = 30 * time.Second
+	// checkTimeout limits how long a readiness check can wait for the Terraform API.
+	checkTimeout = 10 * time.Second
+)
+
+// Checker verifies the connectivity to the Terraform API and the validity of the API token, of every instance scraped.
+type Checker struct {
+	configs []setup.Config
+
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+	// checking is closed once the check in progress finishes, nil if none is.
+	checking chan struct{}
+}
+
+// NewChecker returns a new Checker for the provided Config, checking the instances of --instance along with it.
+func NewChecker(config setup.Config) *Checker {
+	configs := config.Instances
+	if len(configs) == 0 {
+		configs = []setup.Config{config}
+	}
+
+	return &Checker{configs: configs}
+}
+
+// Check returns the result of the last readiness check, refreshing it if it's older than checkTTL.
+// Concurrent calls share the same check, which isn't bound to the context of any of them.
+func (c *Checker) Check(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.checked.IsZero() && time.Since(c.checked) < checkTTL {
+		defer c.mu.Unlock()
+		return c.err
+	}
+	checking := c.checking
+	if checking == nil {
+		checking = make(chan struct{})
+		c.checking = checking
+		go c.check(checking)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-checking:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// check checks every instance concurrently, keeping the result, and then closes done.
+// The error reports every instance that failed.
+func (c *Checker) check(done chan struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	errs := make([]error, len(c.configs))
+	var wg sync.WaitGroup
+	for i := range c.configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Reading the current user requires both connectivity and a valid token.
+			_, errs[i] = c.configs[i].Client.Users.ReadCurrent(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := []string{}
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		config := c.configs[i]
+		level.Warn(config.Logger).Log("msg", "Readiness check failed", "err", err, "instance", config.Instance)
+		if config.Instance != "" {
+			err = fmt.Errorf("instance %s: %w", config.Instance, err)
+		}
+		failed = append(failed, err.Error())
+	}
+
+	var err error
+	if len(failed) > 0 {
+		err = errors.New(strings.Join(failed, "; "))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err, c.checked, c.checking = err, time.Now(), nil
+}
+
+// LivenessHandler reports the exporter is alive as long as it can serve http requests.
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+// ReadinessHandler reports whether the exporter is able to scrape the Terraform API.
+func (c *Checker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if err := c.Check(r.Context()); err != nil {
+		http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	_, _ = w.Write([]byte("ok"))
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/setup"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	var requests int32
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/account/details" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"id":"user-1","type":"users","attributes":{"username":"test"}}}`))
+	}))
+	defer mockAPI.Close()
+
+	newConfig := func(token string) setup.Config {
+		client, err := tfe.NewClient(&tfe.Config{
+			Address: mockAPI.URL,
+			Token:   token,
+		})
+		if err != nil {
+			t.Fatalf("error creating a stub api client: %s", err)
+		}
+
+		return setup.Config{Client: *client, Logger: log.NewNopLogger()}
+	}
+	newChecker := func(token string) *Checker {
+		return NewChecker(newConfig(token))
+	}
+
+	convey.Convey("Readiness", t, func() {
+		atomic.StoreInt32(&requests, 0)
+
+		convey.Convey("succeeds with a valid token and caches the result", func() {
+			checker := newChecker("valid")
+			for i := 0; i < 2; i++ {
+				rec := httptest.NewRecorder()
+				checker.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+				convey.So(rec.Code, convey.ShouldEqual, http.StatusOK)
+			}
+			convey.So(atomic.LoadInt32(&requests), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("fails with an invalid token", func() {
+			rec := httptest.NewRecorder()
+			newChecker("invalid").ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			convey.So(rec.Code, convey.ShouldEqual, http.StatusServiceUnavailable)
+		})
+
+		convey.Convey("checks every instance, reporting the ones that failed", func() {
+			config := newConfig("valid")
+			valid, invalid := newConfig("valid"), newConfig("invalid")
+			valid.Instance, invalid.Instance = "default", "tfe1"
+			config.Instances = []setup.Config{valid, invalid}
+
+			rec := httptest.NewRecorder()
+			NewChecker(config).ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			convey.So(rec.Code, convey.ShouldEqual, http.StatusServiceUnavailable)
+			convey.So(rec.Body.String(), convey.ShouldContainSubstring, "instance tfe1")
+			convey.So(rec.Body.String(), convey.ShouldNotContainSubstring, "instance default")
+			convey.So(atomic.LoadInt32(&requests), convey.ShouldEqual, 2)
+		})
+	})
+}
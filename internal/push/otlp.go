@@ -0,0 +1,229 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLP pushes the metrics to an OpenTelemetry collector using OTLP/HTTP.
+type OTLP struct {
+	url    string
+	client *http.Client
+	// start is reported as the start time of cumulative metrics (counters, histograms and summaries).
+	start time.Time
+}
+
+// NewOTLP returns a new OTLP pusher for the given host:port endpoint.
+func NewOTLP(endpoint string, insecure bool) *OTLP {
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+
+	return &OTLP{
+		url:    scheme + "://" + endpoint + "/v1/metrics",
+		client: &http.Client{},
+		start:  time.Now(),
+	}
+}
+
+// Name of the Pusher. Should be unique.
+func (*OTLP) Name() string {
+	return "otlp"
+}
+
+// Push sends the gathered metric families to the remote system.
+func (o *OTLP) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	body, err := proto.Marshal(o.request(families, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, msg)
+	}
+
+	return nil
+}
+
+// request converts the Prometheus metric families into an OTLP export request.
+func (o *OTLP) request(families []*dto.MetricFamily, now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	for _, mf := range families {
+		m := &metricspb.Metric{
+			Name:        mf.GetName(),
+			Description: mf.GetHelp(),
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints:             o.numberDataPoints(mf, now),
+			}}
+		case dto.MetricType_HISTOGRAM:
+			m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints:             o.histogramDataPoints(mf, now),
+			}}
+		case dto.MetricType_SUMMARY:
+			m.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+				DataPoints: o.summaryDataPoints(mf, now),
+			}}
+		default:
+			// Gauges and untyped metrics.
+			m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+				DataPoints: o.numberDataPoints(mf, now),
+			}}
+		}
+
+		metrics = append(metrics, m)
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{stringAttribute("service.name", "tf_exporter")},
+			},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: "github.com/kaizendorks/terraform-cloud-exporter"},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+func (o *OTLP) numberDataPoints(mf *dto.MetricFamily, now time.Time) []*metricspb.NumberDataPoint {
+	points := make([]*metricspb.NumberDataPoint, 0, len(mf.Metric))
+	for _, m := range mf.Metric {
+		var value float64
+		switch {
+		case m.Counter != nil:
+			value = m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			value = m.GetGauge().GetValue()
+		default:
+			value = m.GetUntyped().GetValue()
+		}
+
+		point := &metricspb.NumberDataPoint{
+			Attributes:   attributes(m),
+			TimeUnixNano: timestamp(m, now),
+			Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+		}
+		if m.Counter != nil {
+			point.StartTimeUnixNano = uint64(o.start.UnixNano())
+		}
+		points = append(points, point)
+	}
+
+	return points
+}
+
+func (o *OTLP) histogramDataPoints(mf *dto.MetricFamily, now time.Time) []*metricspb.HistogramDataPoint {
+	points := make([]*metricspb.HistogramDataPoint, 0, len(mf.Metric))
+	for _, m := range mf.Metric {
+		h := m.GetHistogram()
+		sum := h.GetSampleSum()
+
+		// Prometheus buckets are cumulative, while OTLP expects the count of each bucket.
+		var bounds []float64
+		var counts []uint64
+		var previous uint64
+		for _, b := range h.GetBucket() {
+			if math.IsInf(b.GetUpperBound(), 1) {
+				continue
+			}
+			bounds = append(bounds, b.GetUpperBound())
+			counts = append(counts, b.GetCumulativeCount()-previous)
+			previous = b.GetCumulativeCount()
+		}
+		counts = append(counts, h.GetSampleCount()-previous)
+
+		points = append(points, &metricspb.HistogramDataPoint{
+			Attributes:        attributes(m),
+			StartTimeUnixNano: uint64(o.start.UnixNano()),
+			TimeUnixNano:      timestamp(m, now),
+			Count:             h.GetSampleCount(),
+			Sum:               &sum,
+			BucketCounts:      counts,
+			ExplicitBounds:    bounds,
+		})
+	}
+
+	return points
+}
+
+func (o *OTLP) summaryDataPoints(mf *dto.MetricFamily, now time.Time) []*metricspb.SummaryDataPoint {
+	points := make([]*metricspb.SummaryDataPoint, 0, len(mf.Metric))
+	for _, m := range mf.Metric {
+		s := m.GetSummary()
+		quantiles := make([]*metricspb.SummaryDataPoint_ValueAtQuantile, 0, len(s.GetQuantile()))
+		for _, q := range s.GetQuantile() {
+			quantiles = append(quantiles, &metricspb.SummaryDataPoint_ValueAtQuantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+		}
+
+		points = append(points, &metricspb.SummaryDataPoint{
+			Attributes:        attributes(m),
+			StartTimeUnixNano: uint64(o.start.UnixNano()),
+			TimeUnixNano:      timestamp(m, now),
+			Count:             s.GetSampleCount(),
+			Sum:               s.GetSampleSum(),
+			QuantileValues:    quantiles,
+		})
+	}
+
+	return points
+}
+
+func attributes(m *dto.Metric) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(m.Label))
+	for _, l := range m.Label {
+		attrs = append(attrs, stringAttribute(l.GetName(), l.GetValue()))
+	}
+
+	return attrs
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func timestamp(m *dto.Metric, now time.Time) uint64 {
+	if m.TimestampMs != nil {
+		return uint64(m.GetTimestampMs()) * uint64(time.Millisecond)
+	}
+
+	return uint64(now.UnixNano())
+}
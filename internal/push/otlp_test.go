@@ -0,0 +1,81 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestOTLPPush(t *testing.T) {
+	received := &colmetricspb.ExportMetricsServiceRequest{}
+	mockCollector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockCollector.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge", Help: "Test gauge"}, []string{"organization"})
+	gauge.WithLabelValues("test-org").Set(2)
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter"})
+	counter.Add(3)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Help: "Test histogram", Buckets: []float64{1, 5}})
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	histogram.Observe(10)
+	registry.MustRegister(gauge, counter, histogram)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error gathering test metrics: %s", err)
+	}
+
+	pusher := NewOTLP(strings.TrimPrefix(mockCollector.URL, "http://"), true)
+	if err := pusher.Push(context.Background(), families); err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	convey.Convey("Metrics conversion", t, func() {
+		metrics := received.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+		convey.So(len(metrics), convey.ShouldEqual, 3)
+
+		byName := map[string]*metricspb.Metric{}
+		for _, m := range metrics {
+			byName[m.GetName()] = m
+		}
+
+		g := byName["test_gauge"].GetGauge().GetDataPoints()[0]
+		convey.So(g.GetAsDouble(), convey.ShouldEqual, 2)
+		convey.So(g.GetAttributes()[0].GetKey(), convey.ShouldEqual, "organization")
+		convey.So(g.GetAttributes()[0].GetValue().GetStringValue(), convey.ShouldEqual, "test-org")
+
+		c := byName["test_total"].GetSum()
+		convey.So(c.GetIsMonotonic(), convey.ShouldBeTrue)
+		convey.So(c.GetDataPoints()[0].GetAsDouble(), convey.ShouldEqual, 3)
+
+		h := byName["test_seconds"].GetHistogram().GetDataPoints()[0]
+		convey.So(h.GetCount(), convey.ShouldEqual, 3)
+		convey.So(h.GetExplicitBounds(), convey.ShouldResemble, []float64{1, 5})
+		convey.So(h.GetBucketCounts(), convey.ShouldResemble, []uint64{1, 1, 1})
+	})
+}
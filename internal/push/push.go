@@ -0,0 +1,59 @@
+// Package push periodically sends the exporter metrics to remote systems,
+// for environments where the exporter can't be scraped.
+package push
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Pusher is minimal interface that let's you send the exporter metrics to a remote system.
+type Pusher interface {
+	// Name of the Pusher. Should be unique.
+	Name() string
+
+	// Push sends the gathered metric families to the remote system.
+	Push(ctx context.Context, families []*dto.MetricFamily) error
+}
+
+// Run gathers the metrics on every interval and sends them with all pushers, until the context is cancelled.
+// Each gathering and push is given at most one interval to finish.
+func Run(ctx context.Context, interval time.Duration, newGatherer func(context.Context) prometheus.Gatherer, pushers []Pusher, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		push(ctx, interval, newGatherer, pushers, logger)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func push(ctx context.Context, timeout time.Duration, newGatherer func(context.Context) prometheus.Gatherer, pushers []Pusher, logger log.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Gathering returns the metrics that could be collected, even if some failed.
+	families, err := newGatherer(ctx).Gather()
+	if err != nil {
+		level.Warn(logger).Log("msg", "Error gathering metrics to push", "err", err)
+	}
+
+	for _, pusher := range pushers {
+		if err := pusher.Push(ctx, families); err != nil {
+			level.Error(logger).Log("msg", "Error pushing metrics", "pusher", pusher.Name(), "err", err)
+			continue
+		}
+		level.Debug(logger).Log("msg", "Pushed metrics", "pusher", pusher.Name(), "families", len(families))
+	}
+}
@@ -0,0 +1,44 @@
+package push
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prompush "github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// pushgatewayJob is the job label of the metrics pushed to the Pushgateway.
+const pushgatewayJob = "tf_exporter"
+
+// Pushgateway pushes the metrics to a Prometheus Pushgateway,
+// replacing all the metrics previously pushed to the same group.
+type Pushgateway struct {
+	url      string
+	grouping map[string]string
+}
+
+// NewPushgateway returns a new Pushgateway pusher for the given url and grouping labels.
+func NewPushgateway(url string, grouping map[string]string) *Pushgateway {
+	return &Pushgateway{
+		url:      url,
+		grouping: grouping,
+	}
+}
+
+// Name of the Pusher. Should be unique.
+func (*Pushgateway) Name() string {
+	return "pushgateway"
+}
+
+// Push sends the gathered metric families to the remote system.
+func (p *Pushgateway) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	pusher := prompush.New(p.url, pushgatewayJob).Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return families, nil
+	}))
+	for name, value := range p.grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	return pusher.PushContext(ctx)
+}
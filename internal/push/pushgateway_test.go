@@ -0,0 +1,44 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestPushgatewayPush(t *testing.T) {
+	var method, path, body string
+	mockGateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockGateway.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "Test gauge"})
+	gauge.Set(1)
+	registry.MustRegister(gauge)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error gathering test metrics: %s", err)
+	}
+
+	pusher := NewPushgateway(mockGateway.URL, map[string]string{"environment": "test"})
+	if err := pusher.Push(context.Background(), families); err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	convey.Convey("Pushed group", t, func() {
+		convey.So(method, convey.ShouldEqual, http.MethodPut)
+		convey.So(path, convey.ShouldEqual, "/metrics/job/tf_exporter/environment/test")
+		convey.So(body, convey.ShouldNotBeEmpty)
+	})
+}
@@ -0,0 +1,206 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/config"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteConfig configures the Prometheus remote write endpoint and its authentication.
+type RemoteWriteConfig struct {
+	URL                string
+	BearerToken        string
+	BearerTokenFile    string
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// RemoteWrite pushes the metrics using the Prometheus remote write protocol (v1),
+// e.g. to Prometheus, Grafana Mimir or Grafana Cloud.
+type RemoteWrite struct {
+	url    string
+	client *http.Client
+}
+
+// NewRemoteWrite returns a new RemoteWrite pusher for the given config.
+func NewRemoteWrite(c RemoteWriteConfig) (*RemoteWrite, error) {
+	httpConfig := config.HTTPClientConfig{
+		TLSConfig: config.TLSConfig{
+			CAFile:             c.CAFile,
+			CertFile:           c.CertFile,
+			KeyFile:            c.KeyFile,
+			InsecureSkipVerify: c.InsecureSkipVerify,
+		},
+	}
+	if c.BearerToken != "" || c.BearerTokenFile != "" {
+		httpConfig.Authorization = &config.Authorization{
+			Type:            "Bearer",
+			Credentials:     config.Secret(c.BearerToken),
+			CredentialsFile: c.BearerTokenFile,
+		}
+	}
+	if err := httpConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := config.NewClientFromConfig(httpConfig, "remote_write")
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteWrite{
+		url:    c.URL,
+		client: client,
+	}, nil
+}
+
+// Name of the Pusher. Should be unique.
+func (*RemoteWrite) Name() string {
+	return "remote_write"
+}
+
+// Push sends the gathered metric families to the remote system.
+func (rw *RemoteWrite) Push(ctx context.Context, families []*dto.MetricFamily) error {
+	body := snappy.Encode(nil, encodeWriteRequest(toSeries(families, time.Now())))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rw.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := rw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, msg)
+	}
+
+	return nil
+}
+
+type label struct {
+	name, value string
+}
+
+type series struct {
+	labels    []label
+	value     float64
+	timestamp int64
+}
+
+// toSeries flattens the metric families into samples, following the Prometheus naming
+// of the series for histograms (_bucket, _sum, _count) and summaries (_sum, _count).
+func toSeries(families []*dto.MetricFamily, now time.Time) []series {
+	var result []series
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.Metric {
+			ts := now.UnixMilli()
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+
+			add := func(name string, value float64, extra ...label) {
+				labels := []label{{name: "__name__", value: name}}
+				for _, l := range m.Label {
+					labels = append(labels, label{name: l.GetName(), value: l.GetValue()})
+				}
+				labels = append(labels, extra...)
+				sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+				result = append(result, series{labels: labels, value: value, timestamp: ts})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						continue
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), label{name: "le", value: formatFloat(b.GetUpperBound())})
+				}
+				add(name+"_bucket", float64(h.GetSampleCount()), label{name: "le", value: "+Inf"})
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), label{name: "quantile", value: formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			default:
+				add(name, m.GetUntyped().GetValue())
+			}
+		}
+	}
+
+	return result
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest protobuf message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(ss []series) []byte {
+	var req []byte
+	for _, s := range ss {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+
+	return req
+}
@@ -0,0 +1,106 @@
+package push
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// decodeWriteRequest returns the value of every series in the request, keyed by its labels: name{label="value",...}
+func decodeWriteRequest(t *testing.T, b []byte) map[string]float64 {
+	result := map[string]float64{}
+	for len(b) > 0 {
+		_, _, n := protowire.ConsumeTag(b)
+		ts, m := protowire.ConsumeBytes(b[n:])
+		b = b[n+m:]
+
+		labels := []string{}
+		var value float64
+		for len(ts) > 0 {
+			num, _, n := protowire.ConsumeTag(ts)
+			field, m := protowire.ConsumeBytes(ts[n:])
+			ts = ts[n+m:]
+
+			if num == 1 {
+				_, _, n := protowire.ConsumeTag(field)
+				name, m := protowire.ConsumeString(field[n:])
+				_, _, o := protowire.ConsumeTag(field[n+m:])
+				val, _ := protowire.ConsumeString(field[n+m+o:])
+				labels = append(labels, name+"="+val)
+			} else {
+				_, _, n := protowire.ConsumeTag(field)
+				bits, _ := protowire.ConsumeFixed64(field[n:])
+				value = math.Float64frombits(bits)
+			}
+		}
+		sort.Strings(labels)
+		result[strings.Join(labels, ",")] = value
+	}
+	if len(result) == 0 {
+		t.Fatal("empty write request")
+	}
+
+	return result
+}
+
+func TestRemoteWritePush(t *testing.T) {
+	var auth string
+	var received map[string]float64
+	mockReceiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		compressed, _ := io.ReadAll(r.Body)
+		body, err := snappy.Decode(nil, compressed)
+		if err != nil || r.Header.Get("Content-Encoding") != "snappy" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = decodeWriteRequest(t, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer mockReceiver.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge", Help: "Test gauge"}, []string{"organization"})
+	gauge.WithLabelValues("test-org").Set(2)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Help: "Test histogram", Buckets: []float64{1}})
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	registry.MustRegister(gauge, histogram)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error gathering test metrics: %s", err)
+	}
+
+	pusher, err := NewRemoteWrite(RemoteWriteConfig{URL: mockReceiver.URL, BearerToken: "test-token"})
+	if err != nil {
+		t.Fatalf("error creating remote write client: %s", err)
+	}
+	if err := pusher.Push(context.Background(), families); err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	convey.Convey("Written series", t, func() {
+		convey.So(auth, convey.ShouldEqual, "Bearer test-token")
+		convey.So(received, convey.ShouldResemble, map[string]float64{
+			"__name__=test_gauge,organization=test-org": 2,
+			"__name__=test_seconds_bucket,le=1":         1,
+			"__name__=test_seconds_bucket,le=+Inf":      2,
+			"__name__=test_seconds_sum":                 3.5,
+			"__name__=test_seconds_count":               2,
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package setup
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Headers sent by the API with the version of the instance.
+const (
+	headerAPIVersion = "TFP-API-Version"
+	headerAppName    = "TFP-AppName"
+	headerTFEVersion = "X-TFE-Version"
+)
+
+// APIInfo describes the Terraform Cloud/Enterprise instance, as reported by the headers of its responses.
+// It is safe for concurrent use.
+type APIInfo struct {
+	mu         sync.RWMutex
+	apiVersion string
+	appName    string
+	tfeVersion string
+}
+
+// Get returns the API version, application name and Terraform Enterprise release of the instance.
+// Values not reported by the instance (e.g. the release on Terraform Cloud) are empty.
+func (i *APIInfo) Get() (apiVersion, appName, tfeVersion string) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.apiVersion, i.appName, i.tfeVersion
+}
+
+func (i *APIInfo) update(h http.Header) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if v := h.Get(headerAPIVersion); v != "" {
+		i.apiVersion = v
+	}
+	if v := h.Get(headerAppName); v != "" {
+		i.appName = v
+	}
+	if v := h.Get(headerTFEVersion); v != "" {
+		i.tfeVersion = v
+	}
+}
+
+// recordAPIInfo wraps the transport to keep the APIInfo updated from every response.
+func recordAPIInfo(info *APIInfo, next http.RoundTripper) http.RoundTripper {
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err == nil {
+			info.update(resp.Header)
+		}
+		return resp, err
+	})
+}
@@ -0,0 +1,40 @@
+package setup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecordAPIInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("TFP-API-Version", "2.5")
+		w.Header().Set("TFP-AppName", "Terraform Enterprise")
+		if r.URL.Path == "/api/v2/ping" {
+			w.Header().Set("X-TFE-Version", "v202301-1")
+		}
+	}))
+	defer server.Close()
+
+	info := &APIInfo{}
+	client := &http.Client{Transport: recordAPIInfo(info, http.DefaultTransport)}
+
+	convey.Convey("API info from response headers", t, func() {
+		apiVersion, appName, tfeVersion := info.Get()
+		convey.So(apiVersion+appName+tfeVersion, convey.ShouldBeEmpty)
+
+		for _, path := range []string{"/api/v2/ping", "/api/v2/organizations"} {
+			resp, err := client.Get(server.URL + path)
+			convey.So(err, convey.ShouldBeNil)
+			resp.Body.Close()
+		}
+
+		// Headers missing from later responses keep their previous value.
+		apiVersion, appName, tfeVersion = info.Get()
+		convey.So(apiVersion, convey.ShouldEqual, "2.5")
+		convey.So(appName, convey.ShouldEqual, "Terraform Enterprise")
+		convey.So(tfeVersion, convey.ShouldEqual, "v202301-1")
+	})
+}
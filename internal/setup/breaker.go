@@ -0,0 +1,114 @@
+package setup
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ErrCircuitOpen is returned for the requests rejected by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker open after consecutive API failures")
+
+// CircuitBreaker stops sending requests to the API after a number of consecutive failures
+// (network errors or 5xx responses), so a degraded instance isn't hammered with full scrapes.
+// Once open, a single request is let through every cooldown to probe whether the API recovered.
+// It is safe for concurrent use. A nil CircuitBreaker never opens.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker returns a CircuitBreaker opening after threshold consecutive failures,
+// or nil (disabled) if threshold <= 0.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Open reports whether the requests to the API are being rejected.
+func (b *CircuitBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
+
+// allow reports whether a request can be sent, letting a single probe through once the cooldown passed.
+func (b *CircuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || now.Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// record updates the state of the breaker with the result of a request.
+func (b *CircuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
+
+// release lets another probe through, when a request finished without telling whether the API is healthy.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// breakCircuit wraps the transport to reject the requests while the breaker is open.
+func breakCircuit(b *CircuitBreaker, next http.RoundTripper) http.RoundTripper {
+	if b == nil {
+		return next
+	}
+
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !b.allow(time.Now()) {
+			return nil, ErrCircuitOpen
+		}
+
+		resp, err := next.RoundTrip(req)
+		switch {
+		case req.Context().Err() != nil:
+			// Cancelled by the scrape, not a failure of the API.
+			b.release()
+		case err != nil:
+			b.record(false, time.Now())
+		default:
+			b.record(resp.StatusCode < 500, time.Now())
+		}
+
+		return resp, err
+	})
+}
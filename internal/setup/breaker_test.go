@@ -0,0 +1,68 @@
+package setup
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	convey.Convey("Circuit breaker", t, func() {
+		b := NewCircuitBreaker(2, time.Minute)
+		now := time.Now()
+
+		convey.Convey("opens after consecutive failures", func() {
+			b.record(false, now)
+			convey.So(b.Open(), convey.ShouldBeFalse)
+			convey.So(b.allow(now), convey.ShouldBeTrue)
+
+			b.record(false, now)
+			convey.So(b.Open(), convey.ShouldBeTrue)
+			convey.So(b.allow(now), convey.ShouldBeFalse)
+		})
+
+		convey.Convey("resets on success", func() {
+			b.record(false, now)
+			b.record(true, now)
+			b.record(false, now)
+			convey.So(b.Open(), convey.ShouldBeFalse)
+		})
+
+		convey.Convey("lets a single probe through after the cooldown", func() {
+			b.record(false, now)
+			b.record(false, now)
+
+			later := now.Add(time.Minute)
+			convey.So(b.allow(later), convey.ShouldBeTrue)
+			convey.So(b.allow(later), convey.ShouldBeFalse)
+
+			b.record(true, later)
+			convey.So(b.Open(), convey.ShouldBeFalse)
+			convey.So(b.allow(later), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("is disabled when nil", func() {
+			convey.So(NewCircuitBreaker(0, time.Minute), convey.ShouldBeNil)
+			convey.So(NewCircuitBreaker(0, time.Minute).Open(), convey.ShouldBeFalse)
+		})
+	})
+
+	convey.Convey("Rejects requests while open", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: breakCircuit(NewCircuitBreaker(1, time.Minute), http.DefaultTransport)}
+		resp, err := client.Get(server.URL)
+		convey.So(err, convey.ShouldBeNil)
+		resp.Body.Close()
+
+		_, err = client.Get(server.URL)
+		convey.So(errors.Is(err, ErrCircuitOpen), convey.ShouldBeTrue)
+	})
+}
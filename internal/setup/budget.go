@@ -0,0 +1,69 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ErrRequestBudgetExceeded is returned for the requests over the RequestBudget of the scrape.
+var ErrRequestBudgetExceeded = errors.New("API request budget of the scrape exceeded")
+
+// budgetKey is the context key of the RequestBudget.
+type budgetKey struct{}
+
+// RequestBudget caps the number of requests sent to the API during a scrape, so its cost doesn't grow with the estate.
+// Once spent, the requests are rejected and the scrapers stop, exposing what they got so far.
+// It is safe for concurrent use. A nil RequestBudget is unlimited.
+type RequestBudget struct {
+	limit    int64
+	used     int64
+	exceeded int32
+}
+
+// NewRequestBudget returns a RequestBudget allowing limit requests, or nil (unlimited) if limit <= 0.
+func NewRequestBudget(limit int) *RequestBudget {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &RequestBudget{limit: int64(limit)}
+}
+
+// WithRequestBudget returns a copy of ctx whose API requests are counted against the budget.
+func WithRequestBudget(ctx context.Context, budget *RequestBudget) context.Context {
+	if budget == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, budgetKey{}, budget)
+}
+
+// Exceeded reports whether any request was rejected for being over the budget.
+func (b *RequestBudget) Exceeded() bool {
+	return b != nil && atomic.LoadInt32(&b.exceeded) == 1
+}
+
+// take reports whether another request fits in the budget.
+func (b *RequestBudget) take() bool {
+	if atomic.AddInt64(&b.used, 1) <= b.limit {
+		return true
+	}
+
+	atomic.StoreInt32(&b.exceeded, 1)
+	return false
+}
+
+// limitRequests wraps the transport to reject the requests over the budget of their context.
+func limitRequests(next http.RoundTripper) http.RoundTripper {
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if budget, ok := req.Context().Value(budgetKey{}).(*RequestBudget); ok && !budget.take() {
+			return nil, ErrRequestBudgetExceeded
+		}
+
+		return next.RoundTrip(req)
+	})
+}
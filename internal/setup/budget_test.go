@@ -0,0 +1,53 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestBudget(t *testing.T) {
+	var requests int
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer mockAPI.Close()
+
+	client := &http.Client{Transport: limitRequests(http.DefaultTransport)}
+	get := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, mockAPI.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	convey.Convey("Requests over the budget are rejected", t, func() {
+		requests = 0
+		budget := NewRequestBudget(2)
+		ctx := WithRequestBudget(context.Background(), budget)
+		convey.So(get(ctx), convey.ShouldBeNil)
+		convey.So(get(ctx), convey.ShouldBeNil)
+		convey.So(budget.Exceeded(), convey.ShouldBeFalse)
+
+		err := get(ctx)
+		convey.So(errors.Is(err, ErrRequestBudgetExceeded), convey.ShouldBeTrue)
+		convey.So(budget.Exceeded(), convey.ShouldBeTrue)
+		convey.So(requests, convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Without a budget the requests aren't limited", t, func() {
+		requests = 0
+		ctx := WithRequestBudget(context.Background(), NewRequestBudget(0))
+		for i := 0; i < 3; i++ {
+			convey.So(get(ctx), convey.ShouldBeNil)
+		}
+		convey.So(requests, convey.ShouldEqual, 3)
+		convey.So(NewRequestBudget(0).Exceeded(), convey.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,271 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// demoSeed makes the fabricated data the same on every start, so the demo metrics are stable.
+const demoSeed = 42
+
+var (
+	demoOrganizations = []struct {
+		name       string
+		workspaces int
+	}{
+		{"acme", 24},
+		{"acme-sandbox", 6},
+	}
+	demoComponents   = []string{"network", "app", "data", "iam", "dns", "monitoring"}
+	demoEnvironments = []string{"prod", "staging", "dev", "sandbox"}
+	demoVersions     = []string{"1.3.9", "1.4.6", "1.5.7", "1.5.7", "1.6.6"}
+	// demoStatuses are the final statuses of the runs, the more often the more likely.
+	demoStatuses = []string{"applied", "applied", "applied", "applied", "planned_and_finished", "planned_and_finished", "errored", "discarded"}
+	demoSources  = []string{"tfe-configuration-version", "tfe-configuration-version", "tfe-configuration-version", "tfe-api", "tfe-ui"}
+)
+
+// demoRun is a fabricated run. Its times are relative to the time of the request, so it stays recent.
+type demoRun struct {
+	id        string
+	status    string
+	source    string
+	isDestroy bool
+	age       time.Duration
+	plan      time.Duration
+}
+
+type demoWorkspace struct {
+	id               string
+	name             string
+	terraformVersion string
+	age              time.Duration
+	// runs are sorted from the newest.
+	runs []demoRun
+}
+
+// demoAPI serves the endpoints of the API needed by the main scrapers with fabricated organizations,
+// workspaces and runs. Other lists are empty and other resources aren't found.
+type demoAPI struct {
+	organizations map[string][]demoWorkspace
+	workspaces    map[string]demoWorkspace
+}
+
+func newDemoAPI() *demoAPI {
+	rnd := rand.New(rand.NewSource(demoSeed))
+	api := &demoAPI{organizations: map[string][]demoWorkspace{}, workspaces: map[string]demoWorkspace{}}
+	for _, o := range demoOrganizations {
+		for i := 0; i < o.workspaces; i++ {
+			w := demoWorkspace{
+				id:               fmt.Sprintf("ws-%s-%d", o.name, i),
+				name:             demoComponents[i%len(demoComponents)] + "-" + demoEnvironments[(i/len(demoComponents))%len(demoEnvironments)],
+				terraformVersion: demoVersions[rnd.Intn(len(demoVersions))],
+				age:              time.Duration(30+rnd.Intn(300)) * 24 * time.Hour,
+			}
+			var age time.Duration
+			for r := 0; r < 3+rnd.Intn(10); r++ {
+				age += time.Duration(10+rnd.Intn(600)) * time.Minute
+				run := demoRun{
+					id:        fmt.Sprintf("run-%s-%d-%d", o.name, i, r),
+					status:    demoStatuses[rnd.Intn(len(demoStatuses))],
+					source:    demoSources[rnd.Intn(len(demoSources))],
+					isDestroy: strings.HasSuffix(w.name, "-sandbox") && rnd.Intn(4) == 0,
+					age:       age,
+					plan:      time.Duration(20+rnd.Intn(400)) * time.Second,
+				}
+				if r == 0 && rnd.Intn(8) == 0 {
+					// A few workspaces have a run waiting for a worker.
+					run.status, run.age = "pending", time.Duration(1+rnd.Intn(20))*time.Minute
+				}
+				w.runs = append(w.runs, run)
+			}
+			api.organizations[o.name] = append(api.organizations[o.name], w)
+			api.workspaces[w.id] = w
+		}
+	}
+
+	return api
+}
+
+func demoTimestamp(now time.Time, age time.Duration) string {
+	return now.Add(-age).UTC().Format(time.RFC3339)
+}
+
+func (r demoRun) resource(now time.Time) map[string]interface{} {
+	createdAt := now.Add(-r.age)
+	at := func(d time.Duration) string { return createdAt.Add(d).UTC().Format(time.RFC3339) }
+	timestamps := map[string]string{}
+	if r.status != "pending" {
+		timestamps["plan-queued-at"] = at(10 * time.Second)
+		timestamps["planning-at"] = at(30 * time.Second)
+	}
+	switch r.status {
+	case "applied":
+		timestamps["planned-at"] = at(30*time.Second + r.plan)
+		timestamps["confirmed-at"] = at(2*time.Minute + r.plan)
+		timestamps["applying-at"] = at(2*time.Minute + r.plan)
+		timestamps["applied-at"] = at(2*time.Minute + 2*r.plan)
+	case "planned_and_finished":
+		timestamps["planned-and-finished-at"] = at(30*time.Second + r.plan)
+	case "errored":
+		timestamps["errored-at"] = at(30*time.Second + r.plan)
+	case "discarded":
+		timestamps["planned-at"] = at(30*time.Second + r.plan)
+		timestamps["discarded-at"] = at(time.Hour)
+	}
+
+	return map[string]interface{}{
+		"id":   r.id,
+		"type": "runs",
+		"attributes": map[string]interface{}{
+			"status":            r.status,
+			"source":            r.source,
+			"is-destroy":        r.isDestroy,
+			"auto-apply":        false,
+			"created-at":        createdAt.UTC().Format(time.RFC3339),
+			"status-timestamps": timestamps,
+		},
+	}
+}
+
+func (w demoWorkspace) resource(organization string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   w.id,
+		"type": "workspaces",
+		"attributes": map[string]interface{}{
+			"name":                w.name,
+			"created-at":          demoTimestamp(now, w.age),
+			"environment":         "default",
+			"terraform-version":   w.terraformVersion,
+			"allow-destroy-plan":  strings.HasSuffix(w.name, "-sandbox"),
+			"auto-apply":          false,
+			"queue-all-runs":      false,
+			"assessments-enabled": strings.HasSuffix(w.name, "-prod"),
+		},
+		"relationships": map[string]interface{}{
+			"organization": map[string]interface{}{"data": map[string]string{"id": organization, "type": "organizations"}},
+			"current-run":  map[string]interface{}{"data": map[string]string{"id": w.runs[0].id, "type": "runs"}},
+		},
+	}
+}
+
+// demoPage returns the page of the items requested by the query, and its pagination.
+func demoPage(items []interface{}, query url.Values) ([]interface{}, map[string]interface{}) {
+	number, size := 1, 20
+	if n, err := strconv.Atoi(query.Get("page[number]")); err == nil && n > 0 {
+		number = n
+	}
+	if s, err := strconv.Atoi(query.Get("page[size]")); err == nil && s > 0 {
+		size = s
+	}
+
+	total := (len(items) + size - 1) / size
+	if total == 0 {
+		total = 1
+	}
+	start, end := (number-1)*size, number*size
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return items[start:end], map[string]interface{}{"pagination": map[string]interface{}{
+		"current-page": number,
+		"total-pages":  total,
+		"total-count":  len(items),
+	}}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (a *demoAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v2"), "/"), "/")
+	query := r.URL.Query()
+
+	var doc map[string]interface{}
+	switch {
+	case r.URL.Path == "/api/v2/ping":
+		w.Header().Set("TFP-API-Version", "2.6")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case r.URL.Path == "/api/v2/account/details":
+		doc = map[string]interface{}{"data": map[string]interface{}{"id": "user-demo", "type": "users", "attributes": map[string]string{"username": "demo"}}}
+	case len(path) == 1 && path[0] == "organizations":
+		organizations := []interface{}{}
+		for _, o := range demoOrganizations {
+			organizations = append(organizations, map[string]interface{}{"id": o.name, "type": "organizations", "attributes": map[string]string{"name": o.name}})
+		}
+		data, meta := demoPage(organizations, query)
+		doc = map[string]interface{}{"data": data, "meta": meta}
+	case len(path) == 2 && path[0] == "organizations" && a.organizations[path[1]] != nil:
+		doc = map[string]interface{}{"data": map[string]interface{}{
+			"id":   path[1],
+			"type": "organizations",
+			"attributes": map[string]interface{}{
+				"name":                    path[1],
+				"email":                   "admin@" + path[1] + ".example.com",
+				"created-at":              demoTimestamp(now, 400*24*time.Hour),
+				"two-factor-conformant":   true,
+				"saml-enabled":            false,
+				"cost-estimation-enabled": true,
+			},
+		}}
+	case len(path) == 3 && path[0] == "organizations" && path[2] == "workspaces" && a.organizations[path[1]] != nil:
+		workspaces := []interface{}{}
+		for _, ws := range a.organizations[path[1]] {
+			workspaces = append(workspaces, ws)
+		}
+		data, meta := demoPage(workspaces, query)
+		resources, included := []interface{}{}, []interface{}{}
+		for _, ws := range data {
+			resources = append(resources, ws.(demoWorkspace).resource(path[1], now))
+			included = append(included, ws.(demoWorkspace).runs[0].resource(now))
+		}
+		doc = map[string]interface{}{"data": resources, "included": included, "meta": meta}
+	case len(path) == 3 && path[0] == "workspaces" && path[2] == "runs" && a.workspaces[path[1]].id != "":
+		runs := []interface{}{}
+		for _, run := range a.workspaces[path[1]].runs {
+			if status := query.Get("filter[status]"); status == "" || status == run.status {
+				runs = append(runs, run.resource(now))
+			}
+		}
+		data, meta := demoPage(runs, query)
+		doc = map[string]interface{}{"data": data, "meta": meta}
+	case query.Get("page[number]") != "" || query.Get("page[size]") != "":
+		doc = map[string]interface{}{"data": []interface{}{}, "meta": map[string]interface{}{"pagination": map[string]interface{}{"current-page": 1, "total-pages": 1, "total-count": 0}}}
+	default:
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[{"status":"404","title":"not found"}]}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// demoResponses replaces the transport to serve the fabricated data of the demo, without sending any request to the API.
+func demoResponses(enabled bool, next http.RoundTripper) http.RoundTripper {
+	if !enabled {
+		return next
+	}
+
+	api := newDemoAPI()
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		resp := rec.Result()
+		resp.Request = req
+		return resp, nil
+	})
+}
@@ -0,0 +1,76 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestDemoResponses(t *testing.T) {
+	httpClient := &http.Client{Transport: demoResponses(true, http.DefaultTransport)}
+	client, err := tfe.NewClient(&tfe.Config{Address: "http://demo.example.com", Token: "demo", HTTPClient: httpClient})
+	if err != nil {
+		t.Fatalf("error creating the demo api client: %s", err)
+	}
+	api, err := NewJSONAPI(httpClient, "http://demo.example.com", "demo")
+	if err != nil {
+		t.Fatalf("error creating the demo api client: %s", err)
+	}
+	ctx := context.Background()
+
+	convey.Convey("Organizations", t, func() {
+		list, err := client.Organizations.List(ctx, &tfe.OrganizationListOptions{})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(list.Items, convey.ShouldHaveLength, len(demoOrganizations))
+
+		o, err := client.Organizations.Read(ctx, "acme")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(o.CostEstimationEnabled, convey.ShouldBeTrue)
+	})
+
+	convey.Convey("Workspaces are paginated, with their current run", t, func() {
+		list, err := client.Workspaces.List(ctx, "acme", &tfe.WorkspaceListOptions{
+			ListOptions: tfe.ListOptions{PageNumber: 2, PageSize: 10},
+			Include:     []tfe.WSIncludeOpt{"current_run"},
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(list.Items, convey.ShouldHaveLength, 10)
+		convey.So(list.Pagination.TotalPages, convey.ShouldEqual, 3)
+		convey.So(list.Pagination.TotalCount, convey.ShouldEqual, 24)
+		convey.So(list.Items[0].CurrentRun.Status, convey.ShouldNotBeEmpty)
+	})
+
+	convey.Convey("Runs are listed from the newest, filtered by status", t, func() {
+		runs, err := client.Runs.List(ctx, "ws-acme-0", &tfe.RunListOptions{ListOptions: tfe.ListOptions{PageSize: 100}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(runs.Items, convey.ShouldNotBeEmpty)
+		for i := 1; i < len(runs.Items); i++ {
+			convey.So(runs.Items[i].CreatedAt, convey.ShouldHappenOnOrBefore, runs.Items[i-1].CreatedAt)
+		}
+
+		var applied []*struct {
+			ID     string        `jsonapi:"primary,runs"`
+			Status tfe.RunStatus `jsonapi:"attr,status"`
+		}
+		_, err = api.List(ctx, "workspaces/ws-acme-0/runs", tfe.ListOptions{PageSize: 100}, url.Values{"filter[status]": {"applied"}}, &applied)
+		convey.So(err, convey.ShouldBeNil)
+		for _, r := range applied {
+			convey.So(r.Status, convey.ShouldEqual, tfe.RunApplied)
+		}
+	})
+
+	convey.Convey("Other lists are empty and other resources aren't found", t, func() {
+		list, err := client.PolicySets.List(ctx, "acme", &tfe.PolicySetListOptions{ListOptions: tfe.ListOptions{PageSize: 100}})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(list.Items, convey.ShouldBeEmpty)
+
+		_, err = client.Workspaces.ReadByID(ctx, "ws-acme-0")
+		convey.So(errors.Is(err, tfe.ErrResourceNotFound), convey.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,129 @@
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Endpoints are the addresses of the replicas of the API of an instance (e.g. active/standby or DR), in order of preference.
+// Every request is sent to the active one, until connecting to it fails and the next one takes over.
+// It is safe for concurrent use. A nil Endpoints never fails over.
+type Endpoints struct {
+	urls []*url.URL
+
+	mu     sync.Mutex
+	active int
+}
+
+// NewEndpoints returns the Endpoints of the API at the given address and its failover addresses,
+// or nil (disabled) if there are no failover addresses.
+func NewEndpoints(address string, failovers []string) (*Endpoints, error) {
+	if len(failovers) == 0 {
+		return nil, nil
+	}
+	if address == "" {
+		address = tfe.DefaultAddress
+	}
+
+	e := &Endpoints{}
+	for _, a := range append([]string{address}, failovers...) {
+		u, err := url.Parse(strings.TrimSuffix(a, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid API address: %q", a)
+		}
+		e.urls = append(e.urls, u)
+	}
+
+	return e, nil
+}
+
+// Addresses returns the addresses of the replicas, in order of preference.
+func (e *Endpoints) Addresses() []string {
+	if e == nil {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(e.urls))
+	for _, u := range e.urls {
+		addresses = append(addresses, u.String())
+	}
+
+	return addresses
+}
+
+// Active returns the address of the replica the requests are sent to.
+func (e *Endpoints) Active() string {
+	if e == nil {
+		return ""
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.urls[e.active].String()
+}
+
+func (e *Endpoints) current() (int, *url.URL) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active, e.urls[e.active]
+}
+
+// failover moves on to the next replica, unless a concurrent request already moved on from the failed one.
+func (e *Endpoints) failover(failed int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.active == failed {
+		e.active = (failed + 1) % len(e.urls)
+	}
+}
+
+// connectionFailed reports whether the request couldn't reach the API at all, so it was never sent.
+func connectionFailed(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// failoverRequests wraps the transport to send the requests to the active replica, failing over to the next ones
+// when connecting to it fails. Replicas are expected to serve the API under the same path, only their host differs.
+func failoverRequests(e *Endpoints, next http.RoundTripper) http.RoundTripper {
+	if e == nil {
+		return next
+	}
+
+	return promhttp.RoundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
+		for range e.urls {
+			i, u := e.current()
+
+			r := req.Clone(req.Context())
+			r.URL.Scheme, r.URL.Host, r.Host = u.Scheme, u.Host, ""
+			if req.Body != nil && req.GetBody != nil {
+				if r.Body, err = req.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+
+			resp, err = next.RoundTrip(r)
+			if err == nil || !connectionFailed(err) || req.Context().Err() != nil {
+				return resp, err
+			}
+			e.failover(i)
+
+			// The body was consumed and can't be sent again.
+			if req.Body != nil && req.GetBody == nil {
+				return nil, err
+			}
+		}
+
+		return nil, err
+	})
+}
@@ -0,0 +1,81 @@
+package setup
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestFailoverRequests(t *testing.T) {
+	convey.Convey("Endpoints", t, func() {
+		convey.Convey("are disabled without failover addresses", func() {
+			e, err := NewEndpoints("https://tfe.example.com/", nil)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(e, convey.ShouldBeNil)
+			convey.So(e.Active(), convey.ShouldEqual, "")
+		})
+
+		convey.Convey("reject invalid addresses", func() {
+			_, err := NewEndpoints("https://tfe.example.com/", []string{"tfe-dr"})
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("default to Terraform Cloud", func() {
+			e, err := NewEndpoints("", []string{"https://tfe-dr.example.com/"})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(e.Addresses(), convey.ShouldResemble, []string{"https://app.terraform.io", "https://tfe-dr.example.com"})
+		})
+	})
+
+	convey.Convey("Fails over when the active replica can't be reached", t, func() {
+		standby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.Path))
+		}))
+		defer standby.Close()
+
+		// Nothing listens on the address of the closed listener.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		convey.So(err, convey.ShouldBeNil)
+		active := "http://" + listener.Addr().String()
+		listener.Close()
+
+		e, err := NewEndpoints(active, []string{standby.URL})
+		convey.So(err, convey.ShouldBeNil)
+		client := &http.Client{Transport: failoverRequests(e, http.DefaultTransport)}
+
+		resp, err := client.Get(active + "/api/v2/ping")
+		convey.So(err, convey.ShouldBeNil)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		convey.So(string(body), convey.ShouldEqual, "/api/v2/ping")
+		convey.So(e.Active(), convey.ShouldEqual, standby.URL)
+
+		convey.Convey("and keeps using the standby replica", func() {
+			resp, err := client.Get(active + "/api/v2/organizations")
+			convey.So(err, convey.ShouldBeNil)
+			resp.Body.Close()
+			convey.So(e.Active(), convey.ShouldEqual, standby.URL)
+		})
+	})
+
+	convey.Convey("Doesn't fail over on error responses", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		e, err := NewEndpoints(server.URL, []string{"https://tfe-dr.example.com/"})
+		convey.So(err, convey.ShouldBeNil)
+		client := &http.Client{Transport: failoverRequests(e, http.DefaultTransport)}
+
+		resp, err := client.Get(server.URL)
+		convey.So(err, convey.ShouldBeNil)
+		resp.Body.Close()
+		convey.So(resp.StatusCode, convey.ShouldEqual, http.StatusBadGateway)
+		convey.So(e.Active(), convey.ShouldEqual, server.URL)
+	})
+}
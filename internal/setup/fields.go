@@ -0,0 +1,72 @@
+package setup
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// fieldsKey is the context key of the requested Fields.
+type fieldsKey struct{}
+
+// queryKey is the context key of the query parameters added to the requests.
+type queryKey struct{}
+
+// Fields are the attributes and relationships to request for each resource type, using JSON:API
+// sparse fieldsets (fields[type]=a,b), as the options of the tfe client don't support them.
+// Relationships used by the scraper (e.g. organization) must be requested too.
+type Fields map[string][]string
+
+// With returns a copy of the fields with the other fields added to them.
+func (f Fields) With(other Fields) Fields {
+	fields := make(Fields, len(f)+len(other))
+	for resource, names := range f {
+		fields[resource] = append([]string{}, names...)
+	}
+	for resource, names := range other {
+		fields[resource] = append(fields[resource], names...)
+	}
+
+	return fields
+}
+
+// WithFields returns a copy of ctx that only requests the given fields on the API requests made with it.
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// WithQuery returns a copy of ctx adding the given parameters to the query of the API requests made with it,
+// for the parameters the options of the tfe client don't support (e.g. search[wildcard-name]).
+func WithQuery(ctx context.Context, query url.Values) context.Context {
+	if len(query) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryKey{}, query)
+}
+
+// requestFields wraps the transport to add the sparse fieldsets and the query parameters of their context to the requests.
+func requestFields(next http.RoundTripper) http.RoundTripper {
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		fields, withFields := req.Context().Value(fieldsKey{}).(Fields)
+		params, withQuery := req.Context().Value(queryKey{}).(url.Values)
+		if (!withFields && !withQuery) || req.Method != http.MethodGet {
+			return next.RoundTrip(req)
+		}
+
+		req = req.Clone(req.Context())
+		query := req.URL.Query()
+		for resource, names := range fields {
+			query.Set("fields["+resource+"]", strings.Join(names, ","))
+		}
+		for name, values := range params {
+			query[name] = values
+		}
+		req.URL.RawQuery = query.Encode()
+
+		return next.RoundTrip(req)
+	})
+}
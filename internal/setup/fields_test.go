@@ -0,0 +1,87 @@
+package setup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestFields(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: requestFields(http.DefaultTransport)}
+	get := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v2/organizations/org/workspaces?page[number]=2", nil)
+		convey.So(err, convey.ShouldBeNil)
+		resp, err := client.Do(req)
+		convey.So(err, convey.ShouldBeNil)
+		resp.Body.Close()
+	}
+
+	convey.Convey("Sparse fieldsets from the context", t, func() {
+		get(WithFields(context.Background(), Fields{"workspaces": {"name", "organization"}, "runs": {"status"}}))
+		convey.So(query.Get("fields[workspaces]"), convey.ShouldEqual, "name,organization")
+		convey.So(query.Get("fields[runs]"), convey.ShouldEqual, "status")
+		convey.So(query.Get("page[number]"), convey.ShouldEqual, "2")
+	})
+
+	convey.Convey("All fields without them", t, func() {
+		get(context.Background())
+		convey.So(query.Get("fields[workspaces]"), convey.ShouldBeEmpty)
+		convey.So(query.Get("page[number]"), convey.ShouldEqual, "2")
+	})
+}
+
+func TestFieldsWith(t *testing.T) {
+	fields := Fields{"workspaces": {"name"}, "runs": {"status"}}
+
+	convey.Convey("Adds the other fields to a copy", t, func() {
+		convey.So(fields.With(Fields{"runs": {"created-at"}, "plans": {"status"}}), convey.ShouldResemble, Fields{
+			"workspaces": {"name"},
+			"runs":       {"status", "created-at"},
+			"plans":      {"status"},
+		})
+		convey.So(fields, convey.ShouldResemble, Fields{"workspaces": {"name"}, "runs": {"status"}})
+	})
+}
+
+func TestWithQuery(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: requestFields(http.DefaultTransport)}
+
+	convey.Convey("Query parameters from the context, along with the sparse fieldsets", t, func() {
+		ctx := WithQuery(WithFields(context.Background(), Fields{"workspaces": {"name"}}), url.Values{"search[wildcard-name]": {"*-prod"}})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v2/organizations/org/workspaces?page[number]=2", nil)
+		convey.So(err, convey.ShouldBeNil)
+		resp, err := client.Do(req)
+		convey.So(err, convey.ShouldBeNil)
+		resp.Body.Close()
+
+		convey.So(query.Get("search[wildcard-name]"), convey.ShouldEqual, "*-prod")
+		convey.So(query.Get("fields[workspaces]"), convey.ShouldEqual, "name")
+		convey.So(query.Get("page[number]"), convey.ShouldEqual, "2")
+	})
+
+	convey.Convey("Search parameters of the workspaces from the flags", t, func() {
+		cli := CLI{WorkspacesSearchName: "app", WorkspacesSearchWildcardName: "*-prod", WorkspacesSearchTags: []string{"team:a", "pci"}}
+		convey.So(cli.WorkspacesSearch(), convey.ShouldResemble, url.Values{
+			"search[name]":          {"app"},
+			"search[wildcard-name]": {"*-prod"},
+			"search[tags]":          {"team:a,pci"},
+		})
+		convey.So((&CLI{}).WorkspacesSearch(), convey.ShouldBeEmpty)
+	})
+}
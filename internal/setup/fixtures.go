@@ -0,0 +1,127 @@
+package setup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// fixture is a response of the API recorded to disk.
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// fixtureFile returns the file of the fixture of the request in the directory: Named after its path, to find it
+// easily, and the hash of its method, path and query, as requests only differing by their query (e.g. their page)
+// get different responses. The address of the API isn't part of it, so fixtures can be replayed against any.
+func fixtureFile(dir string, req *http.Request) string {
+	key := req.Method + " " + req.URL.Path + "?" + req.URL.Query().Encode()
+	sum := sha256.Sum256([]byte(key))
+	name := strings.ReplaceAll(strings.Trim(req.URL.Path, "/"), "/", "_")
+
+	return filepath.Join(dir, name+"-"+hex.EncodeToString(sum[:6])+".json")
+}
+
+// fixturesDir returns the directory of the fixtures of the instance, in its own subdirectory when several
+// instances are scraped, as their requests are the same.
+func (c *Config) fixturesDir(dir string) string {
+	if dir == "" || c.Instance == "" {
+		return dir
+	}
+
+	return filepath.Join(dir, c.Instance)
+}
+
+// recordResponses wraps the transport to save the responses of the API to the directory, to replay them with
+// replayResponses. The requests aren't recorded, as they carry the token.
+func recordResponses(dir string, next http.RoundTripper) http.RoundTripper {
+	if dir == "" {
+		return next
+	}
+
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		b, err := json.MarshalIndent(fixture{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Status: resp.StatusCode,
+			Header: resp.Header,
+			Body:   string(body),
+		}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(fixtureFile(dir, req), b, 0o600); err != nil {
+			return nil, err
+		}
+
+		return resp, nil
+	})
+}
+
+// replayResponses replaces the transport to serve the responses saved to the directory by recordResponses,
+// without sending any request to the API. Requests that weren't recorded get a 404 Not Found.
+func replayResponses(dir string, next http.RoundTripper) http.RoundTripper {
+	if dir == "" {
+		return next
+	}
+
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		f := fixture{
+			Status: http.StatusNotFound,
+			Header: http.Header{"Content-Type": {"application/vnd.api+json"}},
+			Body:   `{"errors":[{"status":"404","title":"not recorded"}]}`,
+		}
+		b, err := os.ReadFile(fixtureFile(dir, req))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(b, &f); err != nil {
+				return nil, err
+			}
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+			StatusCode:    f.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        f.Header,
+			Body:          io.NopCloser(strings.NewReader(f.Body)),
+			ContentLength: int64(len(f.Body)),
+			Request:       req,
+		}, nil
+	})
+}
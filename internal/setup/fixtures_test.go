@@ -0,0 +1,59 @@
+package setup
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecordAndReplayResponses(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("TFP-API-Version", "2.5")
+		w.Write([]byte(`{"page":"` + r.URL.Query().Get("page[number]") + `"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	get := func(client *http.Client, address, page string) (int, string, string) {
+		resp, err := client.Get(address + "/api/v2/organizations/org/workspaces?page[number]=" + page)
+		convey.So(err, convey.ShouldBeNil)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		convey.So(err, convey.ShouldBeNil)
+		return resp.StatusCode, resp.Header.Get("TFP-API-Version"), string(body)
+	}
+
+	convey.Convey("Responses are recorded as they're returned", t, func() {
+		client := &http.Client{Transport: recordResponses(dir, http.DefaultTransport)}
+		for _, page := range []string{"1", "2"} {
+			status, version, body := get(client, server.URL, page)
+			convey.So(status, convey.ShouldEqual, http.StatusOK)
+			convey.So(version, convey.ShouldEqual, "2.5")
+			convey.So(body, convey.ShouldEqual, `{"page":"`+page+`"}`)
+		}
+		convey.So(requests, convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Recorded responses are replayed without requests, whatever the address", t, func() {
+		client := &http.Client{Transport: replayResponses(dir, http.DefaultTransport)}
+		for _, page := range []string{"2", "1"} {
+			status, version, body := get(client, "http://replayed.example.com", page)
+			convey.So(status, convey.ShouldEqual, http.StatusOK)
+			convey.So(version, convey.ShouldEqual, "2.5")
+			convey.So(body, convey.ShouldEqual, `{"page":"`+page+`"}`)
+		}
+		convey.So(requests, convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Requests that weren't recorded aren't found", t, func() {
+		client := &http.Client{Transport: replayResponses(dir, http.DefaultTransport)}
+		status, _, _ := get(client, server.URL, "3")
+		convey.So(status, convey.ShouldEqual, http.StatusNotFound)
+		convey.So(requests, convey.ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,24 @@
+package setup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Hash returns a short hash of the configuration, to tell whether the replicas of the exporter run the same one.
+// The secrets are left out, and so is the shard, which differs between the replicas by design.
+func (c CLI) Hash() string {
+	c.APIToken, c.APITokenFile = "", nil
+	c.RemoteWriteBearerToken, c.WebhookToken, c.AuditTrailToken = "", "", ""
+	c.Shard = Shard{}
+
+	// Maps are marshalled with sorted keys, so equal configurations get the same hash.
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])[:12]
+}
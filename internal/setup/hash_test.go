@@ -0,0 +1,31 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCLIHash(t *testing.T) {
+	cli := CLI{
+		Organizations: []string{"org1", "org2"},
+		CacheTTL:      map[string]time.Duration{"runs": 5 * time.Minute, "workspaces": time.Minute},
+		APIToken:      "secret",
+		Shard:         Shard{Index: 0, Total: 2},
+	}
+
+	convey.Convey("Secrets and shards don't change the hash", t, func() {
+		other := cli
+		other.APIToken = "other-secret"
+		other.Shard = Shard{Index: 1, Total: 2}
+		convey.So(cli.Hash(), convey.ShouldHaveLength, 12)
+		convey.So(other.Hash(), convey.ShouldEqual, cli.Hash())
+	})
+
+	convey.Convey("Other settings change the hash", t, func() {
+		other := cli
+		other.Organizations = []string{"org1"}
+		convey.So(other.Hash(), convey.ShouldNotEqual, cli.Hash())
+	})
+}
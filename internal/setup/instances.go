@@ -0,0 +1,67 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// setupInstances creates the Configs of the other instances to scrape along with this one, if any.
+// They share the flags of this one, but get their own address, token, clients, pool and circuit breaker.
+func (c *Config) setupInstances() error {
+	if len(c.APIInstances) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.APIInstances))
+	for name := range c.APIInstances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.Instances = []Config{*c}
+	for _, name := range names {
+		if name == c.Instance {
+			return fmt.Errorf("instance name %q already used by --api-address, set another one with --instance-name", name)
+		}
+
+		token, err := readTokenFile(c.APIInstanceTokenFiles[name])
+		if err != nil {
+			return fmt.Errorf("error reading the token of the instance %q: %w", name, err)
+		}
+
+		instance := *c
+		instance.Instance = name
+		instance.APIAddress = c.APIInstances[name]
+		instance.Instances = nil
+		// The failover addresses are the replicas of --api-address only.
+		instance.APIFailoverAddresses = nil
+		instance.Logger = log.With(c.Logger, "instance", name)
+		if _, err := instance.newClient(token); err != nil {
+			return fmt.Errorf("error creating the clients of the instance %q: %w", name, err)
+		}
+		level.Info(c.Logger).Log("msg", "Scraping another instance", "instance", name, "address", instance.APIAddress)
+
+		c.Instances = append(c.Instances, instance)
+	}
+
+	return nil
+}
+
+// readTokenFile returns the token in the first line of the file.
+func readTokenFile(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("missing token file, set it with --instance-token-file")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0]), nil
+}
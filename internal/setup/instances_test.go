@@ -0,0 +1,64 @@
+package setup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestSetupInstances(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("tfe1-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client pings the API when created.
+	tfe1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("TFP-API-Version", "2.5")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer tfe1.Close()
+
+	config := Config{
+		CLI: CLI{
+			APIAddress:            "https://app.terraform.io/",
+			APIInstances:          map[string]string{"tfe1": tfe1.URL},
+			APIInstanceTokenFiles: map[string]string{"tfe1": tokenFile},
+			MaxConcurrentRequests: 10,
+		},
+		Instance: "default",
+		Logger:   log.NewNopLogger(),
+	}
+	config.setupInstances()
+
+	convey.Convey("Every instance gets its own Config, starting with the one of --api-address", t, func() {
+		convey.So(config.Instances, convey.ShouldHaveLength, 2)
+		convey.So(config.Instances[0].Instance, convey.ShouldEqual, "default")
+		convey.So(config.Instances[0].APIAddress, convey.ShouldEqual, "https://app.terraform.io/")
+		convey.So(config.Instances[1].Instance, convey.ShouldEqual, "tfe1")
+		convey.So(config.Instances[1].APIAddress, convey.ShouldEqual, tfe1.URL)
+		convey.So(config.Instances[1].Pool, convey.ShouldNotBeNil)
+		convey.So(config.Instances[1].Pool, convey.ShouldNotEqual, config.Instances[0].Pool)
+	})
+}
+
+func TestReadTokenFile(t *testing.T) {
+	convey.Convey("Only the first line of the file is the token", t, func() {
+		path := filepath.Join(t.TempDir(), "token")
+		convey.So(os.WriteFile(path, []byte(" secret \nignored\n"), 0o600), convey.ShouldBeNil)
+
+		token, err := readTokenFile(path)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(token, convey.ShouldEqual, "secret")
+	})
+
+	convey.Convey("A missing token file is an error", t, func() {
+		_, err := readTokenFile("")
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
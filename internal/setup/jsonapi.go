@@ -0,0 +1,187 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/jsonapi"
+)
+
+const (
+	// jsonapiRetryMax, jsonapiRetryWaitMin and jsonapiRetryWaitMax match the retries of the go-tfe client
+	// of the rate limited requests.
+	jsonapiRetryMax     = 30
+	jsonapiRetryWaitMin = 100 * time.Millisecond
+	jsonapiRetryWaitMax = 400 * time.Millisecond
+)
+
+// JSONAPI requests the endpoints of the API the go-tfe client doesn't support yet. Requests go through
+// the same http client, so they're instrumented and limited like any other request to the API, and the
+// rate limited ones are retried like the go-tfe client retries its own.
+type JSONAPI struct {
+	client  *http.Client
+	baseURL *url.URL
+	token   string
+}
+
+// NewJSONAPI returns a new JSONAPI client for the API at the given address.
+func NewJSONAPI(client *http.Client, address, token string) (*JSONAPI, error) {
+	if address == "" {
+		address = tfe.DefaultAddress
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(address, "/") + tfe.DefaultBasePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API address: %w", err)
+	}
+
+	return &JSONAPI{
+		client:  client,
+		baseURL: baseURL,
+		token:   token,
+	}, nil
+}
+
+// List fetches a page of the collection at the path, relative to /api/v2/, with the given query parameters.
+// Its items are decoded into v, a pointer to a slice of pointers to structs with jsonapi tags.
+func (a *JSONAPI) List(ctx context.Context, path string, options tfe.ListOptions, query url.Values, v interface{}) (*tfe.Pagination, error) {
+	slice := reflect.ValueOf(v)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("v must be a pointer to a slice, got %T", v)
+	}
+
+	q := url.Values{}
+	for k, vv := range query {
+		q[k] = vv
+	}
+	if options.PageNumber > 0 {
+		q.Set("page[number]", strconv.Itoa(options.PageNumber))
+	}
+	if options.PageSize > 0 {
+		q.Set("page[size]", strconv.Itoa(options.PageSize))
+	}
+
+	body, err := a.get(ctx, path, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta struct {
+		Meta struct {
+			Pagination *tfe.Pagination `json:"pagination"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, err
+	}
+
+	items, err := jsonapi.UnmarshalManyPayload(bytes.NewReader(body), slice.Elem().Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		slice.Elem().Set(reflect.Append(slice.Elem(), reflect.ValueOf(item)))
+	}
+
+	return meta.Meta.Pagination, nil
+}
+
+// GetJSON fetches the plain JSON document at the path, decoding it into v.
+// Paths are relative to /api/v2/, unless they're absolute, e.g. /api/registry/v1/modules, and can include a query.
+func (a *JSONAPI) GetJSON(ctx context.Context, path string, v interface{}) error {
+	body, err := a.get(ctx, path, nil)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func (a *JSONAPI) get(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	u, err := a.baseURL.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	// Paths can carry their own query, e.g. to get a single item.
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", jsonapi.MediaType)
+	req.Header.Set("Authorization", "Bearer "+a.token)
+
+	resp, err := a.client.Do(req)
+	for attempt := 0; err == nil && resp.StatusCode == http.StatusTooManyRequests && attempt < jsonapiRetryMax; attempt++ {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		select {
+		case <-time.After(retryWait(attempt, resp.Header)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		resp, err = a.client.Do(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// The errors match the ones of the go-tfe client, so they're classified the same way.
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, tfe.ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, tfe.ErrResourceNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("unexpected status from %s: %s", path, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// retryWait returns the time to wait before retrying a rate limited request: an exponential backoff, unless the
+// response tells to wait longer in its Retry-After (or X-RateLimit-Reset) header, with some jitter.
+func retryWait(attempt int, header http.Header) time.Duration {
+	wait := jsonapiRetryWaitMin << attempt
+	if wait > jsonapiRetryWaitMax || wait <= 0 {
+		wait = jsonapiRetryWaitMax
+	}
+	if after := retryAfter(header); after > wait {
+		wait = after
+	}
+
+	return wait + time.Duration(rand.Int63n(int64(jsonapiRetryWaitMax-jsonapiRetryWaitMin)))
+}
+
+// retryAfter returns the time to wait told by the Retry-After header, in seconds or as a date,
+// or else by the X-RateLimit-Reset header of the API, in seconds.
+func retryAfter(header http.Header) time.Duration {
+	if after := header.Get("Retry-After"); after != "" {
+		if seconds, err := strconv.ParseFloat(after, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if date, err := http.ParseTime(after); err == nil {
+			return time.Until(date)
+		}
+	}
+	if seconds, err := strconv.ParseFloat(header.Get("X-RateLimit-Reset"), 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	return 0
+}
@@ -0,0 +1,118 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+type testProject struct {
+	ID   string `jsonapi:"primary,projects"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func TestJSONAPIList(t *testing.T) {
+	var path, query, auth string
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query, auth = r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/api/v2/organizations/missing/projects":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/api/registry/v1/modules/org1/vpc/aws":
+			w.Write([]byte(`{"version":"1.2.0"}`))
+			return
+		}
+		w.Write([]byte(`{
+			"meta":{"pagination":{"current-page":2,"total-pages":3,"total-count":5}},
+			"data":[
+				{"id":"prj-1","type":"projects","attributes":{"name":"one"}},
+				{"id":"prj-2","type":"projects","attributes":{"name":"two"}}
+			]
+		}`))
+	}))
+	defer mockAPI.Close()
+
+	api, err := NewJSONAPI(http.DefaultClient, mockAPI.URL+"/", "test")
+	if err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	convey.Convey("Page of a collection", t, func() {
+		var projects []*testProject
+		pagination, err := api.List(context.Background(), "organizations/org1/projects", tfe.ListOptions{PageNumber: 2, PageSize: 2}, nil, &projects)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(path, convey.ShouldEqual, "/api/v2/organizations/org1/projects")
+		convey.So(query, convey.ShouldEqual, "page%5Bnumber%5D=2&page%5Bsize%5D=2")
+		convey.So(auth, convey.ShouldEqual, "Bearer test")
+		convey.So(pagination.TotalPages, convey.ShouldEqual, 3)
+		convey.So(projects, convey.ShouldResemble, []*testProject{{ID: "prj-1", Name: "one"}, {ID: "prj-2", Name: "two"}})
+	})
+
+	convey.Convey("Missing collection", t, func() {
+		var projects []*testProject
+		_, err := api.List(context.Background(), "organizations/missing/projects", tfe.ListOptions{}, nil, &projects)
+		convey.So(errors.Is(err, tfe.ErrResourceNotFound), convey.ShouldBeTrue)
+	})
+
+	convey.Convey("Plain JSON document", t, func() {
+		var module struct {
+			Version string `json:"version"`
+		}
+		err := api.GetJSON(context.Background(), "/api/registry/v1/modules/org1/vpc/aws", &module)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(module.Version, convey.ShouldEqual, "1.2.0")
+	})
+
+	convey.Convey("Plain JSON document with a query", t, func() {
+		var module struct {
+			Version string `json:"version"`
+		}
+		err := api.GetJSON(context.Background(), "/api/registry/v1/modules/org1/vpc/aws?page%5Bsize%5D=1", &module)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(query, convey.ShouldEqual, "page%5Bsize%5D=1")
+	})
+}
+
+func TestJSONAPIRateLimited(t *testing.T) {
+	requests := 0
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"version":"1.2.0"}`))
+	}))
+	defer mockAPI.Close()
+
+	api, err := NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error calling function on test: %s", err)
+	}
+
+	convey.Convey("Rate limited requests are retried", t, func() {
+		var module struct {
+			Version string `json:"version"`
+		}
+		err := api.GetJSON(context.Background(), "/api/registry/v1/modules/org1/vpc/aws", &module)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(requests, convey.ShouldEqual, 3)
+		convey.So(module.Version, convey.ShouldEqual, "1.2.0")
+	})
+
+	convey.Convey("The wait is told by the response, when longer than the backoff", t, func() {
+		convey.So(retryWait(0, http.Header{}), convey.ShouldBeBetween, jsonapiRetryWaitMin-1, jsonapiRetryWaitMax)
+		convey.So(retryWait(10, http.Header{}), convey.ShouldBeBetween, jsonapiRetryWaitMax-1, 2*jsonapiRetryWaitMax-jsonapiRetryWaitMin)
+		convey.So(retryWait(0, http.Header{"Retry-After": {"2"}}), convey.ShouldBeBetween, 2*time.Second-1, 2*time.Second+jsonapiRetryWaitMax)
+		convey.So(retryWait(0, http.Header{"X-Ratelimit-Reset": {"1.5"}}), convey.ShouldBeBetween, 1500*time.Millisecond-1, 1500*time.Millisecond+jsonapiRetryWaitMax)
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package setup
+
+import (
+	"io"
+	"os"
+)
+
+// logWriter returns where the log messages are written to.
+func logWriter() io.Writer {
+	return os.Stderr
+}
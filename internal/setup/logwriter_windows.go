@@ -0,0 +1,55 @@
+//go:build windows
+
+package setup
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// logWriter returns where the log messages are written to:
+// The Windows event log when running as a service, with ServiceName as source.
+func logWriter() io.Writer {
+	if isService, err := svc.IsWindowsService(); err != nil || !isService {
+		return os.Stderr
+	}
+
+	l, err := eventlog.Open(ServiceName)
+	if err != nil {
+		// The event source isn't registered.
+		return os.Stderr
+	}
+
+	return &eventLogWriter{log: l}
+}
+
+// eventLogWriter writes each log message as an event, with the type matching its level.
+type eventLogWriter struct {
+	log *eventlog.Log
+}
+
+// eventID of the log messages, as the exporter doesn't define specific events.
+const eventID = 1
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+
+	var err error
+	switch {
+	case strings.Contains(msg, "level=error"), strings.Contains(msg, `"level":"error"`):
+		err = w.log.Error(eventID, msg)
+	case strings.Contains(msg, "level=warn"), strings.Contains(msg, `"level":"warn"`):
+		err = w.log.Warning(eventID, msg)
+	default:
+		err = w.log.Info(eventID, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,38 @@
+package setup
+
+import (
+	"context"
+)
+
+// Pool bounds the number of concurrent requests to the API, shared by every scraper, organization and page,
+// so adding scrapers or organizations doesn't multiply the pressure on the API.
+// A nil Pool doesn't limit the concurrency.
+type Pool struct {
+	slots chan struct{}
+}
+
+// NewPool returns a Pool running at most size requests at the same time, or nil (unlimited) if size <= 0.
+func NewPool(size int) *Pool {
+	if size <= 0 {
+		return nil
+	}
+
+	return &Pool{slots: make(chan struct{}, size)}
+}
+
+// Do runs fn once a slot is free, or returns the context error if it's cancelled while waiting.
+// fn should only make the request, leaving the processing of the response out of the pool.
+func (p *Pool) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	return fn(ctx)
+}
@@ -0,0 +1,73 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestPool(t *testing.T) {
+	convey.Convey("Bounds the concurrent calls", t, func() {
+		pool := NewPool(2)
+
+		var (
+			wg            sync.WaitGroup
+			inFlight, max int32
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = pool.Do(context.Background(), func(ctx context.Context) error {
+					n := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+					for {
+						m := atomic.LoadInt32(&max)
+						if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					return nil
+				})
+			}()
+		}
+		wg.Wait()
+
+		convey.So(max, convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Stops waiting when the context is cancelled", t, func() {
+		pool := NewPool(1)
+		release := make(chan struct{})
+		go func() {
+			_ = pool.Do(context.Background(), func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		defer close(release)
+		time.Sleep(5 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := pool.Do(ctx, func(ctx context.Context) error { return nil })
+		convey.So(errors.Is(err, context.DeadlineExceeded), convey.ShouldBeTrue)
+	})
+
+	convey.Convey("A nil pool doesn't limit", t, func() {
+		var pool *Pool
+		called := false
+		err := pool.Do(context.Background(), func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(called, convey.ShouldBeTrue)
+	})
+}
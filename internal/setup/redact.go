@@ -0,0 +1,47 @@
+package setup
+
+import (
+	"net/url"
+)
+
+// redacted replaces the secrets of the configuration when it's shown.
+const redacted = "<redacted>"
+
+// Redacted returns the configuration with its secrets redacted, so it can be shown for troubleshooting:
+// The tokens, including the one read from --api-token-file, and the passwords of the addresses.
+func (c CLI) Redacted() CLI {
+	if c.APITokenFile != nil {
+		c.APIToken, c.APITokenFile = redacted, nil
+	}
+	for _, secret := range []*string{&c.APIToken, &c.RemoteWriteBearerToken, &c.WebhookToken, &c.AuditTrailToken} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+
+	c.APIAddress = redactURL(c.APIAddress)
+	c.PushGatewayURL = redactURL(c.PushGatewayURL)
+	c.RemoteWriteURL = redactURL(c.RemoteWriteURL)
+	failovers := make([]string, 0, len(c.APIFailoverAddresses))
+	for _, address := range c.APIFailoverAddresses {
+		failovers = append(failovers, redactURL(address))
+	}
+	c.APIFailoverAddresses = failovers
+	instances := make(map[string]string, len(c.APIInstances))
+	for name, address := range c.APIInstances {
+		instances[name] = redactURL(address)
+	}
+	c.APIInstances = instances
+
+	return c
+}
+
+// redactURL redacts the password of the address, if any.
+func redactURL(address string) string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return address
+	}
+
+	return u.Redacted()
+}
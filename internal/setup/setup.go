@@ -2,45 +2,339 @@ package setup
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
 
 	"github.com/alecthomas/kong"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"golang.org/x/time/rate"
+
 	tfe "github.com/hashicorp/go-tfe"
 )
 
+// ServiceName is the name of the exporter as a Windows service, and the source of its event log entries.
+const ServiceName = "tf_exporter"
+
 type CLI struct {
-	Organizations         []string `short:"o" env:"TF_ORGANIZATIONS" placeholder:"ORG1,ORG2" help:"List of the Organization names to scrape from (Ommit to scrape all)."`
-	APIToken              string   `short:"t" env:"TF_API_TOKEN" help:"User token for autheticating with the API."`
-	APITokenFile          *os.File `placeholder:"/path/to/file" help:"File containing user token for autheticating with the API."`
-	APIAddress            string   `placeholder:"https://app.terraform.io/" help:"Terraform API address to scrape metrics from."`
-	APIInsecureSkipVerify bool     `help:"Accept any certificate presented by the API."`
-	ListenAddress         string   `default:"0.0.0.0:9100" help:"Address to listen on for web interface and telemetry."`
-	LogLevel              string   `default:"info" enum:"debug,info,warn,error" help:"Only log messages with the given severity or above. One of: [${enum}]"`
-	LogFormat             string   `default:"logfmt" enum:"logfmt,json" help:"Output format of log messages. One of: [${enum}]"`
+	Organizations                 []string                 `short:"o" env:"TF_ORGANIZATIONS" placeholder:"ORG1,ORG2" help:"List of the Organization names to scrape from (Ommit to scrape all)."`
+	OrganizationsDiscoveryTTL     time.Duration            `placeholder:"10m" help:"Time to keep the organizations listed when --organizations is omitted, instead of listing them on every scrape; refresh them earlier with a POST to /-/refresh-organizations (Omit to list them on every scrape)."`
+	APIToken                      string                   `short:"t" env:"TF_API_TOKEN" help:"User token for autheticating with the API."`
+	APITokenFile                  *os.File                 `placeholder:"/path/to/file" help:"File containing user token for autheticating with the API."`
+	APIAddress                    string                   `placeholder:"https://app.terraform.io/" help:"Terraform API address to scrape metrics from."`
+	APIFailoverAddresses          []string                 `name:"api-failover-address" placeholder:"https://tfe-dr.example.com/,..." help:"Addresses of the replicas of --api-address to fail over to, in order, when connecting to the one in use fails (Omit to disable failover)."`
+	InstanceName                  string                   `default:"default" help:"Name of the instance of --api-address, in the instance label of the metrics when other instances are scraped with --instance."`
+	APIInstances                  map[string]string        `name:"instance" placeholder:"NAME=ADDRESS;..." help:"Other Terraform Cloud/Enterprise instances to scrape along with --api-address, by name, e.g. tfe1=https://tfe1.example.com/ (Omit to only scrape --api-address)."`
+	APIInstanceTokenFiles         map[string]string        `name:"instance-token-file" placeholder:"NAME=FILE;..." help:"Files containing the user tokens for authenticating with the API of the other instances, by name."`
+	RecordDir                     string                   `xor:"fixtures" placeholder:"/path/to/dir" help:"Directory to record the responses of the API to, to replay them with --replay-dir (Omit to not record them)."`
+	ReplayDir                     string                   `xor:"fixtures" placeholder:"/path/to/dir" help:"Directory of the responses recorded with --record-dir to serve the metrics from, without sending any request to the API."`
+	Demo                          bool                     `xor:"fixtures" help:"Serve the metrics of fabricated organizations, workspaces and runs instead of the API, to preview the dashboards and alerts without a token."`
+	APIInsecureSkipVerify         bool                     `help:"Accept any certificate presented by the API."`
+	APIMaxIdleConnsPerHost        int                      `default:"10" help:"Maximum number of idle connections to keep open to the API."`
+	APIIdleConnTimeout            time.Duration            `default:"90s" help:"Time an idle connection to the API is kept open."`
+	APIResponseHeaderTimeout      time.Duration            `placeholder:"30s" help:"Time to wait for the API to start answering a request (Omit to wait for the scrape deadline)."`
+	APIRequestTimeout             time.Duration            `placeholder:"10s" help:"Time to wait for each request to the API, including its response, before giving up on it (Omit to wait for the scrape deadline)."`
+	APIRequestRetries             int                      `default:"1" help:"Number of times to retry the GET requests to the API timing out by --api-request-timeout."`
+	CircuitBreakerThreshold       int                      `placeholder:"5" help:"Stop sending requests to the API after this number of consecutive failures (Omit to disable)."`
+	CircuitBreakerCooldown        time.Duration            `default:"30s" help:"Time to wait before probing the API again once the circuit breaker opens."`
+	Collect                       []string                 `placeholder:"SCRAPER1,SCRAPER2,..." help:"List of the scrapers to run (Omit to run all but the Terraform Enterprise admin ones)."`
+	GenericScrapersFile           string                   `placeholder:"/path/to/file" help:"YAML file declaring scrapers of endpoints of the API the exporter doesn't support yet, mapping their attributes to metrics and labels (Omit to only run the builtin scrapers)."`
+	WorkspacesSearchName          string                   `name:"workspaces.search-name" placeholder:"NAME" help:"Only scrape the workspaces whose name contains this string, searched by the API (Omit to scrape all)."`
+	WorkspacesSearchWildcardName  string                   `name:"workspaces.search-wildcard-name" placeholder:"*-prod" help:"Only scrape the workspaces whose name matches this pattern, with * wildcards at its start and/or end, searched by the API (Omit to scrape all)."`
+	WorkspacesSearchTags          []string                 `name:"workspaces.search-tags" placeholder:"TAG1,TAG2,..." help:"Only scrape the workspaces with all these tags, searched by the API (Omit to scrape all)."`
+	WorkspacesFullRefreshInterval time.Duration            `name:"workspaces.full-refresh-interval" placeholder:"1h" help:"Only fetch the workspaces changed since the previous scrape, merging them with the ones listed before, and list every workspace again on this interval, forgetting the deleted ones (Omit to list every workspace on every scrape)."`
+	RunsLookback                  time.Duration            `default:"24h" help:"Only the runs created within this window are listed and aggregated by the runs and policy_checks scrapers, bounding the history scanned on each scrape."`
+	ExpectedTerraformVersion      string                   `placeholder:"1.4.0" help:"Terraform version the workspaces are expected to use at least, older ones are exposed as outdated (Omit to not compare them)."`
+	RunConfirmationActor          bool                     `help:"Expose whether the current run of each workspace was confirmed by a user or auto-applied, without identifying the user."`
+	RunConfirmationActorHashed    bool                     `help:"With --run-confirmation-actor, expose the hash of the user who confirmed the current run instead of user, keyed with --labels.hash-key-file, to tell the users apart without identifying them."`
+	RunCommitInfo                 bool                     `help:"Expose the commit (SHA, branch and pull request) the configuration of the current run of each workspace was ingressed from, to correlate the runs with the CI pipelines."`
+	CacheTTL                      map[string]time.Duration `placeholder:"SCRAPER=TTL;..." help:"Time to cache the results of each scraper, e.g. organizations=10m;workspaces=1m (Omit to never cache)."`
+	MaxPages                      map[string]int           `placeholder:"SCRAPER=PAGES;..." help:"Maximum number of pages of each list fetched by each scraper, e.g. runs=50;workspaces=100 (Omit to fetch every page)."`
+	TimeoutOffset                 time.Duration            `default:"250ms" help:"Offset to subtract from the scrape timeout sent by Prometheus, to finish the scrape before Prometheus gives up."`
+	Shard                         Shard                    `placeholder:"N/M" help:"Only scrape the organizations whose hash modulo M is N, to split the work between M replicas (Omit to scrape all)."`
+	MaxConcurrentRequests         int                      `default:"10" help:"Maximum number of concurrent requests to the API, shared by all scrapers, organizations and pages (0 for unlimited)."`
+	MaxAPIRequestsPerScrape       int                      `name:"max-api-requests-per-scrape" placeholder:"1000" help:"Stop scraping once this number of requests was sent to the API, exposing partial results (Omit for unlimited)."`
+	CollectInterval               time.Duration            `placeholder:"1m" help:"Collect metrics in the background on this interval and serve the latest results (Omit to collect on every request)."`
+	TracingEndpoint               string                   `placeholder:"localhost:4318" help:"OTLP/HTTP endpoint to export traces of the scrapes to (Omit to disable tracing)."`
+	TracingInsecure               bool                     `help:"Use plain HTTP to export traces."`
+	PushInterval                  time.Duration            `default:"1m" help:"Interval to push metrics on, when a push mode is enabled."`
+	OTLPMetricsEndpoint           string                   `name:"otlp-metrics-endpoint" placeholder:"localhost:4318" help:"OTLP/HTTP endpoint to push metrics to (Omit to disable)."`
+	OTLPMetricsInsecure           bool                     `name:"otlp-metrics-insecure" help:"Use plain HTTP to push metrics via OTLP."`
+	PushGatewayURL                string                   `name:"push.gateway-url" placeholder:"http://pushgateway:9091" help:"Prometheus Pushgateway to push metrics to (Omit to disable)."`
+	PushGatewayGrouping           map[string]string        `name:"push.grouping" placeholder:"LABEL=VALUE;..." help:"Grouping labels of the metrics pushed to the Pushgateway, besides job=tf_exporter."`
+	RemoteWriteURL                string                   `name:"push.remote-write-url" placeholder:"https://prometheus/api/v1/write" help:"Prometheus remote write endpoint to push metrics to (Omit to disable)."`
+	RemoteWriteBearerToken        string                   `name:"push.remote-write-bearer-token" env:"TF_REMOTE_WRITE_BEARER_TOKEN" help:"Bearer token for authenticating with the remote write endpoint."`
+	RemoteWriteBearerTokenFile    string                   `name:"push.remote-write-bearer-token-file" placeholder:"/path/to/file" help:"File containing the bearer token for authenticating with the remote write endpoint."`
+	RemoteWriteCAFile             string                   `name:"push.remote-write-ca-file" placeholder:"/path/to/file" help:"CA certificate to verify the remote write endpoint."`
+	RemoteWriteCertFile           string                   `name:"push.remote-write-cert-file" placeholder:"/path/to/file" help:"Client certificate for authenticating with the remote write endpoint."`
+	RemoteWriteKeyFile            string                   `name:"push.remote-write-key-file" placeholder:"/path/to/file" help:"Client key for authenticating with the remote write endpoint."`
+	RemoteWriteInsecureSkipVerify bool                     `name:"push.remote-write-insecure-skip-verify" help:"Accept any certificate presented by the remote write endpoint."`
+	WebhookEnabled                bool                     `name:"webhook.enabled" help:"Receive the run notifications of the workspaces on /webhook, counting every run state transition."`
+	WebhookToken                  string                   `name:"webhook.token" env:"TF_WEBHOOK_TOKEN" help:"Token the run notifications are signed with, required unless --webhook.token-file, --webhook.token-files or --webhook.allow-unsigned is set."`
+	WebhookTokenFile              string                   `name:"webhook.token-file" placeholder:"/path/to/file" help:"File containing the tokens the run notifications are signed with, one per line, reloaded when changed."`
+	WebhookTokenFiles             map[string]string        `name:"webhook.token-files" placeholder:"NAME=FILE;..." help:"Files containing the tokens the run notifications sent to /webhook/NAME are signed with, e.g. org1=/etc/tf_exporter/org1."`
+	WebhookAllowUnsigned          bool                     `name:"webhook.allow-unsigned" help:"Accept unsigned run notifications on /webhook, without --webhook.token or --webhook.token-file."`
+	WebhookWorkspaceLabel         bool                     `name:"webhook.workspace-label" help:"Label the run notifications counted with the name of their workspace, one series per workspace."`
+	AuditTrailEnabled             bool                     `name:"audit-trail.enabled" help:"Tail the audit trail of the organization, counting its events (Terraform Cloud Business tier only)."`
+	AuditTrailToken               string                   `name:"audit-trail.token" env:"TF_AUDIT_TRAIL_TOKEN" help:"Organization token for reading the audit trail (Omit to use the API token)."`
+	AuditTrailInterval            time.Duration            `name:"audit-trail.interval" default:"1m" help:"Interval to poll the audit trail for new events on."`
+	AuditTrailBookmarkFile        string                   `name:"audit-trail.bookmark-file" placeholder:"/path/to/file" help:"File to save the timestamp of the last event counted to, so restarts resume from it (Omit to start from now on)."`
+	MetricNamespace               string                   `default:"tf" help:"Namespace (prefix) of the metric names, e.g. tfc_prod to expose tfc_prod_workspaces_info."`
+	Labels                        map[string]string        `name:"label" placeholder:"KEY=VALUE" help:"Label to add to every metric, e.g. environment=prod (Repeatable)."`
+	LabelsNormalize               []string                 `name:"labels.normalize" default:"organization,workspace,name" placeholder:"LABEL1,LABEL2,..." help:"Labels whose values are normalized by --labels.replace-characters and --labels.max-length."`
+	LabelsLowercaseOrganizations  bool                     `name:"labels.lowercase-organizations" help:"Lowercase the names of the organizations in the organization label."`
+	LabelsReplaceCharacters       string                   `name:"labels.replace-characters" placeholder:"[^a-zA-Z0-9_.-]" help:"Regular expression matching the characters to replace with --labels.replacement in the values of the --labels.normalize labels (Omit to keep every character)."`
+	LabelsReplacement             string                   `name:"labels.replacement" default:"_" help:"Replacement of the characters matched by --labels.replace-characters."`
+	LabelsMaxLength               int                      `name:"labels.max-length" placeholder:"63" help:"Trim the values of the --labels.normalize labels to this number of characters (Omit to keep them whole)."`
+	LabelsHash                    []string                 `name:"labels.hash" placeholder:"LABEL1,LABEL2,..." help:"Labels whose values are replaced by their hash, e.g. organization,workspace,name to hide the names of the organizations and workspaces but keep their IDs (Omit to expose the names)."`
+	LabelsHashKeyFile             string                   `name:"labels.hash-key-file" placeholder:"/path/to/file" help:"File containing the key the --labels.hash labels are hashed with, so they can't be told by hashing the names they could be."`
+	NativeHistogramBucketFactor   float64                  `placeholder:"1.1" help:"Also expose the histograms as native histograms with this bucket growth factor, to the scrapers negotiating them, besides their classic buckets (Omit to only expose the classic buckets)."`
+	EnableOpenMetrics             bool                     `name:"enable-openmetrics" help:"Serve the metrics in the OpenMetrics format to the scrapers negotiating it (Always enabled with tracing, to expose the exemplars of the API requests)."`
+	DisableRuntimeMetrics         bool                     `help:"Exclude the Go runtime and process metrics of the exporter, keeping only the Terraform metrics."`
+	ListenAddress                 string                   `default:"0.0.0.0:9100" help:"Address to listen on for web interface and telemetry, or unix socket: unix:///path/to/socket"`
+	TelemetryAddress              string                   `help:"Address to serve the metrics of the exporter itself (runtime, process, API client) on, apart from the Terraform metrics."`
+	SystemdSocket                 bool                     `help:"Use the listeners of the systemd socket activation instead of --listen-address (Linux only)."`
+	ShutdownTimeout               time.Duration            `default:"30s" help:"Time to wait for in-flight requests to finish when shutting down."`
+	LogLevel                      string                   `default:"info" enum:"debug,info,warn,error" help:"Only log messages with the given severity or above. One of: [${enum}]"`
+	LogFormat                     string                   `default:"logfmt" enum:"logfmt,json" help:"Output format of log messages. One of: [${enum}]"`
+
+	Serve        struct{} `cmd:"" default:"1" help:"Serve the metrics (default)."`
+	Check        struct{} `cmd:"" aliases:"check-config" help:"Validate the configuration and the API token against the API, reporting the organizations the token can't access."`
+	ListScrapers struct{} `cmd:"" help:"Print the available scrapers, their API version and help."`
+	Docs         struct{} `cmd:"" help:"Print the metrics every scraper can emit, with their help and labels, as JSON."`
+	Backfill     struct {
+		From   time.Time     `required:"" format:"2006-01-02" placeholder:"YYYY-MM-DD" help:"Day to start the history from."`
+		To     time.Time     `format:"2006-01-02" placeholder:"YYYY-MM-DD" help:"Day to end the history at, excluded (Omit for now)."`
+		Step   time.Duration `default:"1h" help:"Interval between the samples of the history."`
+		Output string        `placeholder:"/path/to/file" help:"File to write the history to (Omit for the standard output)."`
+	} `cmd:"" help:"Write the history of the runs and applies as OpenMetrics with timestamps, to backfill Prometheus with promtool tsdb create-blocks-from openmetrics."`
 }
 
 type Config struct {
 	CLI
-	Client tfe.Client
-	Logger log.Logger
+	// Command is the subcommand to run: serve, check, list-scrapers, docs or backfill.
+	Command string
+	Client  tfe.Client
+	// AuditTrails reads the audit trail with the organization token, when enabled.
+	AuditTrails tfe.AuditTrails
+	// API requests the endpoints the Client doesn't support.
+	API     *JSONAPI
+	APIInfo *APIInfo
+	Pool    *Pool
+	Breaker *CircuitBreaker
+	Logger  log.Logger
+	level   *levelLogger
+	// LabelsHashKey is the key read from --labels.hash-key-file.
+	LabelsHashKey []byte
+
+	// Instance is the name of the Terraform instance scraped with this Config, when several are configured.
+	Instance string
+	// Instances are the Configs of every Terraform instance to scrape, starting with this one, when several are configured.
+	Instances []Config
+	// Endpoints are the replicas of the API the requests fail over between, when configured.
+	Endpoints *Endpoints
+
+	tracerProvider *sdktrace.TracerProvider
+	// registerer registers the metrics of the requests to the API, if any.
+	registerer prometheus.Registerer
+	// embedded is set for the Configs created by New, which leave the global tracer provider to the service.
+	embedded bool
+	// rateLimiter limits every request to the API of the instance, including the ones the client doesn't send
+	// or can't limit (see newTFEClient).
+	rateLimiter *rate.Limiter
+	// background is done once the Config is closed, stopping the work left running in the background.
+	background context.Context
+	stop       context.CancelFunc
+}
+
+// levelLogger filters log messages by a severity that can be changed at runtime.
+type levelLogger struct {
+	next log.Logger
+
+	mu     sync.RWMutex
+	level  string
+	logger log.Logger
+}
+
+// Log implements the log.Logger interface.
+func (l *levelLogger) Log(keyvals ...interface{}) error {
+	l.mu.RLock()
+	logger := l.logger
+	l.mu.RUnlock()
+
+	return logger.Log(keyvals...)
+}
+
+func (l *levelLogger) setLevel(lvl string) error {
+	var allowed level.Option
+	switch lvl {
+	case "debug":
+		allowed = level.AllowDebug()
+	case "info":
+		allowed = level.AllowInfo()
+	case "warn":
+		allowed = level.AllowWarn()
+	case "error":
+		allowed = level.AllowError()
+	default:
+		return fmt.Errorf("unknown log level: %q", lvl)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = lvl
+	l.logger = level.NewFilter(l.next, allowed)
+	return nil
+}
+
+// SetLogLevel changes the severity of the messages logged at runtime.
+func (c *Config) SetLogLevel(lvl string) error {
+	if c.level == nil {
+		return fmt.Errorf("log level can't be changed at runtime")
+	}
+
+	return c.level.setLevel(lvl)
+}
+
+// CurrentLogLevel returns the severity of the messages currently logged.
+func (c *Config) CurrentLogLevel() string {
+	if c.level == nil {
+		return c.LogLevel
+	}
+
+	c.level.mu.RLock()
+	defer c.level.mu.RUnlock()
+	return c.level.level
+}
+
+// Validate checks the values of the flags once they're parsed.
+func (c *CLI) Validate() error {
+	if c.RunsLookback <= 0 {
+		return fmt.Errorf("--runs-lookback must be positive, got %s", c.RunsLookback)
+	}
+	if _, err := regexp.Compile(c.LabelsReplaceCharacters); err != nil {
+		return fmt.Errorf("invalid --labels.replace-characters: %w", err)
+	}
+	if c.LabelsMaxLength < 0 {
+		return fmt.Errorf("--labels.max-length can't be negative, got %d", c.LabelsMaxLength)
+	}
+	if c.MetricNamespace != "" && !model.IsValidMetricName(model.LabelValue(c.MetricNamespace)) {
+		return fmt.Errorf("invalid --metric-namespace %q", c.MetricNamespace)
+	}
+	for name := range c.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid --label name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// WorkspacesSearch returns the search parameters of the workspaces lists, so the API only returns the workspaces
+// matching the --workspaces.search-* flags instead of listing every page of them.
+func (c *CLI) WorkspacesSearch() url.Values {
+	query := url.Values{}
+	if c.WorkspacesSearchName != "" {
+		query.Set("search[name]", c.WorkspacesSearchName)
+	}
+	if c.WorkspacesSearchWildcardName != "" {
+		query.Set("search[wildcard-name]", c.WorkspacesSearchWildcardName)
+	}
+	if len(c.WorkspacesSearchTags) > 0 {
+		query.Set("search[tags]", strings.Join(c.WorkspacesSearchTags, ","))
+	}
+
+	return query
 }
 
 // NewConfig returns a new Config object that was initialized according to the CLI params.
-func NewConfig() Config {
-	config := Config{}
-	kong.Parse(&config.CLI)
+// Invalid params exit with the usage, while the errors setting up the Config are returned
+// along with the Config, so they can be logged with its Logger.
+func NewConfig() (Config, error) {
+	config := Config{registerer: prometheus.DefaultRegisterer}
+	config.Command = kong.Parse(&config.CLI).Command()
+	config.setupLogger()
+	if config.Command == "list-scrapers" || config.Command == "docs" {
+		// Documenting the scrapers doesn't need the API.
+		return config, nil
+	}
+
+	return config, config.setup()
+}
+
+// DefaultCLI returns the params with their default values, as if no flag was given (their environment
+// variables still apply), to be changed before creating a Config with New.
+func DefaultCLI() (CLI, error) {
+	cli := CLI{}
+	parser, err := kong.New(&cli)
+	if err != nil {
+		return cli, err
+	}
+	if _, err := parser.Parse([]string{}); err != nil {
+		return cli, err
+	}
+
+	return cli, nil
+}
+
+// New returns a new Config object initialized according to the given params, e.g. to embed the exporter
+// in another service. The logger is optional, the messages are logged as configured by the params without it.
+// The metrics of the requests to the API are registered with reg, unless it's nil. Unlike NewConfig, it doesn't
+// set the global tracer provider: The requests and scrapes are traced by the one of the service, if any,
+// instead of --tracing-endpoint.
+func New(cli CLI, logger log.Logger, reg prometheus.Registerer) (Config, error) {
+	config := Config{CLI: cli, Command: "serve", registerer: reg, embedded: true}
 	config.setupLogger()
-	config.setupClient()
-	return config
+	if logger != nil {
+		config.Logger, config.level = logger, nil
+	}
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+
+	return config, config.setup()
+}
+
+// setup creates the clients of the API, of every instance, along with the tracing.
+func (c *Config) setup() error {
+	c.background, c.stop = context.WithCancel(context.Background())
+	if err := c.setupTracing(); err != nil {
+		return err
+	}
+	if err := c.setupLabels(); err != nil {
+		return err
+	}
+	if len(c.APIInstances) > 0 {
+		c.Instance = c.InstanceName
+	}
+	if err := c.setupClient(); err != nil {
+		return err
+	}
+
+	return c.setupInstances()
 }
 
 func (c *Config) setupLogger() {
@@ -52,57 +346,170 @@ func (c *Config) setupLogger() {
 	)
 
 	if c.LogFormat == "json" {
-		c.Logger = log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+		c.level = &levelLogger{next: log.NewJSONLogger(log.NewSyncWriter(logWriter()))}
 	} else {
-		c.Logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+		c.level = &levelLogger{next: log.NewLogfmtLogger(log.NewSyncWriter(logWriter()))}
 	}
 
-	switch c.LogLevel {
-	case "debug":
-		c.Logger = level.NewFilter(c.Logger, level.AllowDebug())
-	case "warn":
-		c.Logger = level.NewFilter(c.Logger, level.AllowWarn())
-	case "error":
-		c.Logger = level.NewFilter(c.Logger, level.AllowError())
-	default:
-		c.Logger = level.NewFilter(c.Logger, level.AllowInfo())
+	if err := c.level.setLevel(c.LogLevel); err != nil {
+		c.level.setLevel("info")
+	}
+
+	c.Logger = log.With(c.level, "ts", timestampFormat, "caller", log.DefaultCaller)
+}
+
+func (c *Config) setupTracing() error {
+	if c.TracingEndpoint == "" {
+		return nil
+	}
+	if c.embedded {
+		level.Warn(c.Logger).Log("msg", "Ignoring the tracing endpoint, the traces are exported by the tracer provider of the service", "endpoint", c.TracingEndpoint)
+		return nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(c.TracingEndpoint)}
+	if c.TracingInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("error creating tracing exporter: %w", err)
+	}
+
+	c.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("tf_exporter"))),
+	)
+	otel.SetTracerProvider(c.tracerProvider)
+	level.Info(c.Logger).Log("msg", "Exporting traces", "endpoint", c.TracingEndpoint)
+	return nil
+}
+
+func (c *Config) setupLabels() error {
+	if c.LabelsHashKeyFile == "" {
+		return nil
+	}
+
+	key, err := os.ReadFile(c.LabelsHashKeyFile)
+	if err != nil {
+		return fmt.Errorf("error reading the key to hash the labels with: %w", err)
+	}
+	c.LabelsHashKey = bytes.TrimSpace(key)
+	return nil
+}
+
+// Close stops the work the Config left running in the background, e.g. pinging an API unreachable at startup.
+func (c *Config) Close() {
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+// ShutdownTracing flushes any pending spans to the tracing endpoint.
+func (c *Config) ShutdownTracing(ctx context.Context) error {
+	if c.tracerProvider == nil {
+		return nil
 	}
 
-	c.Logger = log.With(c.Logger, "ts", timestampFormat, "caller", log.DefaultCaller)
+	return c.tracerProvider.Shutdown(ctx)
+}
+
+// TracingEnabled reports whether the traces are being exported.
+func (c *Config) TracingEnabled() bool {
+	return c.tracerProvider != nil
 }
 
-func (c *Config) setupClient() {
-	config := &tfe.Config{}
+// OpenMetricsEnabled reports whether the metrics are served in the OpenMetrics format when negotiated.
+// Exemplars are only exposed in that format, so it's always enabled with tracing.
+func (c *Config) OpenMetricsEnabled() bool {
+	return c.EnableOpenMetrics || c.TracingEnabled()
+}
 
+func (c *Config) setupClient() error {
+	var token string
 	if c.APITokenFile != nil {
 		defer c.APITokenFile.Close()
 		scanner := bufio.NewScanner(c.APITokenFile)
 		scanner.Scan()
-		config.Token = scanner.Text()
+		token = scanner.Text()
 	} else if c.APIToken != "" {
-		config.Token = c.APIToken
+		token = c.APIToken
+	} else if c.ReplayDir != "" || c.Demo {
+		// The recorded responses and the demo are served without authenticating.
+		token = "replay"
 	} else {
-		level.Error(c.Logger).Log("msg", "Error creating tfe client", "err", "Missing API Token.")
-		os.Exit(1)
+		return errors.New("error creating tfe client: missing API token")
 	}
 
 	if c.APIAddress != "" {
-		config.Address = c.APIAddress
 		level.Info(c.Logger).Log("msg", "Overwritten Terraform API address", "address", c.APIAddress)
 	}
 
-	config.HTTPClient = c.setupHTTPClient()
+	config, err := c.newClient(token)
+	if err != nil {
+		return err
+	}
+	c.AuditTrails = c.Client.AuditTrails
+
+	if c.AuditTrailToken != "" {
+		// The audit trail can only be read with an organization token.
+		config.Token = c.AuditTrailToken
+		auditClient, err := c.newTFEClient(config)
+		if err != nil {
+			return fmt.Errorf("error creating tfe client for the audit trail: %w", err)
+		}
+		c.AuditTrails = auditClient.AuditTrails
+	}
+
+	return nil
+}
+
+// newClient creates the clients of the API of the instance, authenticated with the given token.
+func (c *Config) newClient(token string) (*tfe.Config, error) {
+	config := &tfe.Config{
+		Address: c.APIAddress,
+		Token:   token,
+	}
+
+	c.APIInfo = &APIInfo{}
+	c.rateLimiter = rate.NewLimiter(rate.Inf, 0)
+	c.Pool = NewPool(c.MaxConcurrentRequests)
+	c.Breaker = NewCircuitBreaker(c.CircuitBreakerThreshold, c.CircuitBreakerCooldown)
+	endpoints, err := NewEndpoints(c.APIAddress, c.APIFailoverAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the failover endpoints: %w", err)
+	}
+	c.Endpoints = endpoints
+	config.HTTPClient, err = c.setupHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("error registering the metrics of the API client: %w", err)
+	}
 
-	client, err := tfe.NewClient(config)
+	client, err := c.newTFEClient(config)
 	if err != nil {
-		level.Error(c.Logger).Log("msg", "Error creating tfe client", "err", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error creating tfe client: %w", err)
 	}
 	c.Client = *client
+
+	c.API, err = NewJSONAPI(config.HTTPClient, config.Address, config.Token)
+	if err != nil {
+		return nil, fmt.Errorf("error creating JSON:API client: %w", err)
+	}
+
+	return config, nil
 }
 
-func (c *Config) setupHTTPClient() *http.Client {
-	reg := prometheus.DefaultRegisterer
+func (c *Config) setupHTTPClient() (*http.Client, error) {
+	reg := c.registerer
+	if reg == nil {
+		// The metrics are still collected, but not exposed.
+		reg = prometheus.NewRegistry()
+	}
+	if c.Instance != "" {
+		// The requests to every instance are told apart by their label, like their metrics.
+		reg = prometheus.WrapRegistererWith(prometheus.Labels{"instance": c.Instance}, reg)
+	}
 
 	inFlightGauge := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "client_api_in_flight_requests",
@@ -122,11 +529,26 @@ func (c *Config) setupHTTPClient() *http.Client {
 			Name:    "client_api_request_duration_seconds",
 			Help:    "A histogram of request latencies.",
 			Buckets: prometheus.DefBuckets,
+			// Native histograms are only exposed to the scrapers negotiating the protobuf format.
+			NativeHistogramBucketFactor: c.NativeHistogramBucketFactor,
 		},
 		[]string{"method"},
 	)
 
-	reg.MustRegister(counter, histVec, inFlightGauge)
+	// The collectors registered by a previous Config (e.g. created again by a service embedding the exporter) are reused.
+	registered, err := register(reg, counter)
+	if err != nil {
+		return nil, err
+	}
+	counter = registered.(*prometheus.CounterVec)
+	if registered, err = register(reg, histVec); err != nil {
+		return nil, err
+	}
+	histVec = registered.(*prometheus.HistogramVec)
+	if registered, err = register(reg, inFlightGauge); err != nil {
+		return nil, err
+	}
+	inFlightGauge = registered.(prometheus.Gauge)
 
 	tlsConfig := tls.Config{}
 
@@ -134,13 +556,50 @@ func (c *Config) setupHTTPClient() *http.Client {
 		tlsConfig = tls.Config{InsecureSkipVerify: c.APIInsecureSkipVerify}
 	}
 
-	roundTripper := promhttp.InstrumentRoundTripperInFlight(inFlightGauge,
+	// Links the requests to their traces, when tracing is enabled.
+	exemplars := promhttp.WithExemplarFromContext(traceExemplar)
+
+	// Requests rejected by the budget or the circuit breaker aren't sent, so they aren't instrumented either.
+	// Every attempt of the requests retried after timing out counts against the budget, like any other request.
+	var roundTripper http.RoundTripper = timeoutRequests(c.APIRequestTimeout, c.APIRequestRetries, limitRequests(breakCircuit(c.Breaker, countRequests(promhttp.InstrumentRoundTripperInFlight(inFlightGauge,
 		promhttp.InstrumentRoundTripperCounter(counter,
-			promhttp.InstrumentRoundTripperDuration(histVec, &http.Transport{
-				TLSClientConfig: &tlsConfig,
-			}),
+			promhttp.InstrumentRoundTripperDuration(histVec, recordStatus(recordAPIInfo(c.APIInfo, requestFields(recordResponses(c.fixturesDir(c.RecordDir), replayResponses(c.fixturesDir(c.ReplayDir), demoResponses(c.Demo, failoverRequests(c.Endpoints, &http.Transport{
+				TLSClientConfig:       &tlsConfig,
+				MaxIdleConnsPerHost:   c.APIMaxIdleConnsPerHost,
+				IdleConnTimeout:       c.APIIdleConnTimeout,
+				ResponseHeaderTimeout: c.APIResponseHeaderTimeout,
+			}))))))), exemplars),
+			exemplars,
 		),
-	)
+	)))))
+
+	roundTripper = limitRate(c.rateLimiter, roundTripper)
+	if c.tracerProvider != nil || c.embedded {
+		// Creates a span for every request made to the API, with the global tracer provider.
+		roundTripper = otelhttp.NewTransport(roundTripper)
+	}
+
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// register registers the collector, returning the one already registered instead if any.
+func register(reg prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if err := reg.Register(c); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			return registered.ExistingCollector, nil
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// traceExemplar returns the trace ID of the request as exemplar, if it's part of a sampled trace.
+func traceExemplar(ctx context.Context) prometheus.Labels {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		return prometheus.Labels{"trace_id": sc.TraceID().String()}
+	}
 
-	return &http.Client{Transport: roundTripper}
+	return nil
 }
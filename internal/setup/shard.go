@@ -0,0 +1,74 @@
+package setup
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard selects the subset of organizations scraped by a replica of the exporter, so the work can be
+// split between several replicas: N/M keeps the organizations whose hash modulo M is N (0 <= N < M).
+// The zero value keeps every organization.
+type Shard struct {
+	Index int
+	Total int
+}
+
+// UnmarshalText parses a shard in the N/M format.
+func (s *Shard) UnmarshalText(text []byte) error {
+	index, total, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("invalid shard %q, expected N/M", text)
+	}
+
+	var err error
+	if s.Index, err = strconv.Atoi(index); err != nil {
+		return fmt.Errorf("invalid shard index %q: %w", index, err)
+	}
+	if s.Total, err = strconv.Atoi(total); err != nil {
+		return fmt.Errorf("invalid shard total %q: %w", total, err)
+	}
+	if s.Total < 1 || s.Index < 0 || s.Index >= s.Total {
+		return fmt.Errorf("invalid shard %q, expected 0 <= N < M", text)
+	}
+
+	return nil
+}
+
+// String returns the shard in the N/M format.
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Total)
+}
+
+// Enabled reports whether the shard filters the organizations at all.
+func (s Shard) Enabled() bool {
+	return s.Total > 1
+}
+
+// Contains reports whether the key (e.g. an organization name) belongs to the shard.
+func (s Shard) Contains(key string) bool {
+	if !s.Enabled() {
+		return true
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32()%uint32(s.Total)) == s.Index
+}
+
+// Filter returns the keys that belong to the shard.
+func (s Shard) Filter(keys []string) []string {
+	if !s.Enabled() {
+		return keys
+	}
+
+	filtered := []string{}
+	for _, key := range keys {
+		if s.Contains(key) {
+			filtered = append(filtered, key)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,40 @@
+package setup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestShard(t *testing.T) {
+	convey.Convey("Parse shards", t, func() {
+		var s Shard
+		convey.So(s.UnmarshalText([]byte("1/3")), convey.ShouldBeNil)
+		convey.So(s, convey.ShouldResemble, Shard{Index: 1, Total: 3})
+		convey.So(s.String(), convey.ShouldEqual, "1/3")
+
+		for _, invalid := range []string{"1", "a/3", "1/b", "3/3", "-1/3", "0/0"} {
+			convey.So((&Shard{}).UnmarshalText([]byte(invalid)), convey.ShouldNotBeNil)
+		}
+	})
+
+	convey.Convey("Every key belongs to exactly one shard", t, func() {
+		keys := make([]string, 100)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("org-%d", i)
+		}
+
+		total := 0
+		for i := 0; i < 3; i++ {
+			filtered := Shard{Index: i, Total: 3}.Filter(keys)
+			convey.So(filtered, convey.ShouldNotBeEmpty)
+			total += len(filtered)
+		}
+		convey.So(total, convey.ShouldEqual, len(keys))
+	})
+
+	convey.Convey("The zero value keeps every key", t, func() {
+		convey.So(Shard{}.Filter([]string{"a", "b"}), convey.ShouldResemble, []string{"a", "b"})
+	})
+}
@@ -0,0 +1,163 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+const (
+	// apiRetryMin and apiRetryMax bound the backoff between the pings of an API that couldn't be reached at startup.
+	apiRetryMin = 5 * time.Second
+	apiRetryMax = time.Minute
+
+	// defaultRateLimit is the rate limit of Terraform Cloud, in requests per second, applied to the requests
+	// to an API that couldn't be reached at startup until it reports its own.
+	defaultRateLimit = "30"
+)
+
+// newTFEClient creates a tfe client, even when the API can't be reached at startup (e.g. during an outage):
+// The ping sent by tfe.NewClient is then answered locally, so the exporter starts serving its endpoints,
+// reporting the API as not ready and the scrapes as failed until it's reachable. The client doesn't rate limit
+// its requests without the limit reported by the ping, so they're limited by the rate limiter of the Config
+// instead, reconfigured once the API is pinged in the background. Other errors (e.g. an invalid address) are returned.
+// The rate limiter of the Config is configured with the limit reported by the ping as well, as it also limits
+// the requests the client doesn't send (see JSONAPI).
+func (c *Config) newTFEClient(config *tfe.Config) (*tfe.Client, error) {
+	client, err := c.pingTFEClient(config)
+	var urlErr *url.Error
+	if err == nil || config.HTTPClient == nil || !errors.As(err, &urlErr) {
+		return client, err
+	}
+
+	level.Warn(c.Logger).Log("msg", "Unable to reach the API, starting without it", "err", err, "instance", c.Instance)
+	// The ping is answered by a copy of the http client, as the client is shared with the other clients of the API.
+	httpClient := *config.HTTPClient
+	httpClient.Transport = promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Status:     "204 No Content",
+			StatusCode: http.StatusNoContent,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	pingless := *config
+	pingless.HTTPClient = &httpClient
+	client, err = tfe.NewClient(&pingless)
+	if err != nil {
+		return nil, err
+	}
+	// The copy is only used by the new client from now on.
+	httpClient.Transport = config.HTTPClient.Transport
+
+	configureRateLimit(c.rateLimiter, defaultRateLimit)
+	go c.waitForAPI(c.background, config.HTTPClient, urlErr.URL)
+	return client, nil
+}
+
+// pingTFEClient creates a tfe client, configuring the rate limiter of the Config with the rate limit reported
+// by the ping it sends, like the client configures its own.
+func (c *Config) pingTFEClient(config *tfe.Config) (*tfe.Client, error) {
+	if config.HTTPClient == nil {
+		return tfe.NewClient(config)
+	}
+
+	// The ping is sent by a copy of the http client, as the client is shared with the other clients of the API.
+	httpClient := *config.HTTPClient
+	next := config.HTTPClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	httpClient.Transport = promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err == nil && strings.HasSuffix(req.URL.Path, "/ping") {
+			configureRateLimit(c.rateLimiter, resp.Header.Get("X-RateLimit-Limit"))
+		}
+		return resp, err
+	})
+	pinging := *config
+	pinging.HTTPClient = &httpClient
+	client, err := tfe.NewClient(&pinging)
+	// The copy is only used by the new client from now on.
+	httpClient.Transport = config.HTTPClient.Transport
+
+	return client, err
+}
+
+// waitForAPI pings the API with an exponential backoff until it answers or ctx is done, then configures the
+// rate limiter of the Config with the rate limit reported by the API.
+func (c *Config) waitForAPI(ctx context.Context, client *http.Client, ping string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for backoff := apiRetryMin; ; backoff *= 2 {
+		if backoff > apiRetryMax {
+			backoff = apiRetryMax
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ping, nil)
+		if err != nil {
+			level.Error(c.Logger).Log("msg", "Error pinging the API", "err", err, "instance", c.Instance)
+			return
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			level.Debug(c.Logger).Log("msg", "API still unreachable", "err", err, "instance", c.Instance)
+			continue
+		}
+		resp.Body.Close()
+
+		limit := resp.Header.Get("X-RateLimit-Limit")
+		configureRateLimit(c.rateLimiter, limit)
+		level.Info(c.Logger).Log("msg", "Reached the API", "instance", c.Instance, "rate_limit", limit)
+		return
+	}
+}
+
+// configureRateLimit configures the limiter like the tfe client configures its own with the rate limit reported
+// by the API: 2/3 of the limit, with a burst of 1/3. Requests aren't limited without a rate limit.
+func configureRateLimit(limiter *rate.Limiter, rawLimit string) {
+	if limiter == nil {
+		return
+	}
+
+	limit, burst := rate.Inf, 0
+	if rateLimit, err := strconv.ParseFloat(rawLimit, 64); err == nil && rateLimit > 0 {
+		limit = rate.Limit(rateLimit * 0.66)
+		burst = int(rateLimit * 0.33)
+	}
+	limiter.SetLimit(limit)
+	limiter.SetBurst(burst)
+}
+
+// limitRate wraps the transport to wait for the limiter before sending every request.
+func limitRate(limiter *rate.Limiter, next http.RoundTripper) http.RoundTripper {
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		return next.RoundTrip(req)
+	})
+}
@@ -0,0 +1,92 @@
+package setup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/go-kit/kit/log"
+
+	tfe "github.com/hashicorp/go-tfe"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewTFEClient(t *testing.T) {
+	background, stop := context.WithCancel(context.Background())
+	defer stop()
+	c := &Config{Logger: log.NewNopLogger(), rateLimiter: rate.NewLimiter(rate.Inf, 0), background: background}
+
+	convey.Convey("The client is created even if the API can't be reached, and its requests fail until it is", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		address := server.URL
+		server.Close()
+
+		httpClient := &http.Client{Transport: http.DefaultTransport}
+		client, err := c.newTFEClient(&tfe.Config{Address: address, Token: "test", HTTPClient: httpClient})
+		convey.So(err, convey.ShouldBeNil)
+		_, err = client.Users.ReadCurrent(context.Background())
+		convey.So(err, convey.ShouldNotBeNil)
+
+		// The shared http client is left alone, and the requests are limited to the default rate limit meanwhile.
+		convey.So(httpClient.Transport, convey.ShouldEqual, http.DefaultTransport)
+		convey.So(c.rateLimiter.Limit(), convey.ShouldAlmostEqual, 30*0.66)
+	})
+
+	convey.Convey("The requests are limited to the rate limit reported by the ping of a reachable API", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		httpClient := &http.Client{Transport: http.DefaultTransport}
+		_, err := c.newTFEClient(&tfe.Config{Address: server.URL, Token: "test", HTTPClient: httpClient})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(httpClient.Transport, convey.ShouldEqual, http.DefaultTransport)
+		convey.So(c.rateLimiter.Limit(), convey.ShouldAlmostEqual, 66)
+	})
+
+	convey.Convey("Other errors are returned", t, func() {
+		_, err := c.newTFEClient(&tfe.Config{Address: "https://app.terraform.io", HTTPClient: &http.Client{}})
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+func TestConfigureRateLimit(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+
+	convey.Convey("The limiter is configured like the one of the tfe client", t, func() {
+		configureRateLimit(limiter, "100")
+		convey.So(limiter.Limit(), convey.ShouldAlmostEqual, 66)
+		convey.So(limiter.Burst(), convey.ShouldEqual, 33)
+
+		// APIs without rate limit aren't limited.
+		configureRateLimit(limiter, "")
+		convey.So(limiter.Limit(), convey.ShouldEqual, rate.Inf)
+	})
+}
+
+func TestWaitForAPI(t *testing.T) {
+	c := &Config{Logger: log.NewNopLogger(), rateLimiter: rate.NewLimiter(rate.Inf, 0)}
+
+	convey.Convey("The pings stop once the context is done", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.waitForAPI(ctx, http.DefaultClient, "http://127.0.0.1:0/api/v2/ping")
+		}()
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("waitForAPI didn't return once cancelled")
+		}
+	})
+}
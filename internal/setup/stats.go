@@ -0,0 +1,88 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// statsKey is the context key of the RequestStats.
+type statsKey struct{}
+
+// RequestStats counts the requests sent to the API with a context, and the pages and items of the lists they got,
+// to tune the page size and concurrency of the scrapes. It is safe for concurrent use.
+type RequestStats struct {
+	requests, pages, items int64
+}
+
+// WithRequestStats returns a copy of ctx whose API requests are counted by the returned RequestStats.
+func WithRequestStats(ctx context.Context) (context.Context, *RequestStats) {
+	stats := &RequestStats{}
+	return context.WithValue(ctx, statsKey{}, stats), stats
+}
+
+// Requests returns the number of requests sent.
+func (s *RequestStats) Requests() int {
+	return int(atomic.LoadInt64(&s.requests))
+}
+
+// Pages returns the number of pages of lists received.
+func (s *RequestStats) Pages() int {
+	return int(atomic.LoadInt64(&s.pages))
+}
+
+// Items returns the number of items of the pages received.
+func (s *RequestStats) Items() int {
+	return int(atomic.LoadInt64(&s.items))
+}
+
+// statsBody counts the items of the page in the body, once it's been read and closed.
+type statsBody struct {
+	io.ReadCloser
+	stats *RequestStats
+	buf   bytes.Buffer
+}
+
+// Read implements the io.Reader interface.
+func (b *statsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// Close implements the io.Closer interface.
+func (b *statsBody) Close() error {
+	// Only the JSON:API documents whose data is a list are pages, the others have a single item or none.
+	var page struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b.buf.Bytes(), &page); err == nil && page.Data != nil {
+		atomic.AddInt64(&b.stats.pages, 1)
+		atomic.AddInt64(&b.stats.items, int64(len(page.Data)))
+	}
+
+	return b.ReadCloser.Close()
+}
+
+// countRequests wraps the transport to count the requests of the RequestStats of their context.
+func countRequests(next http.RoundTripper) http.RoundTripper {
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		stats, ok := req.Context().Value(statsKey{}).(*RequestStats)
+		if !ok {
+			return next.RoundTrip(req)
+		}
+
+		atomic.AddInt64(&stats.requests, 1)
+		resp, err := next.RoundTrip(req)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			resp.Body = &statsBody{ReadCloser: resp.Body, stats: stats}
+		}
+
+		return resp, err
+	})
+}
@@ -0,0 +1,51 @@
+package setup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestStats(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list":
+			w.Write([]byte(`{"data":[{"id":"ws-1"},{"id":"ws-2"}],"meta":{"pagination":{"total-count":2}}}`))
+		case "/item":
+			w.Write([]byte(`{"data":{"id":"ws-1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"status":"404"}],"data":[]}`))
+		}
+	}))
+	defer mockAPI.Close()
+
+	client := &http.Client{Transport: countRequests(http.DefaultTransport)}
+	get := func(ctx context.Context, path string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, mockAPI.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	convey.Convey("Requests, pages and items are counted", t, func() {
+		ctx, stats := WithRequestStats(context.Background())
+		get(ctx, "/list")
+		get(ctx, "/list")
+		get(ctx, "/item")
+		get(ctx, "/missing")
+		// Requests without stats aren't counted.
+		get(context.Background(), "/list")
+
+		convey.So(stats.Requests(), convey.ShouldEqual, 4)
+		convey.So(stats.Pages(), convey.ShouldEqual, 2)
+		convey.So(stats.Items(), convey.ShouldEqual, 4)
+	})
+}
@@ -0,0 +1,42 @@
+package setup
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// statusKey is the context key of the StatusRecorder.
+type statusKey struct{}
+
+// StatusRecorder keeps the status code of the last failed request made to the API with a context,
+// as the errors returned by the tfe client don't always carry it.
+type StatusRecorder struct {
+	status int32
+}
+
+// WithStatusRecorder returns a copy of ctx that records the status code of the failed API requests made with it.
+func WithStatusRecorder(ctx context.Context) (context.Context, *StatusRecorder) {
+	recorder := &StatusRecorder{}
+	return context.WithValue(ctx, statusKey{}, recorder), recorder
+}
+
+// LastErrorStatus returns the status code of the last failed request, or 0 if none failed.
+func (r *StatusRecorder) LastErrorStatus() int {
+	return int(atomic.LoadInt32(&r.status))
+}
+
+// recordStatus wraps the transport to record the failed requests in the StatusRecorder of their context.
+func recordStatus(next http.RoundTripper) http.RoundTripper {
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err == nil && resp.StatusCode >= 400 {
+			if recorder, ok := req.Context().Value(statusKey{}).(*StatusRecorder); ok {
+				atomic.StoreInt32(&recorder.status, int32(resp.StatusCode))
+			}
+		}
+		return resp, err
+	})
+}
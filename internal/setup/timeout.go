@@ -0,0 +1,50 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// cancelBody cancels the context of a request once its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements the io.Closer interface.
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// timeoutRequests wraps the transport to give up on the requests taking longer than timeout, retrying the GET requests
+// up to retries times, so a single hung request fails fast instead of taking the whole scrape deadline.
+func timeoutRequests(timeout time.Duration, retries int, next http.RoundTripper) http.RoundTripper {
+	if timeout <= 0 {
+		return next
+	}
+
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		for attempt := 0; ; attempt++ {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			resp, err := next.RoundTrip(req.Clone(ctx))
+			if err == nil {
+				// The timeout keeps applying while the body is read.
+				resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+			cancel()
+
+			// Only the requests timing out by themselves are retried, not the ones of a cancelled scrape.
+			timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil
+			if !timedOut || req.Method != http.MethodGet || attempt >= retries {
+				return nil, err
+			}
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimeoutRequests(t *testing.T) {
+	var requests, hangs int32
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first requests hang until the client gives up.
+		if atomic.AddInt32(&requests, 1) <= atomic.LoadInt32(&hangs) {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer mockAPI.Close()
+
+	client := &http.Client{Transport: timeoutRequests(50*time.Millisecond, 1, http.DefaultTransport)}
+	get := func() (string, error) {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, mockAPI.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	convey.Convey("Hung requests are retried", t, func() {
+		atomic.StoreInt32(&requests, 0)
+		atomic.StoreInt32(&hangs, 1)
+		body, err := get()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(body, convey.ShouldEqual, "ok")
+		convey.So(atomic.LoadInt32(&requests), convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Requests fail once the retries are spent", t, func() {
+		atomic.StoreInt32(&requests, 0)
+		atomic.StoreInt32(&hangs, 2)
+		_, err := get()
+		convey.So(errors.Is(err, context.DeadlineExceeded), convey.ShouldBeTrue)
+		convey.So(atomic.LoadInt32(&requests), convey.ShouldEqual, 2)
+	})
+
+	convey.Convey("Without a timeout the transport is unchanged", t, func() {
+		convey.So(timeoutRequests(0, 1, http.DefaultTransport), convey.ShouldEqual, http.DefaultTransport)
+	})
+}
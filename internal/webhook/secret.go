@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// secret holds the tokens a notification can be signed with. Any of them is accepted,
+// so a token can be rotated by adding the new one before removing the old one.
+type secret struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	tokens  [][]byte
+}
+
+// newStaticSecret returns a secret with a single token, or with none if it's empty.
+func newStaticSecret(token string) *secret {
+	s := &secret{}
+	if token != "" {
+		s.tokens = [][]byte{[]byte(token)}
+	}
+
+	return s
+}
+
+// newFileSecret returns a secret with the tokens of the file, one per line.
+// The file is read again whenever it changes, so tokens can be rotated without restarting the exporter.
+func newFileSecret(path string) (*secret, error) {
+	s := &secret{path: path}
+	if _, err := s.get(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// get returns the current tokens, reading the file again if it was modified since the last time.
+// If the file can't be read, the last tokens read keep being used.
+func (s *secret) get() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		return s.tokens, nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return s.tokens, fmt.Errorf("reading webhook token file: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return s.tokens, nil
+	}
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return s.tokens, fmt.Errorf("reading webhook token file: %w", err)
+	}
+
+	var tokens [][]byte
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			tokens = append(tokens, line)
+		}
+	}
+	if len(tokens) == 0 {
+		return s.tokens, fmt.Errorf("webhook token file %s is empty", s.path)
+	}
+	s.tokens, s.modTime = tokens, info.ModTime()
+
+	return s.tokens, nil
+}
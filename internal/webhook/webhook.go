@@ -0,0 +1,231 @@
+// Package webhook receives the run notifications of Terraform Cloud/Enterprise workspaces,
+// counting every run state transition as it happens instead of sampling them on scrapes.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "tf"
+	subsystem = "webhook"
+
+	// signatureHeader carries the HMAC-SHA512 of the body, signed with the token of the notification configuration.
+	signatureHeader = "X-TFE-Notification-Signature"
+
+	// path is where the notifications are received, followed by the name of their secret if any.
+	path = "/webhook"
+
+	// maxBodySize limits the size of the notifications read.
+	maxBodySize = 1 << 20
+
+	// verificationTrigger is sent when a notification configuration is created or verified.
+	verificationTrigger = "verification"
+)
+
+// Config configures the tokens the notifications are signed with.
+type Config struct {
+	// Token or TokenFile validate the notifications sent to /webhook.
+	Token     string
+	TokenFile string
+	// TokenFiles validate the notifications sent to /webhook/<name>, by name.
+	TokenFiles map[string]string
+	// AllowUnsigned accepts unsigned notifications on /webhook, when neither Token nor TokenFile is set.
+	AllowUnsigned bool
+	// WorkspaceLabel labels the notifications with the name of their workspace. As it's read from the payload,
+	// unsigned notifications can create any number of series.
+	WorkspaceLabel bool
+	// NativeHistogramBucketFactor also exposes the run durations as a native histogram, if greater than 1.
+	NativeHistogramBucketFactor float64
+}
+
+// Payload is a run notification, as sent by the generic webhooks:
+// https://www.terraform.io/cloud-docs/api-docs/notification-configurations#notification-payload
+type Payload struct {
+	PayloadVersion   int            `json:"payload_version"`
+	RunID            string         `json:"run_id"`
+	RunCreatedAt     time.Time      `json:"run_created_at"`
+	WorkspaceID      string         `json:"workspace_id"`
+	WorkspaceName    string         `json:"workspace_name"`
+	OrganizationName string         `json:"organization_name"`
+	Notifications    []Notification `json:"notifications"`
+}
+
+// Notification is a run state transition.
+type Notification struct {
+	Trigger      string    `json:"trigger"`
+	RunStatus    string    `json:"run_status"`
+	RunUpdatedAt time.Time `json:"run_updated_at"`
+}
+
+// Receiver turns the run notifications into metrics.
+type Receiver struct {
+	secrets map[string]*secret
+	logger  log.Logger
+
+	notifications  *prometheus.CounterVec
+	runDuration    *prometheus.HistogramVec
+	workspaceLabel bool
+}
+
+// NewReceiver returns a new Receiver for the given config. A token is required, unless unsigned notifications
+// are explicitly allowed. Notifications sent to /webhook are only received with a Token, a TokenFile or AllowUnsigned,
+// not when only the TokenFiles of the named paths are configured.
+func NewReceiver(c Config, logger log.Logger) (*Receiver, error) {
+	secrets := map[string]*secret{}
+	if c.Token != "" || c.AllowUnsigned {
+		secrets[""] = newStaticSecret(c.Token)
+	}
+	if c.TokenFile != "" {
+		s, err := newFileSecret(c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		secrets[""] = s
+	}
+	for name, file := range c.TokenFiles {
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid webhook token name: %q", name)
+		}
+		s, err := newFileSecret(file)
+		if err != nil {
+			return nil, err
+		}
+		secrets[name] = s
+	}
+	if len(secrets) == 0 {
+		return nil, errors.New("missing webhook token, required unless unsigned run notifications are allowed")
+	}
+	labels := []string{"organization", "trigger", "run_status"}
+	if c.WorkspaceLabel {
+		labels = []string{"organization", "workspace", "trigger", "run_status"}
+	}
+
+	return &Receiver{
+		secrets: secrets,
+		logger:  logger,
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "run_notifications_total",
+			Help:      "Total number of run notifications received, by trigger and run status.",
+		}, labels),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "run_duration_seconds",
+			Help:      "Time from the creation of the runs to their notified state transitions, by trigger.",
+			// From 10s to ~5.7h.
+			Buckets:                     prometheus.ExponentialBuckets(10, 2, 12),
+			NativeHistogramBucketFactor: c.NativeHistogramBucketFactor,
+		}, []string{"organization", "trigger"}),
+		workspaceLabel: c.WorkspaceLabel,
+	}, nil
+}
+
+// Describe implements the prometheus.Collector interface.
+func (rc *Receiver) Describe(ch chan<- *prometheus.Desc) {
+	rc.notifications.Describe(ch)
+	rc.runDuration.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (rc *Receiver) Collect(ch chan<- prometheus.Metric) {
+	rc.notifications.Collect(ch)
+	rc.runDuration.Collect(ch)
+}
+
+// verify checks the body was signed with any of the tokens, if there are any.
+func verify(body []byte, signature string, tokens [][]byte) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	for _, token := range tokens {
+		mac := hmac.New(sha512.New, token)
+		mac.Write(body)
+		if hmac.Equal(mac.Sum(nil), expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// observe records the state transitions of a notification.
+func (rc *Receiver) observe(p Payload) {
+	for _, n := range p.Notifications {
+		if n.Trigger == verificationTrigger {
+			continue
+		}
+
+		if rc.workspaceLabel {
+			rc.notifications.WithLabelValues(p.OrganizationName, p.WorkspaceName, n.Trigger, n.RunStatus).Inc()
+		} else {
+			rc.notifications.WithLabelValues(p.OrganizationName, n.Trigger, n.RunStatus).Inc()
+		}
+		if !p.RunCreatedAt.IsZero() && !n.RunUpdatedAt.IsZero() {
+			rc.runDuration.WithLabelValues(p.OrganizationName, n.Trigger).Observe(n.RunUpdatedAt.Sub(p.RunCreatedAt).Seconds())
+		}
+	}
+}
+
+// ServeHTTP receives a run notification on /webhook, or on /webhook/<name> to validate it with a named token.
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, path), "/")
+	s, ok := rc.secrets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	// Failing to reload a token file keeps the last tokens read, so no notification is dropped.
+	tokens, err := s.get()
+	if err != nil {
+		level.Warn(rc.logger).Log("msg", "Error reloading webhook tokens", "name", name, "err", err)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !verify(body, r.Header.Get(signatureHeader), tokens) {
+		level.Warn(rc.logger).Log("msg", "Invalid webhook signature", "name", name, "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rc.observe(p)
+	level.Debug(rc.logger).Log("msg", "Received run notification", "organization", p.OrganizationName, "workspace", p.WorkspaceName, "run", p.RunID, "notifications", len(p.Notifications))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+const testPayload = `{
+  "payload_version": 1,
+  "run_id": "run-1",
+  "run_created_at": "2022-01-25T18:34:00.000Z",
+  "workspace_id": "ws-1",
+  "workspace_name": "workspace1",
+  "organization_name": "org1",
+  "notifications": [
+    {"trigger": "run:completed", "run_status": "applied", "run_updated_at": "2022-01-25T18:37:00.000Z"}
+  ]
+}`
+
+func sign(token, body string) string {
+	mac := hmac.New(sha512.New, []byte(token))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func notify(rc *Receiver, body, signature string) int {
+	return notifyPath(rc, "/webhook", body, signature)
+}
+
+func notifyPath(rc *Receiver, path, body, signature string) int {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(signatureHeader, signature)
+	rec := httptest.NewRecorder()
+	rc.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestReceiver(t *testing.T) {
+	convey.Convey("Signed notification", t, func() {
+		rc, err := NewReceiver(Config{Token: "secret"}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+
+		convey.So(notify(rc, testPayload, sign("secret", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+		convey.So(testutil.ToFloat64(rc.notifications.WithLabelValues("org1", "run:completed", "applied")), convey.ShouldEqual, 1)
+		convey.So(testutil.CollectAndCount(rc.runDuration), convey.ShouldEqual, 1)
+	})
+
+	convey.Convey("Workspace label", t, func() {
+		rc, err := NewReceiver(Config{Token: "secret", WorkspaceLabel: true}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+
+		convey.So(notify(rc, testPayload, sign("secret", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+		convey.So(testutil.ToFloat64(rc.notifications.WithLabelValues("org1", "workspace1", "run:completed", "applied")), convey.ShouldEqual, 1)
+	})
+
+	convey.Convey("Native histogram of the run durations", t, func() {
+		rc, err := NewReceiver(Config{AllowUnsigned: true, NativeHistogramBucketFactor: 1.1}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+
+		convey.So(notify(rc, testPayload, ""), convey.ShouldEqual, http.StatusNoContent)
+		m := &dto.Metric{}
+		convey.So(rc.runDuration.WithLabelValues("org1", "run:completed").(prometheus.Metric).Write(m), convey.ShouldBeNil)
+		// The classic buckets are kept for the scrapers not negotiating native histograms.
+		convey.So(m.GetHistogram().Schema, convey.ShouldNotBeNil)
+		convey.So(m.GetHistogram().GetBucket(), convey.ShouldNotBeEmpty)
+	})
+
+	convey.Convey("Invalid signature", t, func() {
+		rc, err := NewReceiver(Config{Token: "secret"}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+
+		convey.So(notify(rc, testPayload, sign("other", testPayload)), convey.ShouldEqual, http.StatusUnauthorized)
+		convey.So(notify(rc, testPayload, "not-hex"), convey.ShouldEqual, http.StatusUnauthorized)
+		convey.So(testutil.CollectAndCount(rc.notifications), convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("Unsigned notifications are only accepted explicitly", t, func() {
+		_, err := NewReceiver(Config{}, log.NewNopLogger())
+		convey.So(err, convey.ShouldNotBeNil)
+
+		rc, err := NewReceiver(Config{AllowUnsigned: true}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(notify(rc, testPayload, ""), convey.ShouldEqual, http.StatusNoContent)
+	})
+
+	convey.Convey("Verification notification", t, func() {
+		rc, err := NewReceiver(Config{AllowUnsigned: true}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+
+		body := `{"payload_version": 1, "run_id": null, "notifications": [{"trigger": "verification"}]}`
+		convey.So(notify(rc, body, ""), convey.ShouldEqual, http.StatusNoContent)
+		convey.So(testutil.CollectAndCount(rc.notifications), convey.ShouldEqual, 0)
+	})
+
+	convey.Convey("Named token files", t, func() {
+		file := filepath.Join(t.TempDir(), "org1")
+		convey.So(os.WriteFile(file, []byte("old\n"), 0o600), convey.ShouldBeNil)
+		rc, err := NewReceiver(Config{TokenFiles: map[string]string{"org1": file}}, log.NewNopLogger())
+		convey.So(err, convey.ShouldBeNil)
+
+		convey.So(notifyPath(rc, "/webhook/org1", testPayload, sign("old", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+		convey.So(notifyPath(rc, "/webhook/org2", testPayload, sign("old", testPayload)), convey.ShouldEqual, http.StatusNotFound)
+		// Without a token of its own, the unnamed path would accept unsigned notifications.
+		convey.So(notify(rc, testPayload, ""), convey.ShouldEqual, http.StatusNotFound)
+
+		convey.Convey("Rotated while both tokens are accepted", func() {
+			convey.So(os.WriteFile(file, []byte("old\nnew\n"), 0o600), convey.ShouldBeNil)
+			convey.So(os.Chtimes(file, time.Now(), time.Now().Add(time.Second)), convey.ShouldBeNil)
+			convey.So(notifyPath(rc, "/webhook/org1", testPayload, sign("old", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+			convey.So(notifyPath(rc, "/webhook/org1", testPayload, sign("new", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+
+			convey.So(os.WriteFile(file, []byte("new\n"), 0o600), convey.ShouldBeNil)
+			convey.So(os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)), convey.ShouldBeNil)
+			convey.So(notifyPath(rc, "/webhook/org1", testPayload, sign("old", testPayload)), convey.ShouldEqual, http.StatusUnauthorized)
+			convey.So(notifyPath(rc, "/webhook/org1", testPayload, sign("new", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+		})
+
+		convey.Convey("Removed token file keeps the last tokens", func() {
+			convey.So(os.Remove(file), convey.ShouldBeNil)
+			convey.So(notifyPath(rc, "/webhook/org1", testPayload, sign("old", testPayload)), convey.ShouldEqual, http.StatusNoContent)
+		})
+	})
+}
@@ -0,0 +1,7 @@
+//go:build plugin_example
+
+package main
+
+// Plugins are built in by a file like this one, importing their package behind their own build tag:
+// go build -tags plugin_example
+import _ "github.com/kaizendorks/terraform-cloud-exporter/plugins/example"
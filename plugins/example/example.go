@@ -0,0 +1,103 @@
+// Package example is an example of a plugin of tf_exporter: A Scraper shipped out of the collector package,
+// only built in with the plugin_example build tag. It only uses the exporter package, so it could as well be
+// a third-party module.
+package example
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/exporter"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric descriptors.
+var (
+	Entitlements = prometheus.NewDesc(
+		prometheus.BuildFQName("tf", "example", "entitlements"),
+		"Whether each feature is available to the organization (1 for available, 0 otherwise)",
+		[]string{"organization", "feature"}, nil,
+	)
+)
+
+// entitlementSet is the entitlement set of an organization, decoded as plain JSON as it's an example
+// of an endpoint the go-tfe client doesn't support.
+type entitlementSet struct {
+	Data struct {
+		Attributes map[string]interface{} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ScrapeEntitlements scrapes the features available to the organizations.
+type ScrapeEntitlements struct{}
+
+func init() {
+	exporter.Register(ScrapeEntitlements{})
+}
+
+// Name of the Scraper. Should be unique.
+func (ScrapeEntitlements) Name() string {
+	return "example_entitlements"
+}
+
+// Help describes the role of the Scraper.
+func (ScrapeEntitlements) Help() string {
+	return "Scrape the features available to the organizations from the Organizations API, an example of plugin: https://www.terraform.io/cloud-docs/api-docs/organizations#show-the-entitlement-set"
+}
+
+// Version of Terraform Cloud/Enterprise API from which scraper is available.
+func (ScrapeEntitlements) Version() string {
+	return "v2"
+}
+
+// Describe sends the descriptors of the metrics of the Scraper.
+func (ScrapeEntitlements) Describe(ch chan<- *prometheus.Desc) {
+	ch <- Entitlements
+}
+
+func getEntitlements(ctx context.Context, organization string, config *exporter.Config, ch chan<- prometheus.Metric) error {
+	var set entitlementSet
+	err := config.Pool.Do(ctx, func(ctx context.Context) error {
+		return config.API.GetJSON(ctx, "organizations/"+organization+"/entitlement-set", &set)
+	})
+	if err != nil {
+		return fmt.Errorf("%w, (organization=%s)", err, organization)
+	}
+
+	for feature, v := range set.Data.Attributes {
+		// Besides the features, the attributes include limits, e.g. the number of users.
+		available, ok := v.(bool)
+		if !ok {
+			continue
+		}
+		value := 0.0
+		if available {
+			value = 1
+		}
+
+		select {
+		case ch <- prometheus.MustNewConstMetric(Entitlements, prometheus.GaugeValue, value, organization, feature):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Scrape collects data from Terraform API and sends it over channel as prometheus metric.
+func (ScrapeEntitlements) Scrape(ctx context.Context, config *exporter.Config, ch chan<- prometheus.Metric) error {
+	// A failing organization doesn't cancel the scrape of the others.
+	g := new(errgroup.Group)
+	for _, name := range config.Organizations {
+		name := name
+		g.Go(func() error {
+			return getEntitlements(ctx, name, config, ch)
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,69 @@
+package example
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaizendorks/terraform-cloud-exporter/exporter"
+	"github.com/kaizendorks/terraform-cloud-exporter/internal/collector"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapeEntitlements(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/organizations/test-org/entitlement-set" {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"id":"org-1","type":"entitlement-sets","attributes":{"sentinel":true,"sso":false,"users":5}}}`))
+	}))
+	defer mockAPI.Close()
+
+	api, err := exporter.NewJSONAPI(http.DefaultClient, mockAPI.URL, "test")
+	if err != nil {
+		t.Fatalf("error creating a stub api client: %s", err)
+	}
+
+	config := &exporter.Config{
+		API: api,
+		CLI: exporter.Flags{Organizations: []string{"test-org"}},
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err = (ScrapeEntitlements{}).Scrape(context.Background(), config, ch); err != nil {
+			t.Errorf("error calling function on test: %s", err)
+		}
+	}()
+
+	got := map[string]float64{}
+	for m := range ch {
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatalf("error reading the metric: %s", err)
+		}
+		labels := map[string]string{}
+		for _, l := range metric.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		got[labels["organization"]+"/"+labels["feature"]] = metric.GetGauge().GetValue()
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		convey.So(got, convey.ShouldResemble, map[string]float64{"test-org/sentinel": 1, "test-org/sso": 0})
+	})
+
+	convey.Convey("The plugin is registered", t, func() {
+		scrapers, err := collector.FilterScrapers([]string{"example_entitlements"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(scrapers, convey.ShouldResemble, []collector.Scraper{ScrapeEntitlements{}})
+	})
+}